@@ -0,0 +1,115 @@
+package analysis
+
+import (
+	"fmt"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/callgraph/rta"
+	"golang.org/x/tools/go/callgraph/static"
+	"golang.org/x/tools/go/callgraph/vta"
+	"golang.org/x/tools/go/pointer"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// Algorithm builds the call graph, from the cheapest
+// and least precise to the most expensive and precise
+type Algorithm string
+
+const (
+	// AlgorithmStatic only finds static calls,
+	// calls of interfaces and func values are missing
+	AlgorithmStatic Algorithm = "static"
+	// AlgorithmCHA resolves dynamic calls by Class Hierarchy
+	// Analysis: an interface call may call methods of every
+	// type implementing the interface
+	AlgorithmCHA Algorithm = "cha"
+	// AlgorithmRTA is Rapid Type Analysis, only types instantiated
+	// in code reachable from main and init are considered.
+	// It requires main packages.
+	AlgorithmRTA Algorithm = "rta"
+	// AlgorithmVTA is Variable Type Analysis, refining
+	// the CHA call graph by how values flow
+	AlgorithmVTA Algorithm = "vta"
+	// AlgorithmPointer is the pointer analysis of golang.org/x/tools/go/pointer.
+	// It is the default for compatibility, but deprecated, memory hungry
+	// and does not support newer go versions, prefer AlgorithmVTA.
+	// It requires main packages.
+	AlgorithmPointer Algorithm = "pointer"
+)
+
+// Algorithms lists all supported algorithms
+var Algorithms = []Algorithm{AlgorithmStatic, AlgorithmCHA, AlgorithmRTA, AlgorithmVTA, AlgorithmPointer}
+
+type CallGraphOptions struct {
+	// Algorithm default AlgorithmPointer
+	Algorithm Algorithm
+}
+
+func (c *CallGraphOptions) algorithm() Algorithm {
+	if c == nil || c.Algorithm == "" {
+		return AlgorithmPointer
+	}
+	return c.Algorithm
+}
+
+// ParseAlgorithm checks `s` is one of Algorithms
+func ParseAlgorithm(s string) (Algorithm, error) {
+	for _, algo := range Algorithms {
+		if string(algo) == s {
+			return algo, nil
+		}
+	}
+	return "", fmt.Errorf("unknown call graph algorithm: %s, expect one of %v", s, Algorithms)
+}
+
+// buildSSACallGraph builds the call graph of `prog` by `algo`,
+// `mains` are the packages being analysed. Warnings are only
+// reported by AlgorithmPointer.
+func buildSSACallGraph(prog *ssa.Program, mains []*ssa.Package, algo Algorithm) (*callgraph.Graph, []pointer.Warning, error) {
+	switch algo {
+	case AlgorithmStatic:
+		return static.CallGraph(prog), nil, nil
+	case AlgorithmCHA:
+		return cha.CallGraph(prog), nil, nil
+	case AlgorithmVTA:
+		return vta.CallGraph(ssautil.AllFunctions(prog), cha.CallGraph(prog)), nil, nil
+	case AlgorithmRTA:
+		var roots []*ssa.Function
+		for _, pkg := range ssautil.MainPackages(nonNilPkgs(mains)) {
+			for _, name := range []string{"init", "main"} {
+				if fn := pkg.Func(name); fn != nil {
+					roots = append(roots, fn)
+				}
+			}
+		}
+		if len(roots) == 0 {
+			return nil, nil, fmt.Errorf("%s requires main packages", algo)
+		}
+		return rta.Analyze(roots, true).CallGraph, nil, nil
+	case AlgorithmPointer:
+		res, err := pointer.Analyze(&pointer.Config{
+			Mains:          mains,
+			BuildCallGraph: true,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return res.CallGraph, res.Warnings, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown call graph algorithm: %s", algo)
+	}
+}
+
+// nonNilPkgs removes nil packages returned by
+// ssautil.AllPackages for packages with errors
+func nonNilPkgs(pkgs []*ssa.Package) []*ssa.Package {
+	list := make([]*ssa.Package, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		if pkg != nil {
+			list = append(list, pkg)
+		}
+	}
+	return list
+}
@@ -0,0 +1,290 @@
+package analysis
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/xhd2015/go-inspect/inspect"
+	"github.com/xhd2015/go-inspect/inspect/load"
+)
+
+// bump this when the format of cache or
+// the way call graphs are built changes
+const analysisCacheVersion = "v1"
+
+type CacheOptions struct {
+	// Dir holds cached results, default
+	// {UserCacheDir}/go-inspect/analysis
+	Dir string
+
+	// Force analyses even if the cached result
+	// is up to date, the new result is still saved
+	Force bool
+
+	// CallGraph are options of analysing, results
+	// of different algorithms are cached separately
+	CallGraph *CallGraphOptions
+}
+
+// cacheEntry is a saved result, together
+// with the key it was made for
+type cacheEntry struct {
+	Key    string          `json:"key"`
+	Args   []string        `json:"args"`
+	Result json.RawMessage `json:"result"`
+}
+
+// callGraphData is the serialized StaticAnalyseResult, edges
+// are saved once instead of in both In and Out of funcs
+type callGraphData struct {
+	Algorithm Algorithm  `json:"algorithm"`
+	Root      FuncID     `json:"root"`
+	Funcs     []*Func    `json:"funcs"`
+	Edges     []*Edge    `json:"edges"`
+	Warnings  []*Warning `json:"warnings,omitempty"`
+}
+
+// LoadCallGraphCached is like LoadCallGraph, but the result is saved
+// into cacheOpts.Dir, and reused by later calls with the same args,
+// as long as the key computed by CallGraphKey does not change.
+// Packages are still loaded, only the analysis is skipped.
+// `hit` reports whether the result is from cache.
+func LoadCallGraphCached(args []string, opts *load.LoadOptions, cacheOpts *CacheOptions) (g inspect.Global, res *StaticAnalyseResult, hit bool, err error) {
+	if opts == nil {
+		opts = &load.LoadOptions{}
+	}
+	if cacheOpts == nil {
+		cacheOpts = &CacheOptions{}
+	}
+	dir := cacheOpts.Dir
+	if dir == "" {
+		dir, err = DefaultCacheDir()
+		if err != nil {
+			return
+		}
+	}
+	g, err = load.LoadPackages(args, opts)
+	if err != nil {
+		return
+	}
+	key := CallGraphKey(g, opts, cacheOpts.CallGraph)
+	file := cacheFile(dir, g, args, opts)
+	if !cacheOpts.Force {
+		res = loadCacheEntry(file, key)
+		if res != nil {
+			hit = true
+			return
+		}
+	}
+	res, err = findCallGraphOfMainModule(g, cacheOpts.CallGraph)
+	if err != nil {
+		return
+	}
+	err = saveCacheEntry(file, key, args, res)
+	if err != nil {
+		err = fmt.Errorf("save analysis cache: %w", err)
+	}
+	return
+}
+
+// DefaultCacheDir returns {UserCacheDir}/go-inspect/analysis
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "go-inspect", "analysis"), nil
+}
+
+// CallGraphKey computes the key of analysing `g`, from the go
+// environment, the load options, the algorithm and the content of every
+// package loaded. Only packages reachable from the starter
+// packages are covered, so the result is reanalysed only when
+// one of them changes. Packages of versioned modules are covered
+// by their versions, and std packages by the go version.
+func CallGraphKey(g inspect.Global, opts *load.LoadOptions, cgOpts *CallGraphOptions) string {
+	h := md5.New()
+	writeKeyParts(h,
+		analysisCacheVersion,
+		string(cgOpts.algorithm()),
+		runtime.Version(),
+		g.GOROOT(),
+		os.Getenv("GOOS"),
+		os.Getenv("GOARCH"),
+		os.Getenv("CGO_ENABLED"),
+		os.Getenv("GOFLAGS"),
+		fmt.Sprintf("test=%v", opts.ForTest),
+		strings.Join(opts.BuildFlags, " "),
+	)
+	keys := make(map[string]string)
+	var ids []string
+	for _, p := range g.LoadInfo().StarterPkgs() {
+		ids = append(ids, p.GoPkg().ID)
+		keys[p.GoPkg().ID] = pkgKey(g, p.GoPkg(), keys)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		writeKeyParts(h, id, keys[id])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// pkgKey computes key of `p` from the content of its
+// files and keys of its dependencies, memorized by `keys`
+func pkgKey(g inspect.Global, p *packages.Package, keys map[string]string) string {
+	if key, ok := keys[p.ID]; ok {
+		return key
+	}
+	// mark visiting, import cycle is not
+	// possible but be defensive
+	keys[p.ID] = ""
+
+	var key string
+	mod := p.Module
+	if mod == nil || mod.Path == "" || mod.Path == "std" {
+		// std packages are covered by go version and GOROOT
+		key = p.PkgPath
+	} else if mod.Replace == nil && mod.Version != "" {
+		// module cache is read only
+		key = p.PkgPath + "@" + mod.Version
+	} else {
+		h := md5.New()
+		writeKeyParts(h, p.PkgPath)
+		for _, file := range p.GoFiles {
+			writeKeyParts(h, file, g.FileCode(file))
+		}
+		imports := make([]string, 0, len(p.Imports))
+		for imp := range p.Imports {
+			imports = append(imports, imp)
+		}
+		sort.Strings(imports)
+		for _, imp := range imports {
+			writeKeyParts(h, imp, pkgKey(g, p.Imports[imp], keys))
+		}
+		key = hex.EncodeToString(h.Sum(nil))
+	}
+	keys[p.ID] = key
+	return key
+}
+
+func writeKeyParts(h io.Writer, parts ...string) {
+	for _, part := range parts {
+		// length prefixed to avoid ambiguity
+		fmt.Fprintf(h, "%d:%s;", len(part), part)
+	}
+}
+
+// cacheFile is the entry of `args` loaded from the project root,
+// a result of different args does not replace it
+func cacheFile(dir string, g inspect.Global, args []string, opts *load.LoadOptions) string {
+	h := md5.New()
+	writeKeyParts(h, g.LoadInfo().Root(), fmt.Sprintf("test=%v", opts.ForTest))
+	writeKeyParts(h, args...)
+	return filepath.Join(dir, hex.EncodeToString(h.Sum(nil))+".json")
+}
+
+func loadCacheEntry(file string, key string) *StaticAnalyseResult {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	err = json.Unmarshal(data, &entry)
+	if err != nil || entry.Key != key {
+		return nil
+	}
+	res, err := UnmarshalResult(entry.Result)
+	if err != nil {
+		return nil
+	}
+	return res
+}
+
+func saveCacheEntry(file string, key string, args []string, res *StaticAnalyseResult) error {
+	resData, err := MarshalResult(res)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(&cacheEntry{
+		Key:    key,
+		Args:   args,
+		Result: resData,
+	})
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// MarshalResult serializes `res`, which
+// can be restored by UnmarshalResult
+func MarshalResult(res *StaticAnalyseResult) ([]byte, error) {
+	data := &callGraphData{
+		Algorithm: res.Algorithm,
+		Warnings:  res.Warnings,
+	}
+	if res.Callgraph != nil {
+		data.Root = res.Callgraph.Root
+		ids := make([]FuncID, 0, len(res.Callgraph.Funcs))
+		for id := range res.Callgraph.Funcs {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			fn := res.Callgraph.Funcs[id]
+			data.Edges = append(data.Edges, fn.Out...)
+
+			copied := *fn
+			copied.In = nil
+			copied.Out = nil
+			data.Funcs = append(data.Funcs, &copied)
+		}
+	}
+	return json.Marshal(data)
+}
+
+// UnmarshalResult restores a result serialized by MarshalResult,
+// each edge is shared by Out of its caller and In of its callee.
+func UnmarshalResult(b []byte) (*StaticAnalyseResult, error) {
+	var data callGraphData
+	err := json.Unmarshal(b, &data)
+	if err != nil {
+		return nil, err
+	}
+	funcs := make(map[FuncID]*Func, len(data.Funcs))
+	for _, fn := range data.Funcs {
+		funcs[fn.ID] = fn
+	}
+	for _, edge := range data.Edges {
+		caller := funcs[edge.Caller]
+		callee := funcs[edge.Callee]
+		if caller == nil || callee == nil {
+			return nil, fmt.Errorf("edge %d->%d: func not found", edge.Caller, edge.Callee)
+		}
+		caller.Out = append(caller.Out, edge)
+		callee.In = append(callee.In, edge)
+	}
+	return &StaticAnalyseResult{
+		Algorithm: data.Algorithm,
+		Callgraph: &CallGraph{
+			Root:  data.Root,
+			Funcs: funcs,
+		},
+		Warnings: data.Warnings,
+	}, nil
+}
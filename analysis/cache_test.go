@@ -0,0 +1,159 @@
+package analysis
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xhd2015/go-inspect/inspect/load"
+)
+
+// go test -run TestMarshalResult -v ./analysis/
+func TestMarshalResult(t *testing.T) {
+	edge := &Edge{Caller: 0, Callee: 1, Site: &Pos{Pkg: "a", File: "a.go", FilePos: &FilePos{Offset: 10, Line: 2, Column: 3}}}
+	res := &StaticAnalyseResult{
+		Callgraph: &CallGraph{
+			Funcs: map[FuncID]*Func{
+				0: {ID: 0, Pkg: "a", File: "a.go", Name: "main", QuanlifiedName: "main", Out: []*Edge{edge}},
+				1: {ID: 1, Pkg: "a", File: "a.go", Name: "Run", QuanlifiedName: "Status.Run", In: []*Edge{edge}},
+			},
+		},
+		Warnings: []*Warning{{Message: "warn"}},
+	}
+	data, err := MarshalResult(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := UnmarshalResult(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	funcs := restored.Callgraph.Funcs
+	if len(funcs) != 2 || funcs[1].QuanlifiedName != "Status.Run" {
+		t.Fatalf("unexpected funcs: %+v", funcs)
+	}
+	if len(funcs[0].Out) != 1 || len(funcs[1].In) != 1 || funcs[0].Out[0] != funcs[1].In[0] {
+		t.Fatalf("expect the edge shared by caller and callee")
+	}
+	if funcs[0].Out[0].Site.Line != 2 {
+		t.Fatalf("expect site line 2, actual: %d", funcs[0].Out[0].Site.Line)
+	}
+	if len(restored.Warnings) != 1 || restored.Warnings[0].Message != "warn" {
+		t.Fatalf("unexpected warnings: %+v", restored.Warnings)
+	}
+}
+
+// go test -run TestCallGraphKey -v ./analysis/
+func TestCallGraphKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "analysis-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/key\n\ngo 1.13\n")
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n\nimport \"example.com/key/dep\"\n\nfunc main() { dep.Run() }\n")
+	writeFile(t, filepath.Join(dir, "dep", "dep.go"), "package dep\n\nfunc Run() {}\n")
+	writeFile(t, filepath.Join(dir, "other", "other.go"), "package other\n\nfunc Other() {}\n")
+
+	key := func() string {
+		opts := &load.LoadOptions{ProjectDir: dir}
+		g, err := load.LoadPackages([]string{"./"}, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return CallGraphKey(g, opts, nil)
+	}
+	key1 := key()
+	if key() != key1 {
+		t.Fatalf("expect key unchanged")
+	}
+
+	// not imported, not covered
+	writeFile(t, filepath.Join(dir, "other", "other.go"), "package other\n\nfunc Other() { println() }\n")
+	if key() != key1 {
+		t.Fatalf("expect key unchanged by packages not imported")
+	}
+
+	writeFile(t, filepath.Join(dir, "dep", "dep.go"), "package dep\n\nfunc Run() { println() }\n")
+	if key() == key1 {
+		t.Fatalf("expect key changed by dependency")
+	}
+}
+
+// go test -run TestLoadCallGraphCached -v ./analysis/
+func TestLoadCallGraphCached(t *testing.T) {
+	dir, err := ioutil.TempDir("", "analysis-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	args := []string{"./testdata/simple"}
+	opts := &load.LoadOptions{}
+	g, err := load.LoadPackages(args, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := &StaticAnalyseResult{
+		Callgraph: &CallGraph{
+			Funcs: map[FuncID]*Func{
+				0: {ID: 0, Name: "main", QuanlifiedName: "main"},
+			},
+		},
+	}
+	err = saveCacheEntry(cacheFile(dir, g, args, opts), CallGraphKey(g, opts, nil), args, saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, res, hit, err := LoadCallGraphCached(args, opts, &CacheOptions{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hit {
+		t.Fatalf("expect cache hit")
+	}
+	if len(res.Callgraph.Funcs) != 1 || res.Callgraph.Funcs[0].Name != "main" {
+		t.Fatalf("unexpected result: %+v", res.Callgraph.Funcs)
+	}
+}
+
+func writeFile(t *testing.T, file string, content string) {
+	err := os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(file, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// go test -run TestLoadCallGraphCachedAlgorithm -v ./analysis/
+func TestLoadCallGraphCachedAlgorithm(t *testing.T) {
+	dir, err := ioutil.TempDir("", "analysis-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cacheOpts := &CacheOptions{Dir: dir, CallGraph: &CallGraphOptions{Algorithm: AlgorithmCHA}}
+	_, res, hit, err := LoadCallGraphCached([]string{"./testdata/simple"}, &load.LoadOptions{}, cacheOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hit {
+		t.Fatalf("expect no cache hit for the first time")
+	}
+	_, cached, hit, err := LoadCallGraphCached([]string{"./testdata/simple"}, &load.LoadOptions{}, cacheOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hit {
+		t.Fatalf("expect cache hit")
+	}
+	if cached.Algorithm != AlgorithmCHA || len(cached.Callgraph.Funcs) != len(res.Callgraph.Funcs) {
+		t.Fatalf("expect %d funcs by %s, actual: %d by %s", len(res.Callgraph.Funcs), AlgorithmCHA, len(cached.Callgraph.Funcs), cached.Algorithm)
+	}
+}
@@ -0,0 +1,246 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+
+	"github.com/xhd2015/go-inspect/depcheck"
+	"github.com/xhd2015/go-inspect/inspect"
+	"github.com/xhd2015/go-inspect/inspect/load"
+)
+
+type DeadImportKind string
+
+const (
+	// DeadImportInitOnly means the package is only needed
+	// for side effects of its initialization
+	DeadImportInitOnly DeadImportKind = "init-only"
+	// DeadImportUnused means no code of the package runs,
+	// only its types or constants are used, if any
+	DeadImportUnused DeadImportKind = "unused"
+)
+
+type DeadImport struct {
+	Pkg  string         `json:"pkg"`
+	Kind DeadImportKind `json:"kind"`
+	// ImportedBy are packages directly importing Pkg
+	ImportedBy []string `json:"importedBy"`
+	// Trace is an import trace from a main package to Pkg
+	Trace []string `json:"trace"`
+}
+
+type DeadImportOptions struct {
+	// Algorithm defaults to AlgorithmRTA, AlgorithmStatic
+	// misses dynamic calls, thus reports false positives
+	Algorithm Algorithm
+	// IncludeStd also reports std packages
+	IncludeStd bool
+}
+
+func (c *DeadImportOptions) algorithm() Algorithm {
+	if c == nil || c.Algorithm == "" {
+		return AlgorithmRTA
+	}
+	return c.Algorithm
+}
+
+func LoadDeadImports(args []string, opts *load.LoadOptions, diOpts *DeadImportOptions) (g inspect.Global, res []*DeadImport, err error) {
+	g, err = load.LoadPackages(args, opts)
+	if err != nil {
+		return
+	}
+	res, err = FindDeadImports(g, diOpts)
+	return
+}
+
+// FindDeadImports lists packages imported by the main packages of `g`,
+// directly or not, of which no function is reached from main, such
+// packages can be pruned unless they are needed for initialization,
+// i.e. of kind DeadImportInitOnly. `g` must be loaded with syntax
+// and types of all packages, and with modules to tell std packages.
+func FindDeadImports(g inspect.Global, opts *DeadImportOptions) ([]*DeadImport, error) {
+	var loadPkgs []*packages.Package
+	for _, p := range g.LoadInfo().StarterPkgs() {
+		loadPkgs = append(loadPkgs, p.GoPkg())
+	}
+	deps, pkgMapping, err := depcheck.CollectDeps(loadPkgs, &depcheck.CollectOptions{NeedDependedBy: true})
+	if err != nil {
+		return nil, err
+	}
+
+	prog, ssaPkgs := ssautil.AllPackages(loadPkgs, 0)
+	prog.Build()
+	mains := ssautil.MainPackages(nonNilPkgs(ssaPkgs))
+	if len(mains) == 0 {
+		return nil, fmt.Errorf("dead imports requires main packages")
+	}
+	ssaGraph, _, err := buildSSACallGraph(prog, ssaPkgs, opts.algorithm())
+	if err != nil {
+		return nil, err
+	}
+	var mainFuncs []*ssa.Function
+	var initFuncs []*ssa.Function
+	starters := make(map[string]bool, len(loadPkgs))
+	for _, p := range loadPkgs {
+		starters[p.PkgPath] = true
+	}
+	for _, m := range mains {
+		mainFuncs = append(mainFuncs, m.Func("main"))
+		initFuncs = append(initFuncs, m.Func("init"))
+	}
+	fromMain := reachableFuncs(ssaGraph, mainFuncs)
+	fromInit := reachableFuncs(ssaGraph, initFuncs)
+
+	// packages having code run by main or init
+	usedPkgs := make(map[string]bool)
+	initPkgs := make(map[string]bool)
+	for fn := range fromMain {
+		if pkg := funcPkg(fn); pkg != "" {
+			usedPkgs[pkg] = true
+		}
+	}
+	for fn := range fromInit {
+		pkg := funcPkg(fn)
+		if pkg == "" {
+			continue
+		}
+		if fn.Name() != "init" || fn.Parent() != nil || hasInitWork(fn) {
+			initPkgs[pkg] = true
+		}
+	}
+
+	std := depcheck.CollectStdPkgs(loadPkgs)
+	var res []*DeadImport
+	for pkg, dep := range pkgMapping {
+		if starters[pkg] || usedPkgs[pkg] || pkg == "C" || pkg == "unsafe" {
+			continue
+		}
+		if !opts.includeStd() && std[pkg] {
+			continue
+		}
+		kind := DeadImportUnused
+		if initPkgs[pkg] {
+			kind = DeadImportInitOnly
+		}
+		res = append(res, &DeadImport{
+			Pkg:        pkg,
+			Kind:       kind,
+			ImportedBy: importers(dep),
+			Trace:      depcheck.GetImportTrace(deps, pkg),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Pkg < res[j].Pkg
+	})
+	return res, nil
+}
+
+func (c *DeadImportOptions) includeStd() bool {
+	return c != nil && c.IncludeStd
+}
+
+// reachableFuncs returns functions reachable from `roots` in `cg`
+func reachableFuncs(cg *callgraph.Graph, roots []*ssa.Function) map[*ssa.Function]bool {
+	visited := make(map[*ssa.Function]bool)
+	var queue []*callgraph.Node
+	for _, fn := range roots {
+		if node := cg.Nodes[fn]; node != nil && !visited[fn] {
+			visited[fn] = true
+			queue = append(queue, node)
+		}
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, e := range node.Out {
+			if fn := e.Callee.Func; fn != nil && !visited[fn] {
+				visited[fn] = true
+				queue = append(queue, e.Callee)
+			}
+		}
+	}
+	return visited
+}
+
+// funcPkg is the package declaring `fn`, closures and
+// instances of generic functions included
+func funcPkg(fn *ssa.Function) string {
+	if fn.Parent() != nil {
+		return funcPkg(fn.Parent())
+	}
+	if origin := fn.Origin(); origin != nil {
+		fn = origin
+	}
+	if fn.Pkg == nil {
+		return ""
+	}
+	return fn.Pkg.Pkg.Path()
+}
+
+// hasInitWork reports whether the synthesized package initializer
+// does anything other than guarding itself and initializing imported
+// packages, e.g. initializing variables or calling init functions.
+func hasInitWork(fn *ssa.Function) bool {
+	isGuard := func(v ssa.Value) bool {
+		global, ok := v.(*ssa.Global)
+		return ok && global.Name() == "init$guard"
+	}
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			switch instr := instr.(type) {
+			case *ssa.Jump, *ssa.If, *ssa.Return:
+			case *ssa.UnOp:
+				if !isGuard(instr.X) {
+					return true
+				}
+			case *ssa.Store:
+				if !isGuard(instr.Addr) {
+					return true
+				}
+			case *ssa.Call:
+				callee := instr.Call.StaticCallee()
+				if callee == nil || callee.Name() != "init" || callee.Pkg == fn.Pkg {
+					return true
+				}
+			default:
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func importers(dep *depcheck.PkgDepInfo) []string {
+	var list []string
+	seen := make(map[string]bool, len(dep.DependedBy))
+	for _, by := range dep.DependedBy {
+		if by == "" || seen[by] {
+			continue
+		}
+		seen[by] = true
+		list = append(list, by)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// FormatDeadImports formats `res` with importers and import traces
+func FormatDeadImports(res []*DeadImport) string {
+	if len(res) == 0 {
+		return "no dead imports"
+	}
+	var lines []string
+	for _, d := range res {
+		lines = append(lines, fmt.Sprintf("%s (%s), imported by %s", d.Pkg, d.Kind, strings.Join(d.ImportedBy, ", ")))
+		for i, pkg := range d.Trace {
+			lines = append(lines, strings.Repeat("  ", i+1)+pkg)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
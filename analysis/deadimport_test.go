@@ -0,0 +1,49 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/go-inspect/inspect/load"
+)
+
+// go test -run TestFindDeadImports -v ./analysis/
+func TestFindDeadImports(t *testing.T) {
+	_, res, err := LoadDeadImports([]string{"./testdata/deadimport"}, &load.LoadOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("dead imports:\n%s", FormatDeadImports(res))
+
+	const prefix = "github.com/xhd2015/go-inspect/analysis/testdata/deadimport/"
+	var list []string
+	for _, d := range res {
+		list = append(list, strings.TrimPrefix(d.Pkg, prefix)+":"+string(d.Kind))
+	}
+	expect := "consts:unused heavy:init-only plugin:init-only"
+	if strings.Join(list, " ") != expect {
+		t.Fatalf("expect %s, actual: %s", expect, strings.Join(list, " "))
+	}
+	heavy := res[1]
+	if strings.Join(heavy.ImportedBy, " ") != prefix+"used" || len(heavy.Trace) != 3 {
+		t.Fatalf("unexpected heavy: %+v", heavy)
+	}
+}
+
+// go test -run TestFindDeadImportsDotlessModule -v ./analysis/
+func TestFindDeadImportsDotlessModule(t *testing.T) {
+	_, res, err := LoadDeadImports([]string{"./"}, &load.LoadOptions{ProjectDir: "./testdata/deadimport_dotless"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("dead imports:\n%s", FormatDeadImports(res))
+
+	var list []string
+	for _, d := range res {
+		list = append(list, d.Pkg+":"+string(d.Kind))
+	}
+	expect := "app/consts:unused app/heavy:init-only"
+	if strings.Join(list, " ") != expect {
+		t.Fatalf("expect %s, actual: %s", expect, strings.Join(list, " "))
+	}
+}
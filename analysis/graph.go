@@ -0,0 +1,78 @@
+package analysis
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/xhd2015/go-inspect/graph"
+)
+
+type GraphOptions struct {
+	// Cluster groups functions, e.g. by ClusterByPkg,
+	// nil means no groups
+	Cluster func(fn *Func) string
+	// Highlight marks functions matching a filter
+	Highlight func(fn *Func) bool
+}
+
+// ClusterByPkg groups functions by their packages
+func ClusterByPkg(fn *Func) string {
+	return fn.Pkg
+}
+
+// Graph converts the call graph of `res` to a graph of functions,
+// edges point from callers to callees, calls of the same caller
+// and callee at different sites are merged.
+func Graph(res *StaticAnalyseResult, opts *GraphOptions) *graph.Graph {
+	if opts == nil {
+		opts = &GraphOptions{}
+	}
+	g := &graph.Graph{Name: "callgraph"}
+	if res.Callgraph == nil {
+		return g
+	}
+	q := NewQuery(res)
+	funcs := q.sortedFuncs()
+	for _, fn := range funcs {
+		node := &graph.Node{
+			ID:    strconv.Itoa(fn.ID),
+			Label: funcLabel(fn, opts.Cluster != nil),
+		}
+		if opts.Cluster != nil {
+			node.Cluster = opts.Cluster(fn)
+		}
+		if opts.Highlight != nil {
+			node.Highlight = opts.Highlight(fn)
+		}
+		g.Nodes = append(g.Nodes, node)
+	}
+	edges := make(map[[2]FuncID]bool)
+	for _, fn := range funcs {
+		for _, e := range fn.Out {
+			if edges[[2]FuncID{e.Caller, e.Callee}] || q.Func(e.Callee) == nil {
+				continue
+			}
+			edges[[2]FuncID{e.Caller, e.Callee}] = true
+			g.Edges = append(g.Edges, &graph.Edge{From: strconv.Itoa(e.Caller), To: strconv.Itoa(e.Callee)})
+		}
+	}
+	return g
+}
+
+// funcLabel is the name of `fn` qualified by the base name of its
+// package, unless clustered by package. Function literals are
+// labeled by their positions.
+func funcLabel(fn *Func, clustered bool) string {
+	name := fn.QuanlifiedName
+	if fn.Name == "" {
+		name = "func@" + fn.File
+		if fn.Pos != nil {
+			name += fmt.Sprintf(":%d", fn.Pos.Line)
+		}
+	}
+	if clustered || fn.Pkg == "" {
+		return name
+	}
+	return path.Base(fn.Pkg) + "." + name
+}
@@ -8,7 +8,6 @@ import (
 
 	"golang.org/x/tools/go/callgraph"
 	"golang.org/x/tools/go/packages"
-	"golang.org/x/tools/go/pointer"
 	"golang.org/x/tools/go/ssa/ssautil"
 
 	"github.com/xhd2015/go-inspect/inspect"
@@ -50,6 +49,12 @@ type Opts struct {
 type MatchFunc func(g inspect.Global, decl *ast.FuncDecl /*parent decl*/, lit *ast.FuncLit /*func decl,or func literal*/) bool
 
 func LoadMatch(args []string, m Matcher, opts *load.LoadOptions) (g inspect.Global, nodeMap map[ast.Node]bool, err error) {
+	return LoadMatchWithOptions(args, m, opts, nil)
+}
+
+// LoadMatchWithOptions is like LoadMatch, with
+// the algorithm chosen by `cgOpts`
+func LoadMatchWithOptions(args []string, m Matcher, opts *load.LoadOptions, cgOpts *CallGraphOptions) (g inspect.Global, nodeMap map[ast.Node]bool, err error) {
 	g, err = load.LoadPackages(args, opts)
 	if err != nil {
 		return
@@ -61,22 +66,23 @@ func LoadMatch(args []string, m Matcher, opts *load.LoadOptions) (g inspect.Glob
 		loadPkgs = append(loadPkgs, p.GoPkg())
 	}
 
-	nodeMap, err = FindMatch(g, loadPkgs, m)
+	nodeMap, err = FindMatchWithOptions(g, loadPkgs, m, cgOpts)
 	return
 }
 
 func FindMatch(g inspect.Global, loadPkgs []*packages.Package, m Matcher) (nodeMap map[ast.Node]bool, err error) {
+	return FindMatchWithOptions(g, loadPkgs, m, nil)
+}
+
+// FindMatchWithOptions is like FindMatch, with
+// the algorithm chosen by `cgOpts`
+func FindMatchWithOptions(g inspect.Global, loadPkgs []*packages.Package, m Matcher, cgOpts *CallGraphOptions) (nodeMap map[ast.Node]bool, err error) {
 	prog, ssaPkgs := ssautil.AllPackages(loadPkgs, 0)
 	// NOTE: Build() must be called to get full relationship
 	// otherwise only main pkg will appear
 	prog.Build()
 
-	config := &pointer.Config{
-		Mains:          ssaPkgs,
-		BuildCallGraph: true,
-	}
-
-	res, err := pointer.Analyze(config)
+	callGraph, _, err := buildSSACallGraph(prog, ssaPkgs, cgOpts.algorithm())
 	if err != nil {
 		return
 	}
@@ -162,8 +168,9 @@ func FindMatch(g inspect.Global, loadPkgs []*packages.Package, m Matcher) (nodeM
 	ssaToAST := make(map[*callgraph.Node]ast.Node)
 	var flatten func(n *callgraph.Node) map[*callgraph.Node]bool
 	flatten = func(n *callgraph.Node) map[*callgraph.Node]bool {
-		// skip
-		if n.Func.Synthetic != "" {
+		// skip, the root of graphs other
+		// than pointer's has no func
+		if n.Func == nil || n.Func.Synthetic != "" {
 			return nil
 		}
 		if found, ok := dependencyMap[n]; ok {
@@ -185,6 +192,11 @@ func FindMatch(g inspect.Global, loadPkgs []*packages.Package, m Matcher) (nodeM
 
 		ast := origAstNode(n.Func.Syntax())
 		if ast == nil {
+			// functions of packages built without syntax are
+			// found by algorithms analysing the whole program
+			if cgOpts.algorithm() != AlgorithmPointer {
+				return res
+			}
 			panic(fmt.Errorf("unexpected nil ast"))
 		}
 		astToSSA[ast] = n
@@ -192,7 +204,7 @@ func FindMatch(g inspect.Global, loadPkgs []*packages.Package, m Matcher) (nodeM
 		return res
 	}
 	debug := false
-	for _, n := range res.CallGraph.Nodes {
+	for _, n := range callGraph.Nodes {
 		flatten(n)
 
 		// TODO: comment out
@@ -246,7 +258,9 @@ func FindMatch(g inspect.Global, loadPkgs []*packages.Package, m Matcher) (nodeM
 		for n := range dependencyMap[ssaNode] {
 			astNode := ssaToAST[n]
 			if astNode == nil {
-				err = fmt.Errorf("ast node not found:%+v", n)
+				if cgOpts.algorithm() == AlgorithmPointer {
+					err = fmt.Errorf("ast node not found:%+v", n)
+				}
 				continue
 			}
 			nodeMap[astNode] = true
@@ -0,0 +1,206 @@
+package analysis
+
+import (
+	"sort"
+	"strings"
+)
+
+// Query answers questions about a call graph: who calls a
+// function, what it calls, and how one reaches another.
+type Query struct {
+	res *StaticAnalyseResult
+	// qualified name -> funcs
+	byName map[string][]*Func
+}
+
+// NewQuery indexes named functions of `res` by their qualified name
+func NewQuery(res *StaticAnalyseResult) *Query {
+	q := &Query{
+		res:    res,
+		byName: make(map[string][]*Func),
+	}
+	if res.Callgraph == nil {
+		return q
+	}
+	for _, fn := range q.sortedFuncs() {
+		if fn.Name == "" {
+			// function literals
+			continue
+		}
+		name := FuncName(fn)
+		q.byName[name] = append(q.byName[name], fn)
+	}
+	return q
+}
+
+// FuncName is the qualified name of `fn`: {pkg}.{name} for functions,
+// and {pkg}.{type}.{name} or {pkg}.(*{type}).{name} for methods.
+// It is empty for function literals.
+func FuncName(fn *Func) string {
+	if fn.Name == "" {
+		return ""
+	}
+	qname := fn.QuanlifiedName
+	if strings.HasPrefix(qname, "*") {
+		// *T.M -> (*T).M
+		idx := strings.LastIndex(qname, ".")
+		qname = "(" + qname[:idx] + ")" + qname[idx:]
+	}
+	return fn.Pkg + "." + qname
+}
+
+// Lookup finds functions by qualified name, see FuncName.
+// Methods of pointer receivers can also be found as {pkg}.{type}.{name}.
+// There may be several results if the name is ambiguous,
+// e.g. functions of test variants of a package.
+func (c *Query) Lookup(name string) []*Func {
+	if fns := c.byName[name]; len(fns) > 0 {
+		return fns
+	}
+	// pkg.T.M -> pkg.(*T).M
+	idx := strings.LastIndex(name, ".")
+	if idx < 0 {
+		return nil
+	}
+	recvIdx := strings.LastIndex(name[:idx], ".")
+	if recvIdx < 0 {
+		return nil
+	}
+	return c.byName[name[:recvIdx+1]+"(*"+name[recvIdx+1:idx]+")"+name[idx:]]
+}
+
+// Func returns the function of `id`, nil if not found
+func (c *Query) Func(id FuncID) *Func {
+	if c.res.Callgraph == nil {
+		return nil
+	}
+	return c.res.Callgraph.Funcs[id]
+}
+
+// Callers returns functions calling `fn` directly or indirectly, nearer
+// callers first. `depth` limits the levels followed, 1 means direct callers
+// only, 0 or negative means unlimited. `fn` itself is not included.
+func (c *Query) Callers(fn *Func, depth int) []*Func {
+	return c.reach(fn, depth, func(fn *Func) []*Edge { return fn.In }, func(e *Edge) FuncID { return e.Caller })
+}
+
+// Callees is like Callers, but returns functions called by `fn`
+func (c *Query) Callees(fn *Func, depth int) []*Func {
+	return c.reach(fn, depth, func(fn *Func) []*Edge { return fn.Out }, func(e *Edge) FuncID { return e.Callee })
+}
+
+func (c *Query) reach(fn *Func, depth int, edges func(fn *Func) []*Edge, next func(e *Edge) FuncID) []*Func {
+	seen := map[FuncID]bool{fn.ID: true}
+	var result []*Func
+	level := []*Func{fn}
+	for d := 0; len(level) > 0 && (depth <= 0 || d < depth); d++ {
+		var nextLevel []*Func
+		for _, f := range level {
+			for _, e := range edges(f) {
+				id := next(e)
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+				if nf := c.Func(id); nf != nil {
+					nextLevel = append(nextLevel, nf)
+				}
+			}
+		}
+		result = append(result, nextLevel...)
+		level = nextLevel
+	}
+	return result
+}
+
+// Path returns edges of a shortest call path from `from` to `to`,
+// nil if `to` is not reachable from `from`, or they are the same.
+func (c *Query) Path(from *Func, to *Func) []*Edge {
+	if from.ID == to.ID {
+		return nil
+	}
+	// the edge reaching each func first
+	via := map[FuncID]*Edge{}
+	seen := map[FuncID]bool{from.ID: true}
+	level := []*Func{from}
+	for len(level) > 0 {
+		var nextLevel []*Func
+		for _, f := range level {
+			for _, e := range f.Out {
+				if seen[e.Callee] {
+					continue
+				}
+				seen[e.Callee] = true
+				via[e.Callee] = e
+				if e.Callee == to.ID {
+					return pathTo(via, from.ID, to.ID)
+				}
+				if nf := c.Func(e.Callee); nf != nil {
+					nextLevel = append(nextLevel, nf)
+				}
+			}
+		}
+		level = nextLevel
+	}
+	return nil
+}
+
+func pathTo(via map[FuncID]*Edge, from FuncID, to FuncID) []*Edge {
+	var path []*Edge
+	for id := to; id != from; id = via[id].Caller {
+		path = append(path, via[id])
+	}
+	// reverse
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// Mains returns functions main and init, which are roots of a program
+func (c *Query) Mains() []*Func {
+	var mains []*Func
+	for _, fn := range c.sortedFuncs() {
+		if (fn.Name == "main" || fn.Name == "init") && fn.QuanlifiedName == fn.Name {
+			mains = append(mains, fn)
+		}
+	}
+	return mains
+}
+
+// Unreachable returns functions not reachable from `roots`, default Mains().
+// Functions only called by package level var initializers are reported
+// as well, because such calls belong to synthetic init functions.
+func (c *Query) Unreachable(roots []*Func) []*Func {
+	if roots == nil {
+		roots = c.Mains()
+	}
+	reached := make(map[FuncID]bool)
+	for _, root := range roots {
+		reached[root.ID] = true
+		for _, fn := range c.Callees(root, 0) {
+			reached[fn.ID] = true
+		}
+	}
+	var result []*Func
+	for _, fn := range c.sortedFuncs() {
+		if !reached[fn.ID] {
+			result = append(result, fn)
+		}
+	}
+	return result
+}
+
+func (c *Query) sortedFuncs() []*Func {
+	if c.res.Callgraph == nil {
+		return nil
+	}
+	funcs := make([]*Func, 0, len(c.res.Callgraph.Funcs))
+	for _, fn := range c.res.Callgraph.Funcs {
+		funcs = append(funcs, fn)
+	}
+	sort.Slice(funcs, func(i, j int) bool {
+		return funcs[i].ID < funcs[j].ID
+	})
+	return funcs
+}
@@ -0,0 +1,121 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/xhd2015/go-inspect/inspect/load"
+)
+
+func newTestGraph() *StaticAnalyseResult {
+	funcs := map[FuncID]*Func{
+		0: {ID: 0, Pkg: "app", Name: "main", QuanlifiedName: "main"},
+		1: {ID: 1, Pkg: "app/api", Name: "Handle", QuanlifiedName: "Handle"},
+		2: {ID: 2, Pkg: "app/svc", Name: "Query", QuanlifiedName: "*Service.Query"},
+		3: {ID: 3, Pkg: "app/db", Name: "Exec", QuanlifiedName: "Exec"},
+		4: {ID: 4, Pkg: "app/api", Name: "Admin", QuanlifiedName: "Admin"},
+		5: {ID: 5, Pkg: "app/api", Name: "Unused", QuanlifiedName: "Unused"},
+	}
+	link := func(caller, callee FuncID) {
+		e := &Edge{Caller: caller, Callee: callee}
+		funcs[caller].Out = append(funcs[caller].Out, e)
+		funcs[callee].In = append(funcs[callee].In, e)
+	}
+	link(0, 1)
+	link(1, 2)
+	link(2, 3)
+	link(0, 4)
+	link(4, 3)
+	link(5, 2)
+	return &StaticAnalyseResult{Callgraph: &CallGraph{Funcs: funcs}}
+}
+
+func funcNames(fns []*Func) []string {
+	names := make([]string, 0, len(fns))
+	for _, fn := range fns {
+		names = append(names, FuncName(fn))
+	}
+	return names
+}
+
+// go test -run TestQuery -v ./analysis/
+func TestQuery(t *testing.T) {
+	q := NewQuery(newTestGraph())
+
+	query := q.Lookup("app/svc.(*Service).Query")
+	if len(query) != 1 || query[0].ID != 2 {
+		t.Fatalf("expect app/svc.(*Service).Query found, actual: %v", funcNames(query))
+	}
+	if fns := q.Lookup("app/svc.Service.Query"); len(fns) != 1 || fns[0] != query[0] {
+		t.Fatalf("expect pointer method found without *, actual: %v", funcNames(fns))
+	}
+	exec := q.Lookup("app/db.Exec")[0]
+
+	callers := funcNames(q.Callers(exec, 1))
+	if len(callers) != 2 || callers[0] != "app/svc.(*Service).Query" || callers[1] != "app/api.Admin" {
+		t.Fatalf("unexpected direct callers: %v", callers)
+	}
+	callers = funcNames(q.Callers(exec, 0))
+	if len(callers) != 5 || callers[4] != "app.main" {
+		t.Fatalf("unexpected transitive callers: %v", callers)
+	}
+	callees := funcNames(q.Callees(q.Lookup("app.main")[0], 2))
+	if len(callees) != 4 {
+		t.Fatalf("expect 4 callees within depth 2, actual: %v", callees)
+	}
+
+	path := q.Path(q.Lookup("app.main")[0], exec)
+	if len(path) != 2 || path[0].Callee != 4 || path[1].Callee != 3 {
+		t.Fatalf("expect path main -> Admin -> Exec, actual: %+v", path)
+	}
+	if q.Path(exec, q.Lookup("app.main")[0]) != nil {
+		t.Fatalf("expect no path from Exec to main")
+	}
+
+	unreachable := funcNames(q.Unreachable(nil))
+	if len(unreachable) != 1 || unreachable[0] != "app/api.Unused" {
+		t.Fatalf("unexpected unreachable: %v", unreachable)
+	}
+}
+
+// go test -run TestQueryCallGraph -v ./analysis/
+func TestQueryCallGraph(t *testing.T) {
+	_, res, err := LoadCallGraphWithOptions([]string{"./testdata/simple"}, &load.LoadOptions{}, &CallGraphOptions{Algorithm: AlgorithmCHA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := NewQuery(res)
+	mains := q.Lookup("github.com/xhd2015/go-inspect/analysis/testdata/simple.main")
+	str := q.Lookup("github.com/xhd2015/go-inspect/analysis/testdata/simple/biz.myStringer.String")
+	if len(mains) != 1 || len(str) != 1 {
+		t.Fatalf("expect main and myStringer.String found, actual: %v %v", funcNames(mains), funcNames(str))
+	}
+	path := q.Path(mains[0], str[0])
+	if len(path) != 2 || FuncName(q.Func(path[0].Callee)) != "github.com/xhd2015/go-inspect/analysis/testdata/simple/biz.Status.Run" {
+		t.Fatalf("expect main -> Status.Run -> myStringer.String, actual: %+v", path)
+	}
+	if path[0].Site == nil || path[0].Site.Line == 0 {
+		t.Fatalf("expect call site of Status.Run")
+	}
+}
+
+// go test -run TestGraph -v ./analysis/
+func TestGraph(t *testing.T) {
+	g := Graph(newTestGraph(), &GraphOptions{
+		Cluster: ClusterByPkg,
+		Highlight: func(fn *Func) bool {
+			return fn.Pkg == "app/db"
+		},
+	})
+	if len(g.Nodes) != 6 || len(g.Edges) != 6 {
+		t.Fatalf("expect 6 nodes and 6 edges, actual: %d %d", len(g.Nodes), len(g.Edges))
+	}
+	for _, n := range g.Nodes {
+		if n.ID == "3" && (!n.Highlight || n.Cluster != "app/db" || n.Label != "Exec") {
+			t.Fatalf("unexpected node of Exec: %+v", n)
+		}
+	}
+	g = Graph(newTestGraph(), nil)
+	if g.Nodes[2].Label != "svc.*Service.Query" || g.Nodes[2].Cluster != "" {
+		t.Fatalf("unexpected node of Query: %+v", g.Nodes[2])
+	}
+}
@@ -19,6 +19,8 @@ import (
 // StaticAnalyseResult see pointer.Result
 type FuncID = int
 type StaticAnalyseResult struct {
+	// Algorithm is the algorithm building the call graph
+	Algorithm Algorithm
 	Callgraph *CallGraph
 	Warnings  []*Warning
 }
@@ -79,11 +81,23 @@ type StaticCallFunc struct {
 }
 
 func LoadCallGraph(args []string, opts *load.LoadOptions) (g inspect.Global, res *StaticAnalyseResult, err error) {
+	return LoadCallGraphWithOptions(args, opts, nil)
+}
+
+// LoadCallGraphWithOptions is like LoadCallGraph, with
+// the algorithm chosen by `cgOpts`
+func LoadCallGraphWithOptions(args []string, opts *load.LoadOptions, cgOpts *CallGraphOptions) (g inspect.Global, res *StaticAnalyseResult, err error) {
 	g, err = load.LoadPackages(args, opts)
 	if err != nil {
 		return
 	}
+	res, err = findCallGraphOfMainModule(g, cgOpts)
+	return
+}
 
+// findCallGraphOfMainModule analyses starter packages of `g`,
+// functions outside the main module are not analysed
+func findCallGraphOfMainModule(g inspect.Global, cgOpts *CallGraphOptions) (*StaticAnalyseResult, error) {
 	pkgs := g.LoadInfo().StarterPkgs()
 	var loadPkgs []*packages.Package
 	for _, p := range pkgs {
@@ -104,30 +118,40 @@ func LoadCallGraph(args []string, opts *load.LoadOptions) (g inspect.Global, res
 		}
 		return true
 	})
-	res, err = FindCallGraph(g, loadPkgs)
-	return
+	return FindCallGraphWithOptions(g, loadPkgs, cgOpts)
 }
 
+// FindCallGraph builds the call graph of `mainPkgs`, only functions
+// declared in the main module are included. Calls from or to other
+// functions, such as std functions and package initializers, are
+// dropped, instead of being recorded as calls of the function of ID 0.
 func FindCallGraph(g inspect.Global, mainPkgs []*packages.Package) (*StaticAnalyseResult, error) {
+	return FindCallGraphWithOptions(g, mainPkgs, nil)
+}
+
+// FindCallGraphWithOptions is like FindCallGraph, with
+// the algorithm chosen by `cgOpts`
+func FindCallGraphWithOptions(g inspect.Global, mainPkgs []*packages.Package, cgOpts *CallGraphOptions) (*StaticAnalyseResult, error) {
 	prog, ssaPkgs := ssautil.AllPackages(mainPkgs, 0)
 	// NOTE: Build() must be called to get full relationship
 	// otherwise only main pkg will appear
 	prog.Build()
 
-	config := &pointer.Config{
-		Mains:          ssaPkgs,
-		BuildCallGraph: true,
-	}
-
-	ssaRes, err := pointer.Analyze(config)
+	algo := cgOpts.algorithm()
+	ssaGraph, _, err := buildSSACallGraph(prog, ssaPkgs, algo)
 	if err != nil {
 		return nil, err
 	}
-	return BuildCallGraphFromSSAResult(g, ssaRes), nil
+	return &StaticAnalyseResult{
+		Algorithm: algo,
+		Callgraph: buildCallGraph(g, ssaGraph),
+		// Warnings:  buildWarnings(g, ssaRes.Warnings), // don't build warnings because we know there are much of them due to delete of AST fles
+	}, nil
 }
 
 func BuildCallGraphFromSSAResult(g inspect.Global, ssaRes *pointer.Result) *StaticAnalyseResult {
 	return &StaticAnalyseResult{
+		Algorithm: AlgorithmPointer,
 		Callgraph: buildCallGraph(g, ssaRes.CallGraph),
 		// Warnings:  buildWarnings(g, ssaRes.Warnings), // don't build warnings because we know there are much of them due to delete of AST fles
 	}
@@ -162,13 +186,18 @@ func buildCallGraph(g inspect.Global, ssaGraph *callgraph.Graph) *CallGraph {
 		fn.Out = buildEdges(g, ssaNode.Out, ssaMap)
 	}
 
+	var root FuncID
+	if ssaGraph.Root != nil {
+		root = ssaMap[ssaGraph.Root.Func]
+	}
 	return &CallGraph{
-		Root:  ssaMap[ssaGraph.Root.Func],
+		Root:  root,
 		Funcs: funcs,
 	}
 }
 func buildFunc(g inspect.Global, ssaFunc *ssa.Function) *Func {
-	if ssaFunc.Synthetic != "" {
+	// the root of graphs other than pointer's has no func
+	if ssaFunc == nil || ssaFunc.Synthetic != "" {
 		return nil
 	}
 	// the node is created by ssa, only position reserved
@@ -214,6 +243,12 @@ func buildFunc(g inspect.Global, ssaFunc *ssa.Function) *Func {
 func buildEdges(g inspect.Global, ssaEdges []*callgraph.Edge, ssaMap map[*ssa.Function]FuncID) []*Edge {
 	edges := make([]*Edge, 0, len(ssaEdges))
 	for _, edge := range ssaEdges {
+		// skip edges from or to functions not built
+		_, callerOK := ssaMap[edge.Caller.Func]
+		_, calleeOK := ssaMap[edge.Callee.Func]
+		if !callerOK || !calleeOK {
+			continue
+		}
 		edges = append(edges, buildEdge(g, edge, ssaMap))
 	}
 	return edges
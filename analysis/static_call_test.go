@@ -21,3 +21,63 @@ func TestLoadCallGraph(t *testing.T) {
 	ioutil.WriteFile("./testdata/simple/callgraph.tmp.json", resJSON, 0777)
 	_ = g
 }
+
+// go test -run TestLoadCallGraphAlgorithms -v ./analysis/
+func TestLoadCallGraphAlgorithms(t *testing.T) {
+	for _, algo := range []Algorithm{AlgorithmStatic, AlgorithmCHA, AlgorithmRTA, AlgorithmVTA} {
+		_, res, err := LoadCallGraphWithOptions([]string{"./testdata/simple"}, &load.LoadOptions{}, &CallGraphOptions{Algorithm: algo})
+		if err != nil {
+			t.Fatalf("%s: %v", algo, err)
+		}
+		if res.Algorithm != algo {
+			t.Fatalf("expect algorithm %s, actual: %s", algo, res.Algorithm)
+		}
+		callees := make(map[string]bool)
+		for _, fn := range res.Callgraph.Funcs {
+			if fn.QuanlifiedName != "Status.Run" {
+				continue
+			}
+			for _, out := range fn.Out {
+				callees[res.Callgraph.Funcs[out.Callee].QuanlifiedName] = true
+			}
+		}
+		// the call of fmt.Stringer.String is dynamic
+		expectDynamic := algo != AlgorithmStatic
+		if callees["myStringer.String"] != expectDynamic {
+			t.Fatalf("%s: expect Status.Run calls myStringer.String: %v, actual callees: %v", algo, expectDynamic, callees)
+		}
+	}
+}
+
+// go test -run TestCallGraphEdges -v ./analysis/
+func TestCallGraphEdges(t *testing.T) {
+	_, res, err := LoadCallGraphWithOptions([]string{"./testdata/simple"}, &load.LoadOptions{}, &CallGraphOptions{Algorithm: AlgorithmStatic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	funcs := res.Callgraph.Funcs
+	hasEdge := func(edges []*Edge, caller FuncID, callee FuncID) bool {
+		for _, e := range edges {
+			if e.Caller == caller && e.Callee == callee {
+				return true
+			}
+		}
+		return false
+	}
+	// calls of functions not built, e.g. fmt.Printf outside the
+	// main module, must not appear as calls of the function of ID 0
+	for id, fn := range funcs {
+		for _, out := range fn.Out {
+			callee := funcs[out.Callee]
+			if out.Caller != id || callee == nil || !hasEdge(callee.In, id, out.Callee) {
+				t.Fatalf("unexpected out edge of %s: %d -> %d", fn.QuanlifiedName, out.Caller, out.Callee)
+			}
+		}
+		for _, in := range fn.In {
+			caller := funcs[in.Caller]
+			if in.Callee != id || caller == nil || !hasEdge(caller.Out, in.Caller, id) {
+				t.Fatalf("unexpected in edge of %s: %d -> %d", fn.QuanlifiedName, in.Caller, in.Callee)
+			}
+		}
+	}
+}
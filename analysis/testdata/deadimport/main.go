@@ -0,0 +1,13 @@
+package main
+
+import (
+	"github.com/xhd2015/go-inspect/analysis/testdata/deadimport/consts"
+	_ "github.com/xhd2015/go-inspect/analysis/testdata/deadimport/plugin"
+	"github.com/xhd2015/go-inspect/analysis/testdata/deadimport/registry"
+	"github.com/xhd2015/go-inspect/analysis/testdata/deadimport/used"
+)
+
+// no std imports, the analysis only builds packages here
+func main() {
+	println(used.Hello(), consts.Version, len(registry.Names()))
+}
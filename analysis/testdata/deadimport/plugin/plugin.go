@@ -0,0 +1,9 @@
+package plugin
+
+import "github.com/xhd2015/go-inspect/analysis/testdata/deadimport/registry"
+
+func init() {
+	registry.Register("plugin")
+}
+
+func Unused() {}
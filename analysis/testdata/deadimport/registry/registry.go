@@ -0,0 +1,11 @@
+package registry
+
+var names []string
+
+func Register(name string) {
+	names = append(names, name)
+}
+
+func Names() []string {
+	return names
+}
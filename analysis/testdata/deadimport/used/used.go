@@ -0,0 +1,9 @@
+package used
+
+import "github.com/xhd2015/go-inspect/analysis/testdata/deadimport/heavy"
+
+// Hello only refers to a type of heavy
+func Hello() string {
+	var opt heavy.Option
+	return "hello" + string(opt)
+}
@@ -0,0 +1,13 @@
+package heavy
+
+type Option string
+
+var table = build()
+
+func build() map[string]int {
+	return map[string]int{"a": 1}
+}
+
+func Lookup(name string) int {
+	return table[name]
+}
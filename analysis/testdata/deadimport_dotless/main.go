@@ -0,0 +1,11 @@
+package main
+
+import (
+	"app/consts"
+	"app/used"
+)
+
+// module path without dot, packages must not be taken as std
+func main() {
+	println(used.Hello(), consts.Version)
+}
@@ -0,0 +1,9 @@
+package used
+
+import "app/heavy"
+
+// Hello only refers to a type of heavy
+func Hello() string {
+	var opt heavy.Option
+	return "hello" + string(opt)
+}
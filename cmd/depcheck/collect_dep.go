@@ -1,28 +1,48 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"go/token"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
+	"github.com/xhd2015/go-inspect/analysis"
 	"github.com/xhd2015/go-inspect/depcheck"
+	"github.com/xhd2015/go-inspect/graph"
+	"github.com/xhd2015/go-inspect/inspect/load"
+	"github.com/xhd2015/go-inspect/sh"
 	"golang.org/x/tools/go/packages"
 )
 
 const help = `
 depcheck [FLAGS] <args> 
+depcheck diff [FLAGS] <old> <new> [packages]
 
 Options:
      -mod=vendor         load with -mod=vendor 
      --project-dir DIR   project dir
   -o OUTPUT              write output to file
 	 --check PKG         check specific package
+	 --rules FILE        check imports against rules in json, exit non-zero on violations
 	 --json              output json
+	 --analyze           analyze import cycles across test variants, layers, fan-in/fan-out and instability
+	 --tags TAGS         build tags, repeat to merge variants of different tags with --analyze
+	 --no-std            exclude std packages from --analyze
+	 --modules           group packages by module, with versions, replaces and vendor/modules.txt consistency
+	 --size              with --modules, build the main package and estimate size of each module by go tool nm
+	 --binary FILE       with --modules, estimate size of each module in a built binary
+	 --dead-imports      list imported packages of which no function is reached from main, or only needed for init
+	 --algorithm ALGO    call graph algorithm of --dead-imports: static, cha, rta(default), vta or pointer
+	 --include-std       include std packages in --dead-imports
+	 --format FORMAT     output graph as dot, mermaid or graphml
+	 --cluster module    group packages by module in graph output
+	 --highlight PATTERN highlight matched packages in graph output, e.g. a/b/...
 	 --depth N           max depth to print 
 	 --pretty            pretty json output 
      --version           show version
@@ -30,6 +50,12 @@ Options:
 
 Examples:
   depcheck --check some-git.com/pkg/a -mod=vendor ./src
+  depcheck --rules depcheck.json ./...
+  depcheck diff origin/master HEAD
+  depcheck --modules --size ./cmd/server
+  depcheck --dead-imports ./cmd/server
+  depcheck --analyze --no-std --tags '' --tags integration ./...
+  depcheck --format dot --cluster module ./ | dot -Tsvg -o deps.svg
 `
 const version = "0.0.1"
 
@@ -46,6 +72,9 @@ func main() {
 // they have different algorithms
 func run(args []string) error {
 	n := len(args)
+	if n > 0 && args[0] == "diff" {
+		return runDiff(args[1:])
+	}
 
 	var showVersion bool
 	var showHelp bool
@@ -57,6 +86,19 @@ func run(args []string) error {
 	var output string
 	var pretty bool
 	var fmtJSON bool
+	var format string
+	var cluster string
+	var highlights []string
+	var rulesFile string
+	var analyze bool
+	var noStd bool
+	var tagsList []string
+	var modules bool
+	var size bool
+	var binary string
+	var deadImports bool
+	var algorithm string
+	var includeStd bool
 
 	var maxDepth int
 	for i := 0; i < n; i++ {
@@ -85,6 +127,105 @@ func run(args []string) error {
 			fmtJSON = true
 			continue
 		}
+		if arg == "--dead-imports" {
+			deadImports = true
+			continue
+		}
+		if arg == "--include-std" {
+			includeStd = true
+			continue
+		}
+		if arg == "--algorithm" {
+			if i+1 >= n {
+				return fmt.Errorf("--algorithm requires value")
+			}
+			algorithm = args[i+1]
+			i++
+			continue
+		}
+		if strings.HasPrefix(arg, "--algorithm=") {
+			algorithm = strings.TrimPrefix(arg, "--algorithm=")
+			continue
+		}
+		if arg == "--modules" {
+			modules = true
+			continue
+		}
+		if arg == "--size" {
+			size = true
+			continue
+		}
+		if arg == "--binary" {
+			if i+1 >= n {
+				return fmt.Errorf("--binary requires value")
+			}
+			binary = args[i+1]
+			i++
+			continue
+		}
+		if strings.HasPrefix(arg, "--binary=") {
+			binary = strings.TrimPrefix(arg, "--binary=")
+			continue
+		}
+		if arg == "--analyze" {
+			analyze = true
+			continue
+		}
+		if arg == "--no-std" {
+			noStd = true
+			continue
+		}
+		if arg == "--tags" {
+			if i+1 >= n {
+				return fmt.Errorf("--tags requires value")
+			}
+			tagsList = append(tagsList, args[i+1])
+			i++
+			continue
+		}
+		if strings.HasPrefix(arg, "--tags=") {
+			tagsList = append(tagsList, strings.TrimPrefix(arg, "--tags="))
+			continue
+		}
+		if arg == "--rules" {
+			if i+1 >= n {
+				return fmt.Errorf("--rules requires value")
+			}
+			rulesFile = args[i+1]
+			i++
+			continue
+		}
+		if strings.HasPrefix(arg, "--rules=") {
+			rulesFile = strings.TrimPrefix(arg, "--rules=")
+			continue
+		}
+		if arg == "--format" || arg == "--cluster" || arg == "--highlight" {
+			if i+1 >= n {
+				return fmt.Errorf("%s requires value", arg)
+			}
+			switch arg {
+			case "--format":
+				format = args[i+1]
+			case "--cluster":
+				cluster = args[i+1]
+			default:
+				highlights = append(highlights, args[i+1])
+			}
+			i++
+			continue
+		}
+		if strings.HasPrefix(arg, "--format=") {
+			format = strings.TrimPrefix(arg, "--format=")
+			continue
+		}
+		if strings.HasPrefix(arg, "--cluster=") {
+			cluster = strings.TrimPrefix(arg, "--cluster=")
+			continue
+		}
+		if strings.HasPrefix(arg, "--highlight=") {
+			highlights = append(highlights, strings.TrimPrefix(arg, "--highlight="))
+			continue
+		}
 		if arg == "--depth" {
 			if i+1 >= n {
 				return fmt.Errorf("--depth requires value")
@@ -161,15 +302,46 @@ func run(args []string) error {
 		fmt.Println(strings.TrimPrefix(help, "\n"))
 		return nil
 	}
+	var graphFormat graph.Format
+	if format != "" {
+		var err error
+		graphFormat, err = graph.ParseFormat(format)
+		if err != nil {
+			return err
+		}
+	}
+	if cluster != "" && cluster != "module" {
+		return fmt.Errorf("--cluster: expect module, actual: %s", cluster)
+	}
+	var rules *depcheck.Rules
+	if rulesFile != "" {
+		var err error
+		rules, err = depcheck.LoadRules(rulesFile)
+		if err != nil {
+			return err
+		}
+	}
 	var buildFlags []string
 	if mod != "" {
 		buildFlags = append(buildFlags, "-mod="+mod)
 	}
+	if deadImports {
+		return reportDeadImports(projectDir, buildFlags, remainArgs, algorithm, includeStd, fmtJSON, pretty, output)
+	}
+	if analyze {
+		return analyzeImports(projectDir, buildFlags, tagsList, remainArgs, noStd, fmtJSON, pretty, output)
+	}
+	if len(tagsList) > 1 {
+		return fmt.Errorf("multiple --tags requires --analyze")
+	}
+	if len(tagsList) == 1 {
+		buildFlags = append(buildFlags, "-tags="+tagsList[0])
+	}
 	fset := token.NewFileSet()
 	cfg := &packages.Config{
 		Dir: projectDir,
 		// to have syntax, must also set NeedFiles
-		Mode:       packages.NeedDeps | packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedImports,
+		Mode:       packages.NeedDeps | packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedImports | packages.NeedModule,
 		Fset:       fset,
 		Tests:      test,
 		BuildFlags: buildFlags,
@@ -178,13 +350,20 @@ func run(args []string) error {
 	if err != nil {
 		return err
 	}
+	if modules {
+		return reportModules(pkgs, projectDir, buildFlags, remainArgs, size, binary, fmtJSON, pretty, output)
+	}
 	deps, pkgMapping, err := depcheck.CollectDeps(pkgs, &depcheck.CollectOptions{
-		NeedDependedBy: false,
+		// all import edges are needed by graph and rules
+		NeedDependedBy: graphFormat != "" || rules != nil,
 	})
 
 	if err != nil {
 		return err
 	}
+	if rules != nil {
+		return checkRules(deps, pkgMapping, rules, depcheck.CollectStdPkgs(pkgs), fmtJSON, pretty, output)
+	}
 	if len(checks) > 0 {
 		for _, check := range checks {
 			if pkgMapping[check] == nil {
@@ -197,7 +376,17 @@ func run(args []string) error {
 		deps = depcheck.LimitDepths(deps, maxDepth)
 	}
 	var outputData []byte
-	if fmtJSON {
+	if graphFormat != "" {
+		graphOpts := &depcheck.GraphOptions{
+			Highlight: depcheck.MatchPatterns(append(highlights, checks...), depcheck.CollectStdPkgs(pkgs)),
+		}
+		if cluster == "module" {
+			graphOpts.Cluster = depcheck.ClusterByModule(pkgs)
+		}
+		var buf bytes.Buffer
+		err = graph.Write(&buf, depcheck.Graph(deps, graphOpts), graphFormat)
+		outputData = buf.Bytes()
+	} else if fmtJSON {
 		if pretty {
 			outputData, err = json.MarshalIndent(deps, "", "    ")
 		} else {
@@ -217,3 +406,204 @@ func run(args []string) error {
 	}
 	return nil
 }
+
+func checkRules(deps []*depcheck.PkgDepInfo, pkgMapping map[string]*depcheck.PkgDepInfo, rules *depcheck.Rules, std depcheck.StdPkgs, fmtJSON bool, pretty bool, output string) error {
+	violations := depcheck.CheckRules(deps, pkgMapping, rules, std)
+	if len(violations) == 0 && !fmtJSON {
+		return nil
+	}
+	var outputData []byte
+	if fmtJSON {
+		if violations == nil {
+			violations = []*depcheck.Violation{}
+		}
+		var err error
+		if pretty {
+			outputData, err = json.MarshalIndent(violations, "", "    ")
+		} else {
+			outputData, err = json.Marshal(violations)
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		outputData = []byte(depcheck.FormatViolations(violations))
+	}
+	if output != "" {
+		err := ioutil.WriteFile(output, outputData, 0755)
+		if err != nil {
+			return err
+		}
+	} else {
+		fmt.Println(string(outputData))
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("found %d import violation(s)", len(violations))
+	}
+	return nil
+}
+
+// analyzeImports loads packages with tests once for each tags,
+// and analyzes the merged import graph
+func analyzeImports(projectDir string, buildFlags []string, tagsList []string, args []string, noStd bool, fmtJSON bool, pretty bool, output string) error {
+	if len(tagsList) == 0 {
+		tagsList = []string{""}
+	}
+	g := depcheck.NewImportGraph()
+	std := make(depcheck.StdPkgs)
+	for _, tags := range tagsList {
+		flags := buildFlags
+		if tags != "" {
+			flags = append(flags[:len(flags):len(flags)], "-tags="+tags)
+		}
+		pkgs, err := packages.Load(&packages.Config{
+			Dir:        projectDir,
+			Mode:       packages.NeedDeps | packages.NeedName | packages.NeedImports | packages.NeedModule,
+			Tests:      true,
+			BuildFlags: flags,
+		}, args...)
+		if err != nil {
+			return err
+		}
+		g.Add(pkgs)
+		for pkg := range depcheck.CollectStdPkgs(pkgs) {
+			std[pkg] = true
+		}
+	}
+	if noStd {
+		g = g.Filter(func(pkg string) bool {
+			return !std[pkg]
+		})
+	}
+	res := g.Analyze()
+
+	var outputData []byte
+	var err error
+	if fmtJSON {
+		if pretty {
+			outputData, err = json.MarshalIndent(res, "", "    ")
+		} else {
+			outputData, err = json.Marshal(res)
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		outputData = []byte(depcheck.FormatImportAnalysis(res))
+	}
+	if output != "" {
+		return ioutil.WriteFile(output, outputData, 0755)
+	}
+	fmt.Println(string(outputData))
+	return nil
+}
+
+type modulesReport struct {
+	Modules      []*depcheck.ModuleInfo `json:"modules"`
+	VendorIssues []string               `json:"vendorIssues,omitempty"`
+	// UnknownSize is bytes of symbols not attributed to any module
+	UnknownSize int64 `json:"unknownSize,omitempty"`
+}
+
+func reportModules(pkgs []*packages.Package, projectDir string, buildFlags []string, args []string, size bool, binary string, fmtJSON bool, pretty bool, output string) error {
+	report := &modulesReport{
+		Modules: depcheck.CollectModules(pkgs),
+	}
+	var err error
+	report.VendorIssues, err = depcheck.CheckVendor(projectDir, report.Modules)
+	if err != nil {
+		return err
+	}
+	if size && binary == "" {
+		tmpDir, err := ioutil.TempDir("", "depcheck-size")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmpDir)
+		binary = filepath.Join(tmpDir, "main.bin")
+		dir := projectDir
+		if dir == "" {
+			dir = "."
+		}
+		buildArgs := append([]string{"build", "-o", binary}, buildFlags...)
+		_, _, err = sh.RunBashWithOpts([]string{
+			fmt.Sprintf("cd %s", sh.Quote(dir)),
+			"go " + sh.JoinArgs(append(buildArgs, args...)),
+		}, sh.RunBashOptions{})
+		if err != nil {
+			return err
+		}
+	}
+	if binary != "" {
+		report.UnknownSize, err = depcheck.EstimateModuleSizes(binary, report.Modules)
+		if err != nil {
+			return err
+		}
+	}
+
+	var outputData []byte
+	if fmtJSON {
+		if pretty {
+			outputData, err = json.MarshalIndent(report, "", "    ")
+		} else {
+			outputData, err = json.Marshal(report)
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		text := depcheck.FormatModules(report.Modules)
+		if report.UnknownSize > 0 {
+			text += fmt.Sprintf("\nunknown size: %d bytes", report.UnknownSize)
+		}
+		if len(report.VendorIssues) > 0 {
+			text += "\nvendor/modules.txt inconsistent:\n  " + strings.Join(report.VendorIssues, "\n  ")
+		}
+		outputData = []byte(text)
+	}
+	if output != "" {
+		return ioutil.WriteFile(output, outputData, 0755)
+	}
+	fmt.Println(string(outputData))
+	return nil
+}
+
+func reportDeadImports(projectDir string, buildFlags []string, args []string, algorithm string, includeStd bool, fmtJSON bool, pretty bool, output string) error {
+	opts := &analysis.DeadImportOptions{IncludeStd: includeStd}
+	if algorithm != "" {
+		var err error
+		opts.Algorithm, err = analysis.ParseAlgorithm(algorithm)
+		if err != nil {
+			return err
+		}
+	}
+	_, res, err := analysis.LoadDeadImports(args, &load.LoadOptions{
+		ProjectDir: projectDir,
+		BuildFlags: buildFlags,
+	}, opts)
+	if err != nil {
+		return err
+	}
+
+	var outputData []byte
+	if fmtJSON {
+		if res == nil {
+			res = []*analysis.DeadImport{}
+		}
+		if pretty {
+			outputData, err = json.MarshalIndent(res, "", "    ")
+		} else {
+			outputData, err = json.Marshal(res)
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		outputData = []byte(analysis.FormatDeadImports(res))
+	}
+	if output != "" {
+		return ioutil.WriteFile(output, outputData, 0755)
+	}
+	fmt.Println(string(outputData))
+	return nil
+}
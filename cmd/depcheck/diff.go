@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/xhd2015/go-inspect/depcheck"
+)
+
+const diffHelp = `
+depcheck diff [FLAGS] <old> <new> [packages]
+
+old and new are directories, or git revisions of
+the project dir. packages default to ./...
+
+Options:
+     -mod=vendor         load with -mod=vendor
+     --project-dir DIR   project dir, for git revisions
+     --test              include tests
+  -o OUTPUT              write output to file
+     --json              output json
+     --pretty            pretty json output
+  -h,--help              show help
+
+Examples:
+  depcheck diff origin/master HEAD
+  depcheck diff ./old ./new ./cmd/...
+`
+
+func runDiff(args []string) error {
+	n := len(args)
+	var mod string
+	var projectDir string
+	var test bool
+	var output string
+	var fmtJSON bool
+	var pretty bool
+	var remainArgs []string
+	for i := 0; i < n; i++ {
+		arg := args[i]
+		if arg == "--" {
+			remainArgs = append(remainArgs, args[i+1:]...)
+			break
+		}
+		if arg == "-h" || arg == "--help" {
+			fmt.Println(strings.TrimPrefix(diffHelp, "\n"))
+			return nil
+		}
+		if arg == "--test" {
+			test = true
+			continue
+		}
+		if arg == "--json" {
+			fmtJSON = true
+			continue
+		}
+		if arg == "--pretty" {
+			pretty = true
+			continue
+		}
+		if arg == "-o" || arg == "-mod" || arg == "--project-dir" {
+			if i+1 >= n {
+				return fmt.Errorf("%s requires value", arg)
+			}
+			switch arg {
+			case "-o":
+				output = args[i+1]
+			case "-mod":
+				mod = args[i+1]
+			default:
+				projectDir = args[i+1]
+			}
+			i++
+			continue
+		}
+		if strings.HasPrefix(arg, "-o=") {
+			output = strings.TrimPrefix(arg, "-o=")
+			continue
+		}
+		if strings.HasPrefix(arg, "-mod=") {
+			mod = strings.TrimPrefix(arg, "-mod=")
+			continue
+		}
+		if strings.HasPrefix(arg, "--project-dir=") {
+			projectDir = strings.TrimPrefix(arg, "--project-dir=")
+			continue
+		}
+		if !strings.HasPrefix(arg, "-") {
+			remainArgs = append(remainArgs, arg)
+			continue
+		}
+		return fmt.Errorf("unrecognized flag: %v", arg)
+	}
+	if len(remainArgs) < 2 {
+		return fmt.Errorf("requires old and new, see depcheck diff --help")
+	}
+	pkgArgs := remainArgs[2:]
+	if len(pkgArgs) == 0 {
+		pkgArgs = []string{"./..."}
+	}
+	var buildFlags []string
+	if mod != "" {
+		buildFlags = append(buildFlags, "-mod="+mod)
+	}
+
+	load := func(target string) (*depcheck.Snapshot, error) {
+		dir := target
+		if !isDir(target) {
+			revDir, cleanup, err := depcheck.ExportGitRevision(projectDir, target)
+			if err != nil {
+				return nil, err
+			}
+			defer cleanup()
+			dir = revDir
+		}
+		snapshot, err := depcheck.LoadSnapshot(dir, pkgArgs, buildFlags, test)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", target, err)
+		}
+		return snapshot, nil
+	}
+	from, err := load(remainArgs[0])
+	if err != nil {
+		return err
+	}
+	to, err := load(remainArgs[1])
+	if err != nil {
+		return err
+	}
+	diff := depcheck.Diff(from, to)
+
+	var outputData []byte
+	if fmtJSON {
+		if pretty {
+			outputData, err = json.MarshalIndent(diff, "", "    ")
+		} else {
+			outputData, err = json.Marshal(diff)
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		outputData = []byte(depcheck.FormatDepDiff(diff))
+	}
+	if output != "" {
+		return ioutil.WriteFile(output, outputData, 0755)
+	}
+	fmt.Println(string(outputData))
+	return nil
+}
+
+func isDir(path string) bool {
+	stat, err := os.Stat(path)
+	return err == nil && stat.IsDir()
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/xhd2015/go-inspect/project"
+)
+
+const buildHelp = `
+go-inspect %s [FLAGS] <packages>%s
+
+Rewrite packages with plugins, then %s.
+
+Options:
+  --project-dir DIR   project dir, default current dir
+  --plugin NAMES      comma separated plugins to enable, available: %s
+  --force             ignore caches
+  --concurrency N     number of packages rewritten in parallel, default 1
+  --build-strategy S  copy or overlay, overlay builds with -overlay
+                      instead of copying modules, default copy
+  --debug             build with -gcflags="all=-N -l"
+  --go-binary GO      go binary used to build
+  --verbose           verbose
+  -o OUTPUT           output binary%s
+  -h,--help           show help
+
+Flags of go build are passed to go, e.g. -mod, -tags, -ldflags, -race. -gcflags is merged with the one added for --debug and trimpath.%s
+`
+
+type commandHelp struct {
+	args   string
+	action string
+	extra  string
+}
+
+var commandHelps = map[string]*commandHelp{
+	"build": {
+		action: "build them like go build",
+	},
+	"test": {
+		args:   " [-args TEST_ARGS...]",
+		action: "run go test over them in the rewrite root, like go test ./...",
+		extra:  "\nFlags of go test are passed to tests, e.g. -run, -v, -count, -timeout, -coverprofile.",
+	},
+	"run": {
+		args:   " [ARGS...]",
+		action: "build and run the program like go run",
+	},
+	"rewrite-only": {
+		action: "print the rewrite root without building",
+	},
+}
+
+func printHelp(cmd string) {
+	h := commandHelps[cmd]
+	outputNote := ""
+	if cmd == "test" {
+		outputNote = ", compile and run the test binary of a single package instead"
+	}
+	fmt.Println(strings.TrimPrefix(fmt.Sprintf(buildHelp, cmd, h.args, h.action, pluginNames(), outputNote, h.extra), "\n"))
+}
+
+// exitCodeError makes go-inspect exit with the
+// same code as the program it ran
+type exitCodeError struct {
+	code int
+}
+
+func (c *exitCodeError) Error() string {
+	return fmt.Sprintf("exit status %d", c.code)
+}
+
+func runCommand(cmd string, args []string) error {
+	opts, err := parseFlags(cmd, args)
+	if err != nil {
+		return err
+	}
+	if opts.showHelp {
+		printHelp(cmd)
+		return nil
+	}
+	err = usePlugins(opts.plugins)
+	if err != nil {
+		return err
+	}
+	switch cmd {
+	case "build":
+		res, err := rewrite(opts, opts.output, false, false)
+		if err != nil {
+			return err
+		}
+		if opts.verbose {
+			fmt.Printf("%s\n", res.Output)
+		}
+		return nil
+	case "rewrite-only":
+		var metaRoot string
+		rewriteOpts := newRewriteOpts(opts, "", false)
+		rewriteOpts.SkipBuild = true
+		rewriteOpts.OnRewriteMetaRoot = func(rewriteMeta string) {
+			metaRoot = rewriteMeta
+		}
+		_, err := project.TryRewrite(opts.args, rewriteOpts)
+		if err != nil {
+			return err
+		}
+		fmt.Println(filepath.Join(metaRoot, "src"))
+		return nil
+	case "test", "run":
+		if cmd == "test" && opts.output == "" {
+			return runTest(opts)
+		}
+		if cmd == "run" && len(opts.args) == 0 {
+			return fmt.Errorf("requires package to run")
+		}
+		if cmd == "test" && len(opts.args) > 1 {
+			return fmt.Errorf("-o requires a single package, given: %s", strings.Join(opts.args, " "))
+		}
+		output := opts.output
+		if output == "" {
+			tmpDir, err := ioutil.TempDir("", "go-inspect")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(tmpDir)
+			output = filepath.Join(tmpDir, cmd+".bin")
+		}
+		forTest := cmd == "test"
+		if forTest && hasCoverProfile(opts.testFlags) && !contains(opts.buildFlags, "-cover") {
+			opts.buildFlags = append(opts.buildFlags, "-cover")
+		}
+		res, err := rewrite(opts, output, forTest, false)
+		if err != nil {
+			return err
+		}
+
+		var runArgs []string
+		var dir string
+		if forTest {
+			runArgs = append(opts.testFlags, opts.testArgs...)
+			// go test runs test binary in the package dir
+			dir, err = pkgDir(opts)
+			if err != nil {
+				return err
+			}
+		} else {
+			runArgs = opts.runArgs
+		}
+		return execBinary(res.Output, runArgs, dir, res.SourceMap)
+	default:
+		return fmt.Errorf("unrecognized command: %s", cmd)
+	}
+}
+
+// runTest runs go test over all packages in the rewrite root,
+// outputs are printed as go test does
+func runTest(opts *options) error {
+	testOpts := &project.TestOptions{
+		Output: os.Stdout,
+	}
+	for _, flag := range opts.testFlags {
+		// go test writes a single merged profile, and
+		// resolves relative path against the rewrite root
+		if strings.HasPrefix(flag, "-test.coverprofile=") {
+			coverProfile, err := filepath.Abs(strings.TrimPrefix(flag, "-test.coverprofile="))
+			if err != nil {
+				return err
+			}
+			testOpts.CoverProfile = coverProfile
+			continue
+		}
+		testOpts.Flags = append(testOpts.Flags, flag)
+	}
+	if len(opts.testArgs) > 0 {
+		testOpts.Flags = append(testOpts.Flags, "-args")
+		testOpts.Flags = append(testOpts.Flags, opts.testArgs...)
+	}
+	rewriteOpts := newRewriteOpts(opts, "", false)
+	rewriteOpts.BuildOpts.Test = testOpts
+	res, err := project.TryRewrite(opts.args, rewriteOpts)
+	if err != nil {
+		return err
+	}
+	if !res.Test.Passed {
+		code := res.Test.ExitCode
+		if code == 0 {
+			code = 1
+		}
+		return &exitCodeError{code: code}
+	}
+	return nil
+}
+
+func newRewriteOpts(opts *options, output string, forTest bool) *project.RewriteOpts {
+	return &project.RewriteOpts{
+		Concurrency:   opts.concurrency,
+		BuildStrategy: project.BuildStrategy(opts.buildStrategy),
+		BuildOpts: &project.BuildOpts{
+			ProjectDir: opts.projectDir,
+			Verbose:    opts.verbose,
+			Force:      opts.force,
+			Debug:      opts.debug,
+			Output:     output,
+			ForTest:    forTest,
+			GoFlags:    opts.goFlags,
+			BuildFlags: opts.buildFlags,
+			GCFlags:    opts.gcflags,
+			GoBinary:   opts.goBinary,
+		},
+	}
+}
+
+func rewrite(opts *options, output string, forTest bool, dryRun bool) (*project.RewriteResult, error) {
+	rewriteOpts := newRewriteOpts(opts, output, forTest)
+	rewriteOpts.DryRun = dryRun
+	return project.TryRewrite(opts.args, rewriteOpts)
+}
+
+// execBinary runs `binary`, positions in its outputs,
+// e.g. stack traces, are translated by `sourceMap`
+func execBinary(binary string, args []string, dir string, sourceMap *project.SourceMap) error {
+	stdout := sourceMap.Writer(os.Stdout, "")
+	stderr := sourceMap.Writer(os.Stderr, "")
+	defer stdout.Flush()
+	defer stderr.Flush()
+	cmd := exec.Command(binary, args...)
+	cmd.Dir = dir
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return &exitCodeError{code: exitErr.ExitCode()}
+		}
+		return err
+	}
+	return nil
+}
+
+// pkgDir returns the dir of the tested package, only
+// relative dir is recognized, otherwise the project dir
+func pkgDir(opts *options) (string, error) {
+	dir := opts.projectDir
+	if dir == "" {
+		var err error
+		dir, err = os.Getwd()
+		if err != nil {
+			return "", err
+		}
+	}
+	if len(opts.args) == 1 && (strings.HasPrefix(opts.args[0], "./") || strings.HasPrefix(opts.args[0], "../")) {
+		subDir := filepath.Join(dir, opts.args[0])
+		if stat, err := os.Stat(subDir); err == nil && stat.IsDir() {
+			return subDir, nil
+		}
+	}
+	return dir, nil
+}
+
+func hasCoverProfile(flags []string) bool {
+	for _, flag := range flags {
+		if strings.HasPrefix(flag, "-test.coverprofile") {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
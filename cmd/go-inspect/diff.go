@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+const diffHelp = `
+go-inspect diff [FLAGS] <packages>
+
+Run plugins without building, and show rewritten files
+as a unified diff against their originals. Generated files
+are shown as new files.
+
+Options:
+  --project-dir DIR   project dir, default current dir
+  --plugin NAMES      comma separated plugins to enable, available: %s
+  --test              rewrite for test
+  --name-only         only show names of changed files
+  --verbose           verbose
+  -o OUTPUT           write diff to file
+  -h,--help           show help
+
+Flags of go build affecting loading are passed to go, e.g. -mod, -tags.
+
+Examples:
+  go-inspect diff --plugin=trace ./
+`
+
+func runDiff(args []string) error {
+	var test bool
+	var remainArgs []string
+	for _, arg := range args {
+		if arg == "--test" {
+			test = true
+			continue
+		}
+		remainArgs = append(remainArgs, arg)
+	}
+	opts, err := parseFlags("diff", remainArgs)
+	if err != nil {
+		return err
+	}
+	if opts.showHelp {
+		fmt.Println(strings.TrimPrefix(fmt.Sprintf(diffHelp, pluginNames()), "\n"))
+		return nil
+	}
+	err = usePlugins(opts.plugins)
+	if err != nil {
+		return err
+	}
+
+	res, err := rewrite(opts, "", test, true)
+	if err != nil {
+		return err
+	}
+
+	var out string
+	if opts.nameOnly {
+		var sb strings.Builder
+		for _, f := range res.Diff.Files {
+			fmt.Fprintf(&sb, "%s\t%s\n", f.Kind, f.File)
+		}
+		out = sb.String()
+	} else {
+		out = res.Diff.String()
+	}
+	if opts.output != "" {
+		return ioutil.WriteFile(opts.output, []byte(out), 0755)
+	}
+	fmt.Print(out)
+	return nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xhd2015/go-inspect/project"
+)
+
+// options parsed from command line, flags not
+// recognized by go-inspect are passed to go
+type options struct {
+	showHelp bool
+
+	projectDir string
+	plugins    []string
+	verbose    bool
+	force      bool
+	debug      bool
+	goBinary   string
+	output     string
+	// packages rewritten in parallel
+	concurrency int
+	// copy or overlay
+	buildStrategy string
+
+	// go flags used by both load and build
+	goFlags []string
+	// go flags only used by build
+	buildFlags []string
+	// values of -gcflags, merged with those of go-inspect
+	gcflags []string
+	// flags passed to the test binary
+	testFlags []string
+	// args after -args, for test
+	testArgs []string
+
+	args []string
+	// program args, for run
+	runArgs []string
+
+	// diff
+	nameOnly bool
+}
+
+// flags that affect which packages and files are loaded
+var loadFlags = map[string]bool{
+	"-mod":     true,
+	"-modfile": true,
+	"-tags":    true,
+}
+
+// go build flags that take a value, except -gcflags,
+// which is merged with -gcflags added by go-inspect
+var buildValueFlags = map[string]bool{
+	"-asmflags":      true,
+	"-buildmode":     true,
+	"-compiler":      true,
+	"-coverpkg":      true,
+	"-covermode":     true,
+	"-gccgoflags":    true,
+	"-installsuffix": true,
+	"-ldflags":       true,
+	"-overlay":       true,
+	"-p":             true,
+	"-pgo":           true,
+	"-pkgdir":        true,
+}
+
+// go build flags that are boolean
+var buildBoolFlags = map[string]bool{
+	"-a":          true,
+	"-asan":       true,
+	"-cover":      true,
+	"-linkshared": true,
+	"-msan":       true,
+	"-n":          true,
+	"-race":       true,
+	"-work":       true,
+	"-x":          true,
+}
+
+// go test flags that are passed to the test binary as -test.X,
+// the value indicates whether it takes a value
+var testFlags = map[string]bool{
+	"-bench":        true,
+	"-benchmem":     false,
+	"-benchtime":    true,
+	"-blockprofile": true,
+	"-count":        true,
+	"-coverprofile": true,
+	"-cpu":          true,
+	"-cpuprofile":   true,
+	"-failfast":     false,
+	"-fullpath":     false,
+	"-json":         false,
+	"-list":         true,
+	"-memprofile":   true,
+	"-mutexprofile": true,
+	"-outputdir":    true,
+	"-parallel":     true,
+	"-run":          true,
+	"-short":        false,
+	"-shuffle":      true,
+	"-skip":         true,
+	"-timeout":      true,
+	"-trace":        true,
+	"-v":            false,
+}
+
+// parseFlags parses flags of `cmd`:
+//
+//	go-inspect <cmd> [FLAGS] <packages> [program args for run]
+func parseFlags(cmd string, args []string) (*options, error) {
+	opts := &options{}
+	n := len(args)
+	for i := 0; i < n; i++ {
+		arg := args[i]
+		if arg == "--" {
+			opts.args = append(opts.args, args[i+1:]...)
+			break
+		}
+		if cmd == "test" && arg == "-args" {
+			opts.testArgs = append(opts.testArgs, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			if cmd == "run" {
+				// go run: the first package, then program args
+				opts.args = append(opts.args, arg)
+				opts.runArgs = append(opts.runArgs, args[i+1:]...)
+				break
+			}
+			opts.args = append(opts.args, arg)
+			continue
+		}
+		name, value, hasValue := arg, "", false
+		if idx := strings.Index(arg, "="); idx >= 0 {
+			name, value, hasValue = arg[:idx], arg[idx+1:], true
+		}
+		// normalize --flag to -flag for go flags
+		goName := name
+		if strings.HasPrefix(name, "--") {
+			goName = name[1:]
+		}
+		takeValue := func() (string, error) {
+			if hasValue {
+				return value, nil
+			}
+			if i+1 >= n {
+				return "", fmt.Errorf("%s requires value", name)
+			}
+			i++
+			return args[i], nil
+		}
+		var err error
+		switch name {
+		case "-h", "--help":
+			opts.showHelp = true
+			return opts, nil
+		case "--project-dir":
+			opts.projectDir, err = takeValue()
+		case "--plugin":
+			var v string
+			v, err = takeValue()
+			opts.plugins = append(opts.plugins, splitList(v)...)
+		case "--verbose":
+			opts.verbose = true
+		case "--force":
+			opts.force = true
+		case "--debug":
+			opts.debug = true
+		case "--concurrency":
+			var v string
+			v, err = takeValue()
+			if err == nil {
+				opts.concurrency, err = strconv.Atoi(v)
+				if err != nil {
+					err = fmt.Errorf("invalid %s: %s", name, v)
+				}
+			}
+		case "--build-strategy":
+			opts.buildStrategy, err = takeValue()
+			if err == nil && opts.buildStrategy != string(project.BuildStrategyCopy) && opts.buildStrategy != string(project.BuildStrategyOverlay) {
+				err = fmt.Errorf("invalid %s: %s", name, opts.buildStrategy)
+			}
+		case "--go-binary":
+			opts.goBinary, err = takeValue()
+		case "-o":
+			opts.output, err = takeValue()
+		case "--name-only":
+			if cmd != "diff" {
+				return nil, fmt.Errorf("unrecognized flag: %v", arg)
+			}
+			opts.nameOnly = true
+		default:
+			var v string
+			switch {
+			case loadFlags[goName]:
+				v, err = takeValue()
+				opts.goFlags = append(opts.goFlags, goName+"="+v)
+			case cmd == "test" && isTestFlag(goName):
+				if testFlags[goName] {
+					v, err = takeValue()
+					opts.testFlags = append(opts.testFlags, "-test."+goName[1:]+"="+v)
+				} else if hasValue {
+					opts.testFlags = append(opts.testFlags, "-test."+goName[1:]+"="+value)
+				} else {
+					opts.testFlags = append(opts.testFlags, "-test."+goName[1:])
+				}
+			case goName == "-gcflags":
+				v, err = takeValue()
+				opts.gcflags = append(opts.gcflags, v)
+			case buildValueFlags[goName]:
+				v, err = takeValue()
+				opts.buildFlags = append(opts.buildFlags, goName+"="+v)
+			case buildBoolFlags[goName] || goName == "-v":
+				opts.buildFlags = append(opts.buildFlags, arg)
+			default:
+				return nil, fmt.Errorf("unrecognized flag: %v", arg)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return opts, nil
+}
+
+func isTestFlag(name string) bool {
+	_, ok := testFlags[name]
+	return ok
+}
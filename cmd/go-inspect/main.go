@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const help = `
+go-inspect rewrites go packages with plugins
+
+Usage:
+  go-inspect <command> [FLAGS] <args>
+
+Commands:
+  build         rewrite and build, like go build
+  test          rewrite and test, like go test
+  run           rewrite and run, like go run
+  rewrite-only  rewrite without building, print the rewrite root
+  diff          show what the plugins would rewrite, as a unified diff
+  source-map    translate positions in stdin back to the original files
+  mock-gen      generate mock packages for the mock plugin
+
+Options:
+  --version  show version
+  -h,--help  show help
+
+Run 'go-inspect <command> --help' for help of a command.
+
+Examples:
+  go-inspect build --plugin=export_g,trace -o app ./
+  go-inspect test --plugin=trace -run TestA -v ./pkg
+`
+
+const version = "0.0.1"
+
+func main() {
+	err := run(os.Args[1:])
+	if err != nil {
+		if exitErr, ok := err.(*exitCodeError); ok {
+			os.Exit(exitErr.code)
+		}
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("requires command, see --help")
+	}
+	cmd := args[0]
+	args = args[1:]
+	switch cmd {
+	case "--version":
+		fmt.Println(version)
+		return nil
+	case "-h", "--help", "help":
+		fmt.Println(strings.TrimPrefix(help, "\n"))
+		return nil
+	case "build", "test", "run", "rewrite-only":
+		return runCommand(cmd, args)
+	case "diff":
+		return runDiff(args)
+	case "source-map":
+		return runSourceMap(args)
+	case "mock-gen":
+		return runMockGen(args)
+	default:
+		return fmt.Errorf("unrecognized command: %s, see --help", cmd)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xhd2015/go-inspect/inspect/load"
+	"github.com/xhd2015/go-inspect/plugin/mock"
+)
+
+const mockGenHelp = `
+go-inspect mock-gen [FLAGS] <packages>
+
+Generate a mock package for each package, containing Setup
+and M to set up mocks of its exported functions and methods.
+Mocks take effect when built with --plugin=mock.
+
+Options:
+  --project-dir DIR   project dir, default current dir
+  --verbose           print generated files
+  -o DIR              dir of mock packages relative to the module, default %s
+  -h,--help           show help
+
+Flags of go build affecting loading are passed to go, e.g. -mod, -tags.
+
+Examples:
+  go-inspect mock-gen ./...
+  go-inspect test --plugin=mock ./...
+`
+
+func runMockGen(args []string) error {
+	opts, err := parseFlags("mock-gen", args)
+	if err != nil {
+		return err
+	}
+	if opts.showHelp {
+		fmt.Println(strings.TrimPrefix(fmt.Sprintf(mockGenHelp, mock.DefaultDir), "\n"))
+		return nil
+	}
+	if len(opts.args) == 0 {
+		return fmt.Errorf("requires packages, see --help")
+	}
+	g, err := load.LoadPackages(opts.args, &load.LoadOptions{
+		ProjectDir: opts.projectDir,
+		BuildFlags: opts.goFlags,
+	})
+	if err != nil {
+		return err
+	}
+	files, err := mock.Generate(g, &mock.GenOptions{Dir: opts.output})
+	if err != nil {
+		return err
+	}
+	err = mock.WriteFiles(files)
+	if err != nil {
+		return err
+	}
+	if opts.verbose {
+		for _, file := range files {
+			fmt.Println(file.Path)
+		}
+	}
+	return nil
+}
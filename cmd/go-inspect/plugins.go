@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xhd2015/go-inspect/plugin/export_g"
+	"github.com/xhd2015/go-inspect/plugin/mock"
+	"github.com/xhd2015/go-inspect/plugin/trace"
+)
+
+// pluginOptions collects options of plugins sharing
+// one rewriter, which must be registered only once
+type pluginOptions struct {
+	exportG *export_g.Options
+}
+
+func (c *pluginOptions) useExportG() *export_g.Options {
+	if c.exportG == nil {
+		c.exportG = &export_g.Options{}
+	}
+	return c.exportG
+}
+
+// plugins available by name
+var plugins = map[string]func(opts *pluginOptions){
+	"export_g": func(opts *pluginOptions) {
+		opts.useExportG()
+	},
+	// gls is export_g inheriting goroutine local storage
+	"gls": func(opts *pluginOptions) {
+		opts.useExportG().InheritGLS = true
+	},
+	"mock": func(opts *pluginOptions) {
+		mock.Use()
+	},
+	"trace": func(opts *pluginOptions) {
+		trace.Use()
+	},
+}
+
+func pluginNames() string {
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// usePlugins enables plugins given by comma separated names
+func usePlugins(names []string) error {
+	seen := make(map[string]bool, len(names))
+	opts := &pluginOptions{}
+	for _, name := range names {
+		use, ok := plugins[name]
+		if !ok {
+			return fmt.Errorf("unknown plugin: %s, available: %s", name, pluginNames())
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		use(opts)
+	}
+	if opts.exportG != nil {
+		export_g.UseWithOptions(opts.exportG)
+	}
+	return nil
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, e := range strings.Split(s, ",") {
+		e = strings.TrimSpace(e)
+		if e != "" {
+			list = append(list, e)
+		}
+	}
+	return list
+}
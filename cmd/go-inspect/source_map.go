@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xhd2015/go-inspect/project"
+)
+
+const sourceMapHelp = `
+go-inspect source-map [FLAGS] [DIR]
+
+Read from stdin, translate positions of rewritten files,
+e.g. in stack traces, back to the original files using
+the source map saved by the last rewrite of the project.
+Relative files are resolved against DIR if given.
+
+Options:
+  --project-dir DIR   project dir, default current dir
+  -h,--help           show help
+
+Examples:
+  ./app 2>&1 | go-inspect source-map
+`
+
+func runSourceMap(args []string) error {
+	opts, err := parseFlags("source-map", args)
+	if err != nil {
+		return err
+	}
+	if opts.showHelp {
+		fmt.Println(strings.TrimPrefix(sourceMapHelp, "\n"))
+		return nil
+	}
+	if len(opts.args) > 1 {
+		return fmt.Errorf("requires at most one dir, given: %s", strings.Join(opts.args, " "))
+	}
+	rewriteOpts := newRewriteOpts(opts, "", false)
+	sourceMap, err := project.LoadSourceMap(rewriteOpts)
+	if err != nil {
+		return err
+	}
+	if sourceMap == nil {
+		return fmt.Errorf("no source map found, rewrite the project first")
+	}
+	var dir string
+	if len(opts.args) > 0 {
+		dir, err = filepath.Abs(opts.args[0])
+		if err != nil {
+			return err
+		}
+	}
+	w := sourceMap.Writer(os.Stdout, dir)
+	_, err = io.Copy(w, os.Stdin)
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
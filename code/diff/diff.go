@@ -0,0 +1,218 @@
+// Package diff produces line based unified diffs.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines
+// shown around each change, same as `diff -u`
+const DefaultContext = 3
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+type op struct {
+	kind opKind
+	// line index in old for equal and delete,
+	// in new for insert
+	line int
+}
+
+// Unified returns the unified diff between `old` and `new`,
+// using DefaultContext lines of context.
+// Empty string is returned if they are equal.
+func Unified(oldName string, newName string, old string, new string) string {
+	return UnifiedContext(oldName, newName, old, new, DefaultContext)
+}
+
+// UnifiedContext is like Unified, with custom context lines
+func UnifiedContext(oldName string, newName string, old string, new string, context int) string {
+	if old == new {
+		return ""
+	}
+	a := splitLines(old)
+	b := splitLines(new)
+	ops := myers(a, b)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n", oldName)
+	fmt.Fprintf(&sb, "+++ %s\n", newName)
+
+	// oi, ni: line index of ops[i] in old and new
+	oi, ni := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for i, o := range ops {
+		oi[i+1], ni[i+1] = oi[i], ni[i]
+		switch o.kind {
+		case opEqual:
+			oi[i+1]++
+			ni[i+1]++
+		case opDelete:
+			oi[i+1]++
+		case opInsert:
+			ni[i+1]++
+		}
+	}
+
+	i := 0
+	for i < len(ops) {
+		// find next change
+		for i < len(ops) && ops[i].kind == opEqual {
+			i++
+		}
+		if i >= len(ops) {
+			break
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		// extend the hunk while changes are
+		// within 2*context lines of each other
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != opEqual {
+				end++
+				continue
+			}
+			j := end
+			for j < len(ops) && ops[j].kind == opEqual {
+				j++
+			}
+			if j >= len(ops) || j-end > 2*context {
+				end += context
+				if end > len(ops) {
+					end = len(ops)
+				}
+				break
+			}
+			end = j
+		}
+
+		oldStart, newStart := oi[start], ni[start]
+		oldLen, newLen := oi[end]-oldStart, ni[end]-newStart
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(oldStart, oldLen), hunkRange(newStart, newLen))
+		for _, o := range ops[start:end] {
+			var prefix string
+			var line string
+			switch o.kind {
+			case opEqual:
+				prefix, line = " ", a[o.line]
+			case opDelete:
+				prefix, line = "-", a[o.line]
+			case opInsert:
+				prefix, line = "+", b[o.line]
+			}
+			sb.WriteString(prefix)
+			sb.WriteString(line)
+			if !strings.HasSuffix(line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return sb.String()
+}
+
+func hunkRange(start int, n int) string {
+	if n == 0 {
+		// an empty range refers to the line before
+		return fmt.Sprintf("%d,0", start)
+	}
+	if n == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
+
+// splitLines splits s into lines, each
+// keeps its trailing "\n" if any
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// myers computes the shortest edit script from a to b,
+// see "An O(ND) Difference Algorithm and Its Variations"
+func myers(a []string, b []string) []op {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max
+	v := make([]int, 2*max+2)
+	var trace [][]int
+
+	var d int
+found:
+	for d = 0; d <= max; d++ {
+		vc := make([]int, len(v))
+		copy(vc, v)
+		trace = append(trace, vc)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break found
+			}
+		}
+	}
+
+	// backtrack
+	ops := make([]op, 0, n+m)
+	x, y := n, m
+	for ; d > 0; d-- {
+		vd := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && vd[offset+k-1] < vd[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := vd[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, op{kind: opEqual, line: x})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, op{kind: opInsert, line: y})
+		} else {
+			x--
+			ops = append(ops, op{kind: opDelete, line: x})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, op{kind: opEqual, line: x})
+	}
+	// reverse
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
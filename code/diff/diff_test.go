@@ -0,0 +1,65 @@
+package diff
+
+import (
+	"testing"
+)
+
+// go test -run TestUnified -v ./code/diff
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		old    string
+		new    string
+		expect string
+	}{
+		{
+			old:    "a\nb\nc\n",
+			new:    "a\nb\nc\n",
+			expect: "",
+		},
+		{
+			old: "a\nb\nc\n",
+			new: "a\nB\nc\n",
+			expect: `--- a.go
++++ b.go
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+`,
+		},
+		{
+			old: "",
+			new: "package a\n",
+			expect: `--- a.go
++++ b.go
+@@ -0,0 +1 @@
++package a
+`,
+		},
+		{
+			old: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			new: "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\nx",
+			expect: `--- a.go
++++ b.go
+@@ -1,3 +1,4 @@
++0
+ 1
+ 2
+ 3
+@@ -10,3 +11,4 @@
+ 10
+ 11
+ 12
++x
+\ No newline at end of file
+`,
+		},
+	}
+	for i, tt := range tests {
+		actual := Unified("a.go", "b.go", tt.old, tt.new)
+		if actual != tt.expect {
+			t.Fatalf("case %d: expect:\n%s\nactual:\n%s", i, tt.expect, actual)
+		}
+	}
+}
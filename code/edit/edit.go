@@ -8,22 +8,42 @@ package edit
 import (
 	"fmt"
 	"sort"
+	"strings"
 )
 
 // A Buffer is a queue of edits to apply to a given byte slice.
 type Buffer struct {
-	old []byte
-	q   edits
+	old    []byte
+	q      edits
+	policy Policy
 }
 
+// A Policy decides how overlapping edits are applied.
+type Policy int
+
+const (
+	// PolicyConflict reports overlapping edits as *ConflictError, the default.
+	PolicyConflict Policy = iota
+	// PolicyCompose applies edits nested inside a replaced range to
+	// the replacement, if the replacement contains the replaced text
+	// exactly once, e.g. a replacement wrapping the original expression.
+	// Of edits of the same range, the later one wraps the earlier.
+	// Partially overlapping edits are still conflicts.
+	PolicyCompose
+)
+
 // An edit records a single text modification: change the bytes in [start,end) to new.
 type edit struct {
-	start int
-	end   int
-	new   string
+	start  int
+	end    int
+	new    string
+	origin string
+	// seq is the order the edit is made
+	seq int
 }
 
-// An edits is a list of edits that is sortable by start offset, breaking ties by end offset.
+// An edits is a list of edits that is sortable by start offset, breaking ties by end offset,
+// and then by the order they are made.
 type edits []edit
 
 func (x edits) Len() int      { return len(x) }
@@ -32,7 +52,50 @@ func (x edits) Less(i, j int) bool {
 	if x[i].start != x[j].start {
 		return x[i].start < x[j].start
 	}
-	return x[i].end < x[j].end
+	if x[i].end != x[j].end {
+		return x[i].end < x[j].end
+	}
+	return x[i].seq < x[j].seq
+}
+
+// A ConflictError reports two overlapping edits, and who made them.
+type ConflictError struct {
+	// File is filled by callers knowing the file being edited
+	File string
+
+	Start  int
+	End    int
+	Origin string
+
+	ConflictStart  int
+	ConflictEnd    int
+	ConflictOrigin string
+}
+
+func (c *ConflictError) Error() string {
+	msg := fmt.Sprintf("overlapping edits: [%d,%d) by %s, [%d,%d) by %s", c.ConflictStart, c.ConflictEnd, originName(c.ConflictOrigin), c.Start, c.End, originName(c.Origin))
+	if c.File != "" {
+		msg = c.File + ": " + msg
+	}
+	return msg
+}
+
+func originName(origin string) string {
+	if origin == "" {
+		return "unknown"
+	}
+	return origin
+}
+
+func conflictOf(base int, prev edit, e edit) *ConflictError {
+	return &ConflictError{
+		Start:          base + e.start,
+		End:            base + e.end,
+		Origin:         e.origin,
+		ConflictStart:  base + prev.start,
+		ConflictEnd:    base + prev.end,
+		ConflictOrigin: prev.origin,
+	}
 }
 
 // NewBuffer returns a new buffer to accumulate changes to an initial data slice.
@@ -42,58 +105,231 @@ func NewBuffer(data []byte) *Buffer {
 	return &Buffer{old: data}
 }
 
+// SetPolicy sets how overlapping edits are applied
+func (b *Buffer) SetPolicy(policy Policy) {
+	b.policy = policy
+}
+
+// An Editor adds edits to a Buffer on behalf of an origin.
+type Editor struct {
+	b      *Buffer
+	origin string
+}
+
+// WithOrigin returns an Editor recording `origin` with its edits,
+// it is reported when they conflict with others.
+func (b *Buffer) WithOrigin(origin string) *Editor {
+	return &Editor{b: b, origin: origin}
+}
+
 func (b *Buffer) Insert(pos int, new string) {
-	if pos < 0 || pos > len(b.old) {
+	b.WithOrigin("").Insert(pos, new)
+}
+
+func (b *Buffer) Delete(start, end int) {
+	b.WithOrigin("").Delete(start, end)
+}
+
+func (b *Buffer) Replace(start, end int, new string) {
+	b.WithOrigin("").Replace(start, end, new)
+}
+
+func (e *Editor) Insert(pos int, new string) {
+	if pos < 0 || pos > len(e.b.old) {
 		panic("invalid edit position")
 	}
-	b.q = append(b.q, edit{pos, pos, new})
+	e.add(pos, pos, new)
 }
 
-func (b *Buffer) Delete(start, end int) {
+func (e *Editor) Delete(start, end int) {
+	e.Replace(start, end, "")
+}
+
+func (e *Editor) Replace(start, end int, new string) {
 	if end == -1 {
-		end = len(b.old)
+		end = len(e.b.old)
 	}
-	if end < start || start < 0 || end > len(b.old) {
+	if end < start || start < 0 || end > len(e.b.old) {
 		panic("invalid edit position")
 	}
-	b.q = append(b.q, edit{start, end, ""})
+	e.add(start, end, new)
 }
 
-func (b *Buffer) Replace(start, end int, new string) {
-	if end == -1 {
-		end = len(b.old)
+func (e *Editor) add(start, end int, new string) {
+	e.b.q = append(e.b.q, edit{start: start, end: end, new: new, origin: e.origin, seq: len(e.b.q)})
+}
+
+// Apply returns a new byte slice containing the original data
+// with the queued edits applied, or *ConflictError if edits
+// overlap and cannot be composed by the policy.
+func (b *Buffer) Apply() ([]byte, error) {
+	q, err := b.resolve()
+	if err != nil {
+		return nil, err
 	}
-	if end < start || start < 0 || end > len(b.old) {
-		panic("invalid edit position")
+	return apply(b.old, q), nil
+}
+
+// Bytes is like Apply, but panics with the *ConflictError.
+func (b *Buffer) Bytes() []byte {
+	data, err := b.Apply()
+	if err != nil {
+		panic(err)
 	}
-	b.q = append(b.q, edit{start, end, new})
+	return data
 }
 
-// Bytes returns a new byte slice containing the original data
+// String returns a string containing the original data
 // with the queued edits applied.
-func (b *Buffer) Bytes() []byte {
+func (b *Buffer) String() string {
+	return string(b.Bytes())
+}
+
+func (b *Buffer) resolve() (edits, error) {
+	q := make(edits, len(b.q))
+	copy(q, b.q)
+	return resolve(b.old, 0, q, b.policy)
+}
+
+// resolve returns non-overlapping edits sorted by position, edits nested
+// in another are composed into it. Offsets of `q` are relative to `old`,
+// which starts at `base` of the original data.
+func resolve(old []byte, base int, q edits, policy Policy) (edits, error) {
 	// Sort edits by starting position and then by ending position.
 	// Breaking ties by ending position allows insertions at point x
 	// to be applied before a replacement of the text at [x, y).
-	sort.Stable(b.q)
+	sort.Sort(q)
+
+	type outer struct {
+		edit
+		nested edits
+	}
+	var res []*outer
+	for _, e := range q {
+		if len(res) == 0 {
+			res = append(res, &outer{edit: e})
+			continue
+		}
+		last := res[len(res)-1]
+		if e.start >= last.end {
+			res = append(res, &outer{edit: e})
+			continue
+		}
+		if e.start == last.start && e.end >= last.end {
+			// e wraps last
+			nested := append(edits{last.edit}, last.nested...)
+			res[len(res)-1] = &outer{edit: e, nested: nested}
+			continue
+		}
+		if e.end <= last.end {
+			last.nested = append(last.nested, e)
+			continue
+		}
+		return nil, conflictOf(base, last.edit, e)
+	}
+
+	resolved := make(edits, 0, len(res))
+	for _, o := range res {
+		if len(o.nested) == 0 {
+			resolved = append(resolved, o.edit)
+			continue
+		}
+		if policy != PolicyCompose {
+			return nil, conflictOf(base, o.edit, o.nested[0])
+		}
+		replaced := string(old[o.start:o.end])
+		idx := strings.Index(o.new, replaced)
+		if idx < 0 || strings.Count(o.new, replaced) != 1 {
+			return nil, conflictOf(base, o.edit, o.nested[0])
+		}
+		nested := make(edits, len(o.nested))
+		for i, e := range o.nested {
+			e.start -= o.start
+			e.end -= o.start
+			nested[i] = e
+		}
+		inner, err := resolve(old[o.start:o.end], base+o.start, nested, policy)
+		if err != nil {
+			return nil, err
+		}
+		e := o.edit
+		e.new = o.new[:idx] + string(apply(old[o.start:o.end], inner)) + o.new[idx+len(replaced):]
+		resolved = append(resolved, e)
+	}
+	return resolved, nil
+}
 
+// apply applies sorted non-overlapping edits
+func apply(old []byte, q edits) []byte {
 	var new []byte
 	offset := 0
-	for i, e := range b.q {
-		if e.start < offset {
-			e0 := b.q[i-1]
-			panic(fmt.Sprintf("overlapping edits: [%d,%d)->%q, [%d,%d)->%q", e0.start, e0.end, e0.new, e.start, e.end, e.new))
-		}
-		new = append(new, b.old[offset:e.start]...)
+	for _, e := range q {
+		new = append(new, old[offset:e.start]...)
 		offset = e.end
 		new = append(new, e.new...)
 	}
-	new = append(new, b.old[offset:]...)
+	new = append(new, old[offset:]...)
 	return new
 }
 
-// String returns a string containing the original data
-// with the queued edits applied.
-func (b *Buffer) String() string {
-	return string(b.Bytes())
+// A Span is a range of unchanged data, of length Len, found
+// at offset Old in the original data and New in the edited data.
+type Span struct {
+	New int `json:"new"`
+	Old int `json:"old"`
+	Len int `json:"len"`
+}
+
+// A Mapping maps offsets of the edited data back to the original data.
+type Mapping struct {
+	// Spans are sorted by offset, text between spans is edited
+	Spans []Span `json:"spans"`
+}
+
+// Mapping returns the mapping of the data returned by Bytes
+// to the original data.
+// Composed edits are mapped as a whole.
+func (b *Buffer) Mapping() *Mapping {
+	q, err := b.resolve()
+	if err != nil {
+		panic(err)
+	}
+
+	m := &Mapping{}
+	offset := 0
+	newOffset := 0
+	for _, e := range q {
+		m.add(newOffset, offset, e.start-offset)
+		newOffset += e.start - offset + len(e.new)
+		offset = e.end
+	}
+	m.add(newOffset, offset, len(b.old)-offset)
+	return m
+}
+
+func (m *Mapping) add(newOffset int, oldOffset int, n int) {
+	if n <= 0 {
+		return
+	}
+	m.Spans = append(m.Spans, Span{New: newOffset, Old: oldOffset, Len: n})
+}
+
+// OldOffset returns the offset in the original data of `offset`
+// in the edited data. Offsets inside edited text are mapped
+// to the start of the edit, with exact being false.
+func (m *Mapping) OldOffset(offset int) (old int, exact bool) {
+	// the first span ends after offset
+	i := sort.Search(len(m.Spans), func(i int) bool {
+		s := m.Spans[i]
+		return s.New+s.Len > offset
+	})
+	if i < len(m.Spans) && m.Spans[i].New <= offset {
+		s := m.Spans[i]
+		return s.Old + offset - s.New, true
+	}
+	if i == 0 {
+		return 0, false
+	}
+	s := m.Spans[i-1]
+	return s.Old + s.Len, false
 }
@@ -0,0 +1,158 @@
+package edit
+
+import (
+	"strings"
+	"testing"
+)
+
+// go test -run TestMapping -v ./code/edit
+func TestMapping(t *testing.T) {
+	old := "func f() {\n\treturn\n}\n"
+	b := NewBuffer([]byte(old))
+	b.Insert(0, "// generated\n")
+	b.Insert(10, "defer g();")
+	b.Replace(12, 18, "return nil")
+
+	newData := b.String()
+	expectNew := "// generated\nfunc f() {defer g();\n\treturn nil\n}\n"
+	if newData != expectNew {
+		t.Fatalf("expect %q, actual %q", expectNew, newData)
+	}
+	m := b.Mapping()
+
+	tests := []struct {
+		new   string // locate offset by the first occurrence
+		old   int
+		exact bool
+	}{
+		{new: "// generated", old: 0, exact: false},
+		{new: "func", old: 0, exact: true},
+		{new: "{defer", old: 9, exact: true},
+		{new: "defer", old: 10, exact: false},
+		{new: "\n\treturn nil", old: 10, exact: true},
+		{new: "nil", old: 12, exact: false},
+		{new: "\n}", old: 18, exact: true},
+	}
+	for _, tt := range tests {
+		offset := strings.Index(newData, tt.new)
+		old, exact := m.OldOffset(offset)
+		if old != tt.old || exact != tt.exact {
+			t.Fatalf("%q: expect old=%d exact=%v, actual old=%d exact=%v", tt.new, tt.old, tt.exact, old, exact)
+		}
+	}
+	// unchanged spans have the same content
+	for _, s := range m.Spans {
+		if newData[s.New:s.New+s.Len] != old[s.Old:s.Old+s.Len] {
+			t.Fatalf("bad span %+v", s)
+		}
+	}
+}
+
+// go test -run TestConflict -v ./code/edit
+func TestConflict(t *testing.T) {
+	old := "a := f(x) + g(y)"
+	b := NewBuffer([]byte(old))
+	b.WithOrigin("trace").Replace(5, 9, "wrap(f(x))")
+	b.WithOrigin("mock").Replace(7, 16, "z")
+
+	_, err := b.Apply()
+	conflict, ok := err.(*ConflictError)
+	if !ok {
+		t.Fatalf("expect conflict error, actual: %v", err)
+	}
+	if conflict.ConflictOrigin != "trace" || conflict.Origin != "mock" {
+		t.Fatalf("expect trace conflicts with mock, actual: %v", conflict)
+	}
+	expectMsg := "overlapping edits: [5,9) by trace, [7,16) by mock"
+	if err.Error() != expectMsg {
+		t.Fatalf("expect %q, actual %q", expectMsg, err.Error())
+	}
+
+	// nested edits conflict by default
+	b = NewBuffer([]byte(old))
+	b.WithOrigin("trace").Replace(5, 9, "wrap(f(x))")
+	b.WithOrigin("mock").Replace(7, 8, "z")
+	_, err = b.Apply()
+	if _, ok := err.(*ConflictError); !ok {
+		t.Fatalf("expect conflict error, actual: %v", err)
+	}
+}
+
+// go test -run TestCompose -v ./code/edit
+func TestCompose(t *testing.T) {
+	old := "a := f(x) + g(y)"
+	tests := []struct {
+		name   string
+		edits  func(b *Buffer)
+		expect string
+		err    bool
+	}{
+		{
+			name: "inner replace inside outer wrap",
+			edits: func(b *Buffer) {
+				b.WithOrigin("trace").Replace(5, 9, "wrap(f(x))")
+				b.WithOrigin("mock").Replace(7, 8, "z")
+			},
+			expect: "a := wrap(f(z)) + g(y)",
+		},
+		{
+			name: "order does not matter for nested ranges",
+			edits: func(b *Buffer) {
+				b.WithOrigin("mock").Replace(7, 8, "z")
+				b.WithOrigin("trace").Replace(5, 9, "wrap(f(x))")
+			},
+			expect: "a := wrap(f(z)) + g(y)",
+		},
+		{
+			name: "later wraps earlier of the same range",
+			edits: func(b *Buffer) {
+				b.WithOrigin("a").Replace(5, 9, "A(f(x))")
+				b.WithOrigin("b").Replace(5, 9, "B(f(x))")
+			},
+			expect: "a := B(A(f(x))) + g(y)",
+		},
+		{
+			name: "insert inside wrap",
+			edits: func(b *Buffer) {
+				b.WithOrigin("trace").Replace(5, 16, "(f(x) + g(y)).Check()")
+				b.Insert(12, "h")
+				b.Replace(14, 15, "w")
+			},
+			expect: "a := (f(x) + hg(w)).Check()",
+		},
+		{
+			name: "outer does not contain the original",
+			edits: func(b *Buffer) {
+				b.WithOrigin("trace").Replace(5, 9, "nil")
+				b.WithOrigin("mock").Replace(7, 8, "z")
+			},
+			err: true,
+		},
+		{
+			name: "partial overlap",
+			edits: func(b *Buffer) {
+				b.WithOrigin("trace").Replace(5, 9, "wrap(f(x))")
+				b.WithOrigin("mock").Replace(7, 16, "z")
+			},
+			err: true,
+		},
+	}
+	for _, tt := range tests {
+		b := NewBuffer([]byte(old))
+		b.SetPolicy(PolicyCompose)
+		tt.edits(b)
+		data, err := b.Apply()
+		if tt.err {
+			if _, ok := err.(*ConflictError); !ok {
+				t.Fatalf("%s: expect conflict error, actual: %v", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if string(data) != tt.expect {
+			t.Fatalf("%s: expect %q, actual %q", tt.name, tt.expect, string(data))
+		}
+	}
+}
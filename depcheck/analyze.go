@@ -0,0 +1,288 @@
+package depcheck
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ImportGraph is the import graph of packages, with variants of the same
+// package, such as test variants and those loaded with different build
+// tags, merged by package path. External test packages, e.g. a_test,
+// are merged into their packages under test as variants, so that a
+// cycle like a_test -> b -> a is reported as a -> b -> a.
+type ImportGraph struct {
+	// Imports maps importer to imported packages, and then to IDs of
+	// the importer's variants having the import, e.g. "a [a.test]"
+	Imports map[string]map[string][]string
+}
+
+func NewImportGraph() *ImportGraph {
+	return &ImportGraph{Imports: make(map[string]map[string][]string)}
+}
+
+// Add adds `pkgs` and their dependencies, usually loaded with
+// packages.Config.Tests and packages.NeedName, can be called
+// again to merge packages loaded with different build flags.
+func (c *ImportGraph) Add(pkgs []*packages.Package) {
+	packages.Visit(pkgs, nil, func(p *packages.Package) {
+		// skip generated test mains like a.test
+		if p.Name == "main" && strings.HasSuffix(p.ID, ".test") {
+			return
+		}
+		pkg := pkgUnderTest(p)
+		c.addPkg(pkg)
+		for _, imp := range p.Imports {
+			c.addEdge(pkg, imp.PkgPath, p.ID)
+		}
+	})
+}
+
+// pkgUnderTest is the package tested by `p` if `p` is
+// an external test package, otherwise the path of `p`
+func pkgUnderTest(p *packages.Package) string {
+	if strings.HasSuffix(p.Name, "_test") && strings.HasSuffix(p.PkgPath, "_test") {
+		return strings.TrimSuffix(p.PkgPath, "_test")
+	}
+	return p.PkgPath
+}
+
+func (c *ImportGraph) addPkg(pkg string) {
+	if c.Imports[pkg] == nil {
+		c.Imports[pkg] = make(map[string][]string)
+	}
+}
+
+func (c *ImportGraph) addEdge(from string, to string, variant string) {
+	c.addPkg(from)
+	c.addPkg(to)
+	// tests of a package are not imports of itself
+	if from == to {
+		return
+	}
+	for _, v := range c.Imports[from][to] {
+		if v == variant {
+			return
+		}
+	}
+	c.Imports[from][to] = append(c.Imports[from][to], variant)
+}
+
+// Filter returns a graph with only packages matching `keep`
+func (c *ImportGraph) Filter(keep func(pkg string) bool) *ImportGraph {
+	g := NewImportGraph()
+	for from, imports := range c.Imports {
+		if !keep(from) {
+			continue
+		}
+		g.addPkg(from)
+		for to, variants := range imports {
+			if keep(to) {
+				g.addPkg(to)
+				g.Imports[from][to] = variants
+			}
+		}
+	}
+	return g
+}
+
+type ImportAnalysis struct {
+	// Cycles are strongly connected components with more than
+	// one package, sorted by their first packages
+	Cycles []*ImportCycle `json:"cycles"`
+	// Layers are in topological order, packages of layer 0
+	// import nothing, packages of a cycle are in the same layer
+	Layers [][]string   `json:"layers"`
+	Pkgs   []*PkgMetric `json:"pkgs"`
+}
+
+type ImportCycle struct {
+	Pkgs  []string      `json:"pkgs"`
+	Edges []*ImportEdge `json:"edges"`
+}
+
+type ImportEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Variants are IDs of variants of From having the import
+	Variants []string `json:"variants"`
+}
+
+// PkgMetric measures the coupling of a package, Instability is
+// FanOut/(FanIn+FanOut), 0 means maximally stable, 1 means
+// maximally unstable.
+type PkgMetric struct {
+	Pkg         string  `json:"pkg"`
+	Layer       int     `json:"layer"`
+	FanIn       int     `json:"fanIn"`
+	FanOut      int     `json:"fanOut"`
+	Instability float64 `json:"instability"`
+	// Cycle is the index in ImportAnalysis.Cycles, -1 if none
+	Cycle int `json:"cycle"`
+}
+
+// Analyze finds import cycles by Tarjan's algorithm, then layers and
+// measures packages
+func (c *ImportGraph) Analyze() *ImportAnalysis {
+	pkgs := make([]string, 0, len(c.Imports))
+	for pkg := range c.Imports {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	importsOf := func(pkg string) []string {
+		imports := make([]string, 0, len(c.Imports[pkg]))
+		for to := range c.Imports[pkg] {
+			imports = append(imports, to)
+		}
+		sort.Strings(imports)
+		return imports
+	}
+
+	// components are found in reverse topological order,
+	// i.e. imported ones first
+	var sccs [][]string
+	index := make(map[string]int, len(pkgs))
+	lowLink := make(map[string]int, len(pkgs))
+	onStack := make(map[string]bool)
+	var stack []string
+	var strongConnect func(pkg string)
+	strongConnect = func(pkg string) {
+		index[pkg] = len(index)
+		lowLink[pkg] = index[pkg]
+		stack = append(stack, pkg)
+		onStack[pkg] = true
+		for _, to := range importsOf(pkg) {
+			if _, ok := index[to]; !ok {
+				strongConnect(to)
+				if lowLink[to] < lowLink[pkg] {
+					lowLink[pkg] = lowLink[to]
+				}
+			} else if onStack[to] && index[to] < lowLink[pkg] {
+				lowLink[pkg] = index[to]
+			}
+		}
+		if lowLink[pkg] != index[pkg] {
+			return
+		}
+		var scc []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			scc = append(scc, top)
+			if top == pkg {
+				break
+			}
+		}
+		sort.Strings(scc)
+		sccs = append(sccs, scc)
+	}
+	for _, pkg := range pkgs {
+		if _, ok := index[pkg]; !ok {
+			strongConnect(pkg)
+		}
+	}
+
+	res := &ImportAnalysis{}
+	layerOf := make(map[string]int, len(pkgs))
+	cycleOf := make(map[string]int, len(pkgs))
+	for _, scc := range sccs {
+		layer := 0
+		inSCC := make(map[string]bool, len(scc))
+		for _, pkg := range scc {
+			inSCC[pkg] = true
+		}
+		for _, pkg := range scc {
+			cycleOf[pkg] = -1
+			for to := range c.Imports[pkg] {
+				if !inSCC[to] && layerOf[to]+1 > layer {
+					layer = layerOf[to] + 1
+				}
+			}
+		}
+		for _, pkg := range scc {
+			layerOf[pkg] = layer
+		}
+		for len(res.Layers) <= layer {
+			res.Layers = append(res.Layers, nil)
+		}
+		res.Layers[layer] = append(res.Layers[layer], scc...)
+		if len(scc) > 1 {
+			cycle := &ImportCycle{Pkgs: scc}
+			for _, pkg := range scc {
+				for _, to := range importsOf(pkg) {
+					if inSCC[to] {
+						cycle.Edges = append(cycle.Edges, &ImportEdge{From: pkg, To: to, Variants: c.Imports[pkg][to]})
+					}
+				}
+			}
+			res.Cycles = append(res.Cycles, cycle)
+		}
+	}
+	sort.Slice(res.Cycles, func(i, j int) bool {
+		return res.Cycles[i].Pkgs[0] < res.Cycles[j].Pkgs[0]
+	})
+	for i, cycle := range res.Cycles {
+		for _, pkg := range cycle.Pkgs {
+			cycleOf[pkg] = i
+		}
+	}
+	for _, layer := range res.Layers {
+		sort.Strings(layer)
+	}
+
+	fanIn := make(map[string]int, len(pkgs))
+	for _, imports := range c.Imports {
+		for to := range imports {
+			fanIn[to]++
+		}
+	}
+	for _, layer := range res.Layers {
+		for _, pkg := range layer {
+			m := &PkgMetric{
+				Pkg:    pkg,
+				Layer:  layerOf[pkg],
+				FanIn:  fanIn[pkg],
+				FanOut: len(c.Imports[pkg]),
+				Cycle:  cycleOf[pkg],
+			}
+			if m.FanIn+m.FanOut > 0 {
+				m.Instability = float64(m.FanOut) / float64(m.FanIn+m.FanOut)
+			}
+			res.Pkgs = append(res.Pkgs, m)
+		}
+	}
+	return res
+}
+
+// FormatImportAnalysis formats cycles, layers and metrics as text
+func FormatImportAnalysis(res *ImportAnalysis) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "cycles: %d\n", len(res.Cycles))
+	for i, cycle := range res.Cycles {
+		fmt.Fprintf(&b, "  #%d %s\n", i, strings.Join(cycle.Pkgs, ", "))
+		for _, e := range cycle.Edges {
+			fmt.Fprintf(&b, "    %s -> %s (%s)\n", e.From, e.To, strings.Join(e.Variants, ", "))
+		}
+	}
+	fmt.Fprintf(&b, "layers: %d\n", len(res.Layers))
+	for i, layer := range res.Layers {
+		fmt.Fprintf(&b, "  %d: %s\n", i, strings.Join(layer, " "))
+	}
+	b.WriteString("packages:\n")
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "  PKG\tLAYER\tFAN-IN\tFAN-OUT\tINSTABILITY\tCYCLE")
+	for _, m := range res.Pkgs {
+		cycle := "-"
+		if m.Cycle >= 0 {
+			cycle = fmt.Sprintf("#%d", m.Cycle)
+		}
+		fmt.Fprintf(w, "  %s\t%d\t%d\t%d\t%.2f\t%s\n", m.Pkg, m.Layer, m.FanIn, m.FanOut, m.Instability, cycle)
+	}
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
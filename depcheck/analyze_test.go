@@ -0,0 +1,51 @@
+package depcheck
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// go test -run TestAnalyzeImports -v ./depcheck
+func TestAnalyzeImports(t *testing.T) {
+	pkgs, err := packages.Load(&packages.Config{
+		Dir:   "./testdata/cycle",
+		Mode:  packages.NeedDeps | packages.NeedName | packages.NeedImports | packages.NeedModule,
+		Tests: true,
+	}, "./...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewImportGraph()
+	g.Add(pkgs)
+	std := CollectStdPkgs(pkgs)
+	res := g.Filter(func(pkg string) bool {
+		return !std[pkg]
+	}).Analyze()
+	t.Logf("analysis:\n%s", FormatImportAnalysis(res))
+
+	if len(res.Cycles) != 1 || strings.Join(res.Cycles[0].Pkgs, " ") != "example.com/cycle/a example.com/cycle/b" {
+		t.Fatalf("expect cycle of a and b, actual: %+v", res.Cycles)
+	}
+	var testVariant bool
+	for _, e := range res.Cycles[0].Edges {
+		if e.From == "example.com/cycle/a" && e.To == "example.com/cycle/b" && strings.Join(e.Variants, " ") == "example.com/cycle/a_test [example.com/cycle/a.test]" {
+			testVariant = true
+		}
+	}
+	if !testVariant {
+		t.Fatalf("expect a imports b in its external test, actual: %+v", res.Cycles[0].Edges)
+	}
+	if len(res.Layers) != 2 || strings.Join(res.Layers[1], " ") != "example.com/cycle/c" {
+		t.Fatalf("expect c above the cycle, actual: %v", res.Layers)
+	}
+	for _, m := range res.Pkgs {
+		if m.Pkg == "example.com/cycle/c" && (m.FanIn != 0 || m.FanOut != 2 || m.Instability != 1 || m.Cycle != -1) {
+			t.Fatalf("unexpected metric of c: %+v", m)
+		}
+		if m.Pkg == "example.com/cycle/b" && (m.FanIn != 2 || m.FanOut != 1 || m.Cycle != 0) {
+			t.Fatalf("unexpected metric of b: %+v", m)
+		}
+	}
+}
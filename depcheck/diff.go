@@ -0,0 +1,238 @@
+package depcheck
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/xhd2015/go-inspect/sh"
+)
+
+// Snapshot is the dependencies of packages at some point
+type Snapshot struct {
+	Deps       []*PkgDepInfo
+	PkgMapping map[string]*PkgDepInfo
+	// PkgModules maps packages to module paths, std
+	// packages are absent
+	PkgModules map[string]string
+	// Modules maps module paths to versions, the main
+	// module and directory replacements have no version
+	Modules map[string]string
+}
+
+// LoadSnapshot loads `args` in `dir` and collects their dependencies
+func LoadSnapshot(dir string, args []string, buildFlags []string, tests bool) (*Snapshot, error) {
+	pkgs, err := packages.Load(&packages.Config{
+		Dir: dir,
+		// to have syntax, must also set NeedFiles
+		Mode:       packages.NeedDeps | packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedImports | packages.NeedModule,
+		Tests:      tests,
+		BuildFlags: buildFlags,
+	}, args...)
+	if err != nil {
+		return nil, err
+	}
+	var errs []string
+	packages.Visit(pkgs, nil, func(p *packages.Package) {
+		for _, e := range p.Errors {
+			errs = append(errs, e.Error())
+		}
+	})
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("load %s: %s", dir, strings.Join(errs, "\n"))
+	}
+	deps, pkgMapping, err := CollectDeps(pkgs, nil)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &Snapshot{
+		Deps:       deps,
+		PkgMapping: pkgMapping,
+		PkgModules: make(map[string]string),
+		Modules:    make(map[string]string),
+	}
+	packages.Visit(pkgs, nil, func(p *packages.Package) {
+		if p.Module == nil {
+			return
+		}
+		mod := p.Module
+		version := mod.Version
+		if mod.Replace != nil {
+			version = mod.Replace.Version
+		}
+		snapshot.PkgModules[p.PkgPath] = mod.Path
+		snapshot.Modules[mod.Path] = version
+	})
+	return snapshot, nil
+}
+
+// ExportGitRevision exports the git tree of `rev` into a temp dir,
+// and returns the dir corresponding to `dir` in it. `dir` must be
+// inside a git repository, `cleanup` removes the temp dir.
+func ExportGitRevision(dir string, rev string) (revDir string, cleanup func(), err error) {
+	if dir == "" {
+		dir = "."
+	}
+	out, _, err := sh.RunBashWithOpts([]string{
+		fmt.Sprintf("git -C %s rev-parse --show-toplevel --show-prefix", sh.Quote(dir)),
+	}, sh.RunBashOptions{NeedStdOut: true})
+	if err != nil {
+		return "", nil, err
+	}
+	// archive the whole tree, git archive in a sub dir only has the sub dir
+	lines := strings.Split(out, "\n")
+	topLevel := lines[0]
+	var prefix string
+	if len(lines) > 1 {
+		prefix = lines[1]
+	}
+	tmpDir, err := ioutil.TempDir("", "depcheck-diff")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup = func() {
+		os.RemoveAll(tmpDir)
+	}
+	_, _, err = sh.RunBashWithOpts([]string{
+		fmt.Sprintf("git -C %s archive --format=tar %s | tar -x -C %s", sh.Quote(topLevel), sh.Quote(rev), sh.Quote(tmpDir)),
+	}, sh.RunBashOptions{})
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("export %s: %w", rev, err)
+	}
+	return filepath.Join(tmpDir, filepath.FromSlash(prefix)), cleanup, nil
+}
+
+type DepDiff struct {
+	AddedPkgs      []*PkgChange    `json:"addedPkgs"`
+	RemovedPkgs    []*PkgChange    `json:"removedPkgs"`
+	AddedModules   []*ModuleChange `json:"addedModules"`
+	RemovedModules []*ModuleChange `json:"removedModules"`
+	UpdatedModules []*ModuleChange `json:"updatedModules"`
+}
+
+type PkgChange struct {
+	Pkg    string `json:"pkg"`
+	Module string `json:"module,omitempty"`
+	// Trace is the import trace explaining why Pkg is imported,
+	// in the new snapshot for added packages, and in the old
+	// snapshot for removed packages
+	Trace []string `json:"trace"`
+}
+
+type ModuleChange struct {
+	Path       string `json:"path"`
+	OldVersion string `json:"oldVersion,omitempty"`
+	NewVersion string `json:"newVersion,omitempty"`
+}
+
+// Diff compares dependencies of `from` and `to`, all
+// changes are sorted by package or module path
+func Diff(from *Snapshot, to *Snapshot) *DepDiff {
+	res := &DepDiff{}
+	pkgChanges := func(a *Snapshot, b *Snapshot) []*PkgChange {
+		var changes []*PkgChange
+		for _, pkg := range sortedPkgs(a.PkgMapping) {
+			if b.PkgMapping[pkg] != nil {
+				continue
+			}
+			changes = append(changes, &PkgChange{
+				Pkg:    pkg,
+				Module: a.PkgModules[pkg],
+				Trace:  GetImportTrace(a.Deps, pkg),
+			})
+		}
+		return changes
+	}
+	res.AddedPkgs = pkgChanges(to, from)
+	res.RemovedPkgs = pkgChanges(from, to)
+
+	for _, mod := range sortedModules(to.Modules) {
+		oldVersion, ok := from.Modules[mod]
+		if !ok {
+			res.AddedModules = append(res.AddedModules, &ModuleChange{Path: mod, NewVersion: to.Modules[mod]})
+		} else if oldVersion != to.Modules[mod] {
+			res.UpdatedModules = append(res.UpdatedModules, &ModuleChange{Path: mod, OldVersion: oldVersion, NewVersion: to.Modules[mod]})
+		}
+	}
+	for _, mod := range sortedModules(from.Modules) {
+		if _, ok := to.Modules[mod]; !ok {
+			res.RemovedModules = append(res.RemovedModules, &ModuleChange{Path: mod, OldVersion: from.Modules[mod]})
+		}
+	}
+	return res
+}
+
+// Empty reports whether there is no change
+func (c *DepDiff) Empty() bool {
+	return len(c.AddedPkgs) == 0 && len(c.RemovedPkgs) == 0 &&
+		len(c.AddedModules) == 0 && len(c.RemovedModules) == 0 && len(c.UpdatedModules) == 0
+}
+
+// FormatDepDiff formats `diff` as text, added packages
+// are followed by their import traces
+func FormatDepDiff(diff *DepDiff) string {
+	if diff.Empty() {
+		return "no dependency changes"
+	}
+	var lines []string
+	section := func(title string, n int) bool {
+		if n == 0 {
+			return false
+		}
+		lines = append(lines, fmt.Sprintf("%s (%d):", title, n))
+		return true
+	}
+	if section("added modules", len(diff.AddedModules)) {
+		for _, m := range diff.AddedModules {
+			lines = append(lines, strings.TrimRight("  + "+m.Path+" "+m.NewVersion, " "))
+		}
+	}
+	if section("removed modules", len(diff.RemovedModules)) {
+		for _, m := range diff.RemovedModules {
+			lines = append(lines, strings.TrimRight("  - "+m.Path+" "+m.OldVersion, " "))
+		}
+	}
+	if section("updated modules", len(diff.UpdatedModules)) {
+		for _, m := range diff.UpdatedModules {
+			lines = append(lines, fmt.Sprintf("  ~ %s %s => %s", m.Path, m.OldVersion, m.NewVersion))
+		}
+	}
+	if section("added packages", len(diff.AddedPkgs)) {
+		for _, p := range diff.AddedPkgs {
+			lines = append(lines, "  + "+p.Pkg)
+			for i, pkg := range p.Trace {
+				lines = append(lines, strings.Repeat("  ", i+3)+pkg)
+			}
+		}
+	}
+	if section("removed packages", len(diff.RemovedPkgs)) {
+		for _, p := range diff.RemovedPkgs {
+			lines = append(lines, "  - "+p.Pkg)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func sortedPkgs(m map[string]*PkgDepInfo) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedModules(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
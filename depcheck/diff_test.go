@@ -0,0 +1,60 @@
+package depcheck
+
+import (
+	"strings"
+	"testing"
+)
+
+// go test -run TestDiff -v ./depcheck
+func TestDiff(t *testing.T) {
+	from, err := LoadSnapshot("./testdata/layers", []string{"./api"}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := LoadSnapshot("./testdata/layers", []string{"./"}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := Diff(from, to)
+	t.Logf("diff:\n%s", FormatDepDiff(diff))
+
+	var added []string
+	for _, p := range diff.AddedPkgs {
+		added = append(added, p.Pkg)
+	}
+	if strings.Join(added, " ") != "example.com/layers example.com/layers/fast" {
+		t.Fatalf("unexpected added packages: %v", added)
+	}
+	if trace := strings.Join(diff.AddedPkgs[1].Trace, " "); trace != "example.com/layers example.com/layers/fast" {
+		t.Fatalf("unexpected trace of fast: %s", trace)
+	}
+	if diff.AddedPkgs[1].Module != "example.com/layers" {
+		t.Fatalf("expect module of fast: example.com/layers, actual: %s", diff.AddedPkgs[1].Module)
+	}
+	if len(diff.RemovedPkgs) != 0 || len(diff.AddedModules) != 0 {
+		t.Fatalf("expect no removed packages or added modules, actual: %+v", diff)
+	}
+	if !Diff(to, to).Empty() {
+		t.Fatalf("expect no changes to itself")
+	}
+}
+
+// go test -run TestDiffModules -v ./depcheck
+func TestDiffModules(t *testing.T) {
+	from := &Snapshot{Modules: map[string]string{"a": "v1.0.0", "b": "v1.0.0"}}
+	to := &Snapshot{Modules: map[string]string{"a": "v1.1.0", "c": "v0.1.0"}}
+	diff := Diff(from, to)
+	if len(diff.AddedModules) != 1 || diff.AddedModules[0].Path != "c" {
+		t.Fatalf("expect c added, actual: %+v", diff.AddedModules)
+	}
+	if len(diff.RemovedModules) != 1 || diff.RemovedModules[0].Path != "b" {
+		t.Fatalf("expect b removed, actual: %+v", diff.RemovedModules)
+	}
+	if len(diff.UpdatedModules) != 1 || diff.UpdatedModules[0].OldVersion != "v1.0.0" || diff.UpdatedModules[0].NewVersion != "v1.1.0" {
+		t.Fatalf("expect a updated, actual: %+v", diff.UpdatedModules)
+	}
+	expect := "added modules (1):\n  + c v0.1.0\nremoved modules (1):\n  - b v1.0.0\nupdated modules (1):\n  ~ a v1.0.0 => v1.1.0"
+	if s := FormatDepDiff(diff); s != expect {
+		t.Fatalf("expect:\n%s\nactual:\n%s", expect, s)
+	}
+}
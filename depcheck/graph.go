@@ -0,0 +1,161 @@
+package depcheck
+
+import (
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/xhd2015/go-inspect/graph"
+)
+
+type GraphOptions struct {
+	// Cluster groups packages, e.g. by ClusterByModule,
+	// nil means no groups
+	Cluster func(pkg string) string
+	// Highlight marks packages, e.g. by MatchPatterns
+	Highlight func(pkg string) bool
+}
+
+// Graph converts `deps` to a graph of packages, edges point from importers
+// to imported packages. CollectDeps records each package once, so `deps`
+// only has the first import of a package, collect with NeedDependedBy
+// to have all import edges.
+func Graph(deps []*PkgDepInfo, opts *GraphOptions) *graph.Graph {
+	if opts == nil {
+		opts = &GraphOptions{}
+	}
+	g := &graph.Graph{Name: "deps"}
+	nodes := make(map[string]bool)
+	var addNodes func(dep *PkgDepInfo)
+	addNodes = func(dep *PkgDepInfo) {
+		if nodes[dep.Pkg] {
+			return
+		}
+		nodes[dep.Pkg] = true
+		node := &graph.Node{ID: dep.Pkg}
+		if opts.Cluster != nil {
+			node.Cluster = opts.Cluster(dep.Pkg)
+		}
+		if opts.Highlight != nil {
+			node.Highlight = opts.Highlight(dep.Pkg)
+		}
+		g.Nodes = append(g.Nodes, node)
+		for _, child := range dep.Depends {
+			addNodes(child)
+		}
+	}
+	for _, dep := range deps {
+		addNodes(dep)
+	}
+
+	edges := make(map[[2]string]bool)
+	addEdge := func(from string, to string) {
+		// filtered deps may refer to packages not included
+		if !nodes[from] || !nodes[to] || edges[[2]string{from, to}] {
+			return
+		}
+		edges[[2]string{from, to}] = true
+		g.Edges = append(g.Edges, &graph.Edge{From: from, To: to})
+	}
+	visited := make(map[*PkgDepInfo]bool)
+	var addEdges func(dep *PkgDepInfo)
+	addEdges = func(dep *PkgDepInfo) {
+		if visited[dep] {
+			return
+		}
+		visited[dep] = true
+		for _, child := range dep.Depends {
+			addEdge(dep.Pkg, child.Pkg)
+		}
+		for _, by := range dep.DependedBy {
+			addEdge(by, dep.Pkg)
+		}
+		for _, child := range dep.Depends {
+			addEdges(child)
+		}
+	}
+	for _, dep := range deps {
+		addEdges(dep)
+	}
+	return g
+}
+
+// ClusterByModule groups packages by their modules, std packages
+// are under std. `pkgs` are those passed to CollectDeps, loaded
+// with packages.NeedModule.
+func ClusterByModule(pkgs []*packages.Package) func(pkg string) string {
+	modules := make(map[string]string)
+	packages.Visit(pkgs, func(p *packages.Package) bool {
+		if p.Module != nil {
+			modules[p.PkgPath] = p.Module.Path
+		} else {
+			modules[p.PkgPath] = "std"
+		}
+		return true
+	}, nil)
+	return func(pkg string) string {
+		return modules[pkg]
+	}
+}
+
+// StdPkgs are std packages, keyed by package path
+type StdPkgs map[string]bool
+
+// CollectStdPkgs finds std packages in `pkgs` and their dependencies,
+// which have no module. `pkgs` must be loaded with packages.NeedModule,
+// the fake package C of cgo is also considered std.
+func CollectStdPkgs(pkgs []*packages.Package) StdPkgs {
+	std := StdPkgs{fakePkgC.PkgPath: true}
+	packages.Visit(pkgs, nil, func(p *packages.Package) {
+		if p.Module == nil {
+			std[p.PkgPath] = true
+		}
+	})
+	return std
+}
+
+// MatchPattern reports whether `pkg` matches `pattern`, where `...`
+// matches any string, like go list. As a special case, a trailing
+// /... also matches nothing, e.g. a/... matches a itself.
+func MatchPattern(pattern string, pkg string) bool {
+	if strings.HasSuffix(pattern, "/...") && MatchPattern(strings.TrimSuffix(pattern, "/..."), pkg) {
+		return true
+	}
+	parts := strings.Split(pattern, "...")
+	if len(parts) == 1 {
+		return pattern == pkg
+	}
+	if !strings.HasPrefix(pkg, parts[0]) {
+		return false
+	}
+	rest := pkg[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		idx := strings.Index(rest, part)
+		if idx < 0 {
+			return false
+		}
+		rest = rest[idx+len(part):]
+	}
+	return strings.HasSuffix(rest, last)
+}
+
+// MatchPatterns returns a function reporting whether a package
+// matches any of `patterns`, see MatchPattern. Pattern std
+// matches packages in `std`, a nil `std` matches nothing.
+func MatchPatterns(patterns []string, std StdPkgs) func(pkg string) bool {
+	return func(pkg string) bool {
+		for _, pattern := range patterns {
+			if pattern == "std" {
+				if std[pkg] {
+					return true
+				}
+				continue
+			}
+			if MatchPattern(pattern, pkg) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,71 @@
+package depcheck
+
+import (
+	"testing"
+)
+
+// go test -run TestMatchPattern -v ./depcheck
+func TestMatchPattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		pkg     string
+		match   bool
+	}{
+		{"a/b", "a/b", true},
+		{"a/b", "a/b/c", false},
+		{"a/...", "a", true},
+		{"a/...", "a/b/c", true},
+		{"a/...", "ab", false},
+		{"a/.../c", "a/b/c", true},
+		{"a/.../c", "a/b/d", false},
+		{"...", "fmt", true},
+		{".../internal/...", "a/internal/b", true},
+		{".../internal/...", "a/b", false},
+	}
+	for _, c := range cases {
+		if match := MatchPattern(c.pattern, c.pkg); match != c.match {
+			t.Fatalf("MatchPattern(%q, %q): expect %v, actual: %v", c.pattern, c.pkg, c.match, match)
+		}
+	}
+}
+
+// go test -run TestMatchPatterns -v ./depcheck
+func TestMatchPatterns(t *testing.T) {
+	match := MatchPatterns([]string{"std", "app/..."}, StdPkgs{"net/http": true})
+	if !match("net/http") || !match("app/a") {
+		t.Fatalf("expect net/http and app/a matched")
+	}
+	// std is decided by modules, not by paths
+	if match("fmt") || match("lib/b") {
+		t.Fatalf("expect fmt and lib/b not matched")
+	}
+}
+
+// go test -run TestGraph -v ./depcheck
+func TestGraph(t *testing.T) {
+	c := &PkgDepInfo{Pkg: "app/c", DependedBy: []string{"app/a", "app/b"}}
+	b := &PkgDepInfo{Pkg: "app/b", Depends: []*PkgDepInfo{{Pkg: "app/c"}}, DependedBy: []string{"app"}}
+	a := &PkgDepInfo{Pkg: "app/a", Depends: []*PkgDepInfo{c}, DependedBy: []string{"app"}}
+	root := &PkgDepInfo{Pkg: "app", Depends: []*PkgDepInfo{a, b}}
+
+	g := Graph([]*PkgDepInfo{root}, &GraphOptions{
+		Cluster:   func(pkg string) string { return "app" },
+		Highlight: MatchPatterns([]string{"app/c"}, nil),
+	})
+	if len(g.Nodes) != 4 {
+		t.Fatalf("expect 4 nodes, actual: %d", len(g.Nodes))
+	}
+	for _, n := range g.Nodes {
+		if n.Cluster != "app" || n.Highlight != (n.ID == "app/c") {
+			t.Fatalf("unexpected node: %+v", n)
+		}
+	}
+	// app->a, a->c, app->b, b->c
+	if len(g.Edges) != 4 {
+		var edges []string
+		for _, e := range g.Edges {
+			edges = append(edges, e.From+"->"+e.To)
+		}
+		t.Fatalf("expect 4 edges, actual: %v", edges)
+	}
+}
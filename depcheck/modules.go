@@ -0,0 +1,291 @@
+package depcheck
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/xhd2015/go-inspect/sh"
+)
+
+// StdModule groups std packages, which have no module
+const StdModule = "std"
+
+type ModuleInfo struct {
+	Path    string         `json:"path"`
+	Version string         `json:"version,omitempty"`
+	Replace *ModuleReplace `json:"replace,omitempty"`
+	Main    bool           `json:"main,omitempty"`
+	Dir     string         `json:"dir,omitempty"`
+	Pkgs    []string       `json:"pkgs"`
+	// Size is the estimated bytes of symbols in the binary,
+	// see EstimateModuleSizes
+	Size int64 `json:"size,omitempty"`
+}
+
+type ModuleReplace struct {
+	Path    string `json:"path"`
+	Version string `json:"version,omitempty"`
+}
+
+// CollectModules groups `pkgs` and their dependencies by module, `pkgs`
+// must be loaded with packages.NeedModule. Modules are sorted by path,
+// with std the last.
+func CollectModules(pkgs []*packages.Package) []*ModuleInfo {
+	modMapping := make(map[string]*ModuleInfo)
+	packages.Visit(pkgs, nil, func(p *packages.Package) {
+		path := StdModule
+		if p.Module != nil {
+			path = p.Module.Path
+		}
+		mod := modMapping[path]
+		if mod == nil {
+			mod = &ModuleInfo{Path: path}
+			if p.Module != nil {
+				mod.Version = p.Module.Version
+				mod.Main = p.Module.Main
+				mod.Dir = p.Module.Dir
+				if p.Module.Replace != nil {
+					mod.Replace = &ModuleReplace{Path: p.Module.Replace.Path, Version: p.Module.Replace.Version}
+					mod.Dir = p.Module.Replace.Dir
+				}
+			}
+			modMapping[path] = mod
+		}
+		for _, pkg := range mod.Pkgs {
+			// test variants
+			if pkg == p.PkgPath {
+				return
+			}
+		}
+		mod.Pkgs = append(mod.Pkgs, p.PkgPath)
+	})
+	mods := make([]*ModuleInfo, 0, len(modMapping))
+	for _, mod := range modMapping {
+		sort.Strings(mod.Pkgs)
+		mods = append(mods, mod)
+	}
+	sort.Slice(mods, func(i, j int) bool {
+		if (mods[i].Path == StdModule) != (mods[j].Path == StdModule) {
+			return mods[j].Path == StdModule
+		}
+		return mods[i].Path < mods[j].Path
+	})
+	return mods
+}
+
+// VendorModule is a module listed in vendor/modules.txt
+type VendorModule struct {
+	Path    string
+	Version string
+	Replace *ModuleReplace
+	Pkgs    []string
+}
+
+// ParseVendorModules parses vendor/modules.txt
+func ParseVendorModules(file string) ([]*VendorModule, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var mods []*VendorModule
+	var mod *VendorModule
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "## ") {
+			continue
+		}
+		if !strings.HasPrefix(line, "# ") {
+			if mod == nil {
+				return nil, fmt.Errorf("%s: package %s before any module", file, line)
+			}
+			mod.Pkgs = append(mod.Pkgs, line)
+			continue
+		}
+		// # path version
+		// # path version => replace version
+		// # path => ./dir
+		fields := strings.Fields(strings.TrimPrefix(line, "# "))
+		mod = &VendorModule{Path: fields[0]}
+		fields = fields[1:]
+		if len(fields) > 0 && fields[0] != "=>" {
+			mod.Version = fields[0]
+			fields = fields[1:]
+		}
+		if len(fields) > 1 && fields[0] == "=>" {
+			mod.Replace = &ModuleReplace{Path: fields[1]}
+			if len(fields) > 2 {
+				mod.Replace.Version = fields[2]
+			}
+		}
+		mods = append(mods, mod)
+	}
+	return mods, scanner.Err()
+}
+
+// CheckVendor checks `mods` are consistent with vendor/modules.txt under
+// `dir`, i.e. every dependency module is listed with the same version and
+// replacement, and every package used is listed. It returns nil if there
+// is no vendor/modules.txt.
+func CheckVendor(dir string, mods []*ModuleInfo) ([]string, error) {
+	file := filepath.Join(dir, "vendor", "modules.txt")
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return nil, nil
+	}
+	vendorMods, err := ParseVendorModules(file)
+	if err != nil {
+		return nil, err
+	}
+	vendorMapping := make(map[string]*VendorModule, len(vendorMods))
+	for _, vm := range vendorMods {
+		vendorMapping[vm.Path] = vm
+	}
+	var issues []string
+	for _, mod := range mods {
+		if mod.Main || mod.Path == StdModule {
+			continue
+		}
+		vm := vendorMapping[mod.Path]
+		if vm == nil {
+			issues = append(issues, fmt.Sprintf("%s: not in vendor/modules.txt", mod.Path))
+			continue
+		}
+		if vm.Version != mod.Version {
+			issues = append(issues, fmt.Sprintf("%s: version %s, vendored %s", mod.Path, mod.Version, vm.Version))
+		}
+		if formatReplace(vm.Replace) != formatReplace(mod.Replace) {
+			issues = append(issues, fmt.Sprintf("%s: replace %s, vendored %s", mod.Path, formatReplace(mod.Replace), formatReplace(vm.Replace)))
+		}
+		vendored := make(map[string]bool, len(vm.Pkgs))
+		for _, pkg := range vm.Pkgs {
+			vendored[pkg] = true
+		}
+		for _, pkg := range mod.Pkgs {
+			if !vendored[pkg] {
+				issues = append(issues, fmt.Sprintf("%s: package %s not vendored", mod.Path, pkg))
+			}
+		}
+	}
+	return issues, nil
+}
+
+func formatReplace(r *ModuleReplace) string {
+	if r == nil {
+		return "none"
+	}
+	return strings.TrimSpace(r.Path + " " + r.Version)
+}
+
+// EstimateModuleSizes sets Size of `mods` by summing sizes of symbols in
+// `binary` reported by go tool nm, symbols are attributed to modules by
+// package paths. It returns bytes of symbols not attributed.
+func EstimateModuleSizes(binary string, mods []*ModuleInfo) (unknown int64, err error) {
+	out, _, err := sh.RunBashWithOpts([]string{
+		fmt.Sprintf("go tool nm -size %s", sh.Quote(binary)),
+	}, sh.RunBashOptions{NeedStdOut: true})
+	if err != nil {
+		return 0, err
+	}
+	pkgModules := make(map[string]*ModuleInfo)
+	for _, mod := range mods {
+		mod.Size = 0
+		for _, pkg := range mod.Pkgs {
+			pkgModules[pkg] = mod
+		}
+		// symbols of the main package are named main.xxx
+		if mod.Main {
+			pkgModules["main"] = mod
+		}
+	}
+	for _, line := range strings.Split(out, "\n") {
+		// address size type name
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		mod := pkgModules[symbolPkg(strings.Join(fields[3:], " "))]
+		if mod == nil {
+			unknown += size
+			continue
+		}
+		mod.Size += size
+	}
+	return unknown, nil
+}
+
+// symbolPkg extracts the package path of a symbol, e.g.
+//
+//	github.com/a/b.(*T).M -> github.com/a/b
+//	type:*github.com/a/b.T -> github.com/a/b
+//	go:itab.*github.com/a/b.T,error -> github.com/a/b
+//	gopkg.in/yaml%2ev3.Unmarshal -> gopkg.in/yaml.v3
+func symbolPkg(name string) string {
+	if idx := strings.Index(name, ":"); idx >= 0 {
+		name = name[idx+1:]
+	}
+	name = strings.TrimPrefix(name, "itab.")
+	name = strings.TrimLeft(name, "*[]")
+	// type arguments and receivers may have other packages
+	if idx := strings.IndexAny(name, "[(,"); idx >= 0 {
+		name = name[:idx]
+	}
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return ""
+	}
+	// the linker escapes dots in the last element, e.g. yaml%2ev3
+	return strings.Replace(name[:slash+1+dot], "%2e", ".", -1)
+}
+
+// FormatModules formats `mods` as a table sorted by size, then path
+func FormatModules(mods []*ModuleInfo) string {
+	sorted := make([]*ModuleInfo, len(mods))
+	copy(sorted, mods)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Size > sorted[j].Size
+	})
+	var b bytes.Buffer
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "MODULE\tVERSION\tREPLACE\tPKGS\tSIZE")
+	for _, mod := range sorted {
+		version := mod.Version
+		if mod.Main {
+			version = "(main)"
+		}
+		replace := "-"
+		if mod.Replace != nil {
+			replace = formatReplace(mod.Replace)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", mod.Path, version, replace, len(mod.Pkgs), formatSize(mod.Size))
+	}
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func formatSize(size int64) string {
+	switch {
+	case size == 0:
+		return "-"
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1fM", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1fK", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%dB", size)
+	}
+}
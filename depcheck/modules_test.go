@@ -0,0 +1,137 @@
+package depcheck
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/xhd2015/go-inspect/sh"
+)
+
+func loadModules(t *testing.T) []*ModuleInfo {
+	pkgs, err := packages.Load(&packages.Config{
+		Dir:  "./testdata/mods",
+		Mode: packages.NeedDeps | packages.NeedName | packages.NeedImports | packages.NeedModule,
+	}, "./")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return CollectModules(pkgs)
+}
+
+// go test -run TestCollectModules -v ./depcheck
+func TestCollectModules(t *testing.T) {
+	mods := loadModules(t)
+	t.Logf("modules:\n%s", FormatModules(mods))
+	if len(mods) != 3 || mods[0].Path != "example.com/lib" || mods[1].Path != "example.com/mods" || mods[2].Path != StdModule {
+		t.Fatalf("expect lib, mods and std, actual: %d modules", len(mods))
+	}
+	lib := mods[0]
+	if lib.Version != "v1.0.0" || lib.Replace == nil || lib.Replace.Path != "./lib" {
+		t.Fatalf("unexpected lib: %+v", lib)
+	}
+	if !mods[1].Main || strings.Join(mods[1].Pkgs, " ") != "example.com/mods" {
+		t.Fatalf("unexpected main module: %+v", mods[1])
+	}
+}
+
+// go test -run TestCheckVendor -v ./depcheck
+func TestCheckVendor(t *testing.T) {
+	mods := loadModules(t)
+	dir, err := ioutil.TempDir("", "depcheck-vendor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if issues, err := CheckVendor(dir, mods); err != nil || issues != nil {
+		t.Fatalf("expect no check without vendor, actual: %v %v", issues, err)
+	}
+
+	err = os.MkdirAll(filepath.Join(dir, "vendor"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modulesTxt := "# example.com/lib v1.0.0 => ./lib\n## explicit\nexample.com/lib\n"
+	err = ioutil.WriteFile(filepath.Join(dir, "vendor", "modules.txt"), []byte(modulesTxt), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err := CheckVendor(dir, mods)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 0 {
+		t.Fatalf("expect consistent, actual: %v", issues)
+	}
+
+	modulesTxt = "# example.com/lib v0.9.0\n## explicit\n"
+	err = ioutil.WriteFile(filepath.Join(dir, "vendor", "modules.txt"), []byte(modulesTxt), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err = CheckVendor(dir, mods)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{
+		"example.com/lib: version v1.0.0, vendored v0.9.0",
+		"example.com/lib: replace ./lib, vendored none",
+		"example.com/lib: package example.com/lib not vendored",
+	}
+	if strings.Join(issues, "\n") != strings.Join(expect, "\n") {
+		t.Fatalf("expect issues:\n%s\nactual:\n%s", strings.Join(expect, "\n"), strings.Join(issues, "\n"))
+	}
+}
+
+// go test -run TestEstimateModuleSizes -v ./depcheck
+func TestEstimateModuleSizes(t *testing.T) {
+	mods := loadModules(t)
+	dir, err := ioutil.TempDir("", "depcheck-size")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	binary := filepath.Join(dir, "mods.bin")
+	_, _, err = sh.RunBashWithOpts([]string{
+		"cd ./testdata/mods",
+		"go build -o " + sh.Quote(binary) + " ./",
+	}, sh.RunBashOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown, err := EstimateModuleSizes(binary, mods)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("modules:\n%s\nunknown: %d", FormatModules(mods), unknown)
+	for _, mod := range mods {
+		if mod.Size <= 0 {
+			t.Fatalf("expect size of %s estimated", mod.Path)
+		}
+	}
+	if mods[2].Size < mods[0].Size {
+		t.Fatalf("expect std larger than lib")
+	}
+}
+
+// go test -run TestSymbolPkg -v ./depcheck
+func TestSymbolPkg(t *testing.T) {
+	cases := map[string]string{
+		"github.com/a/b.(*T).M":              "github.com/a/b",
+		"type:*github.com/a/b.T":             "github.com/a/b",
+		"go:itab.*github.com/a/b.T,error":    "github.com/a/b",
+		"gopkg.in/yaml%2ev3.Unmarshal":       "gopkg.in/yaml.v3",
+		"runtime.main":                       "runtime",
+		"github.com/a/b.F[github.com/c/d.T]": "github.com/a/b",
+		"go:buildid":                         "",
+	}
+	for name, pkg := range cases {
+		if actual := symbolPkg(name); actual != pkg {
+			t.Fatalf("symbolPkg(%q): expect %q, actual: %q", name, pkg, actual)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package depcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// Rules is an import policy, usually loaded from a json file:
+//
+//	{
+//	  "rules": [
+//	    {"name": "layering", "from": ["example.com/app/internal/domain/..."], "deny": [".../infra/..."]},
+//	    {"name": "no unsafe", "from": ["..."], "except": ["example.com/app/internal/fastpath"], "deny": ["unsafe"]},
+//	    {"name": "api deps", "from": ["example.com/app/api/..."], "allow": ["std", "example.com/app/..."]}
+//	  ]
+//	}
+//
+// patterns are those of MatchPatterns, std matches packages without module.
+type Rules struct {
+	Rules []*Rule `json:"rules"`
+}
+
+// Rule restricts imports of packages matching From but not Except.
+// They must not import packages matching Deny, and if Allow is
+// not empty, they can only import packages matching Allow.
+type Rule struct {
+	Name   string   `json:"name,omitempty"`
+	Reason string   `json:"reason,omitempty"`
+	From   []string `json:"from"`
+	Except []string `json:"except,omitempty"`
+	Deny   []string `json:"deny,omitempty"`
+	Allow  []string `json:"allow,omitempty"`
+}
+
+type Violation struct {
+	Rule *Rule  `json:"rule"`
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Trace is the import trace from a root package to To
+	Trace []string `json:"trace"`
+}
+
+// LoadRules reads rules from a json file
+func LoadRules(file string) (*Rules, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var rules Rules
+	err = json.Unmarshal(data, &rules)
+	if err != nil {
+		return nil, fmt.Errorf("parse rules %s: %w", file, err)
+	}
+	err = rules.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("rules %s: %w", file, err)
+	}
+	return &rules, nil
+}
+
+func (c *Rules) Validate() error {
+	for i, rule := range c.Rules {
+		if len(rule.From) == 0 {
+			return fmt.Errorf("rule %s: requires from", rule.name(i))
+		}
+		if len(rule.Deny) == 0 && len(rule.Allow) == 0 {
+			return fmt.Errorf("rule %s: requires deny or allow", rule.name(i))
+		}
+	}
+	return nil
+}
+
+func (c *Rule) name(i int) string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return fmt.Sprintf("#%d", i)
+}
+
+// Violates reports whether importing `to` from `from` violates
+// the rule, pattern std matches packages in `std`
+func (c *Rule) Violates(from string, to string, std StdPkgs) bool {
+	if !MatchPatterns(c.From, std)(from) || MatchPatterns(c.Except, std)(from) {
+		return false
+	}
+	if MatchPatterns(c.Deny, std)(to) {
+		return true
+	}
+	return len(c.Allow) > 0 && !MatchPatterns(c.Allow, std)(to)
+}
+
+// CheckRules evaluates `rules` against every import edge, `deps` and
+// `pkgMapping` are results of CollectDeps with NeedDependedBy, `std`
+// are results of CollectStdPkgs of the same packages.
+// Violations are sorted by rule, importer and imported package.
+func CheckRules(deps []*PkgDepInfo, pkgMapping map[string]*PkgDepInfo, rules *Rules, std StdPkgs) []*Violation {
+	if rules == nil || len(rules.Rules) == 0 {
+		return nil
+	}
+	pkgs := make([]string, 0, len(pkgMapping))
+	for pkg := range pkgMapping {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+
+	var violations []*Violation
+	for _, rule := range rules.Rules {
+		var ruleViolations []*Violation
+		for _, to := range pkgs {
+			seen := make(map[string]bool)
+			for _, from := range pkgMapping[to].DependedBy {
+				// root packages are depended by the empty package
+				if from == "" || seen[from] {
+					continue
+				}
+				seen[from] = true
+				if !rule.Violates(from, to, std) {
+					continue
+				}
+				trace := GetImportTrace(deps, from)
+				ruleViolations = append(ruleViolations, &Violation{
+					Rule:  rule,
+					From:  from,
+					To:    to,
+					Trace: append(trace[:len(trace):len(trace)], to),
+				})
+			}
+		}
+		sort.SliceStable(ruleViolations, func(i, j int) bool {
+			return ruleViolations[i].From < ruleViolations[j].From
+		})
+		violations = append(violations, ruleViolations...)
+	}
+	return violations
+}
+
+// FormatViolations formats each violation with its import trace:
+//
+//	rule layering: a/domain imports a/infra
+//	  a
+//	    a/domain
+//	      a/infra
+func FormatViolations(violations []*Violation) string {
+	var lines []string
+	for _, v := range violations {
+		line := fmt.Sprintf("%s imports %s", v.From, v.To)
+		if v.Rule.Name != "" {
+			line = fmt.Sprintf("rule %s: %s", v.Rule.Name, line)
+		}
+		if v.Rule.Reason != "" {
+			line += " (" + v.Rule.Reason + ")"
+		}
+		lines = append(lines, line)
+		for i, pkg := range v.Trace {
+			lines = append(lines, strings.Repeat("  ", i+1)+pkg)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,75 @@
+package depcheck
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func checkRules(t *testing.T, dir string, rules *Rules) []*Violation {
+	pkgs, err := packages.Load(&packages.Config{
+		Dir:  dir,
+		Mode: packages.NeedDeps | packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedImports | packages.NeedModule,
+	}, "./")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deps, pkgMapping, err := CollectDeps(pkgs, &CollectOptions{NeedDependedBy: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return CheckRules(deps, pkgMapping, rules, CollectStdPkgs(pkgs))
+}
+
+// go test -run TestCheckRules -v ./depcheck
+func TestCheckRules(t *testing.T) {
+	rules, err := LoadRules("./testdata/layers/rules.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	violations := checkRules(t, "./testdata/layers", rules)
+	var edges []string
+	for _, v := range violations {
+		edges = append(edges, v.Rule.Name+": "+v.From+"->"+v.To)
+	}
+	expect := []string{
+		"layering: example.com/layers/domain->example.com/layers/infra",
+		"no unsafe: example.com/layers/infra->unsafe",
+	}
+	if strings.Join(edges, "\n") != strings.Join(expect, "\n") {
+		t.Fatalf("expect violations:\n%s\nactual:\n%s", strings.Join(expect, "\n"), strings.Join(edges, "\n"))
+	}
+	trace := strings.Join(violations[0].Trace, " ")
+	if trace != "example.com/layers example.com/layers/api example.com/layers/domain example.com/layers/infra" {
+		t.Fatalf("unexpected trace: %s", trace)
+	}
+	t.Logf("violations:\n%s", FormatViolations(violations))
+
+	err = (&Rules{Rules: []*Rule{{From: []string{"..."}}}}).Validate()
+	if err == nil {
+		t.Fatalf("expect error of rule without deny or allow")
+	}
+}
+
+// go test -run TestCheckRulesDotlessModule -v ./depcheck
+func TestCheckRulesDotlessModule(t *testing.T) {
+	rules, err := LoadRules("./testdata/dotless/rules.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// packages of module app look like std by path,
+	// they must not be matched by std
+	violations := checkRules(t, "./testdata/dotless", rules)
+	var edges []string
+	for _, v := range violations {
+		edges = append(edges, v.Rule.Name+": "+v.From+"->"+v.To)
+	}
+	expect := []string{
+		"core deps: app/core->app/util",
+		"no unsafe: app/util->unsafe",
+	}
+	if strings.Join(edges, "\n") != strings.Join(expect, "\n") {
+		t.Fatalf("expect violations:\n%s\nactual:\n%s", strings.Join(expect, "\n"), strings.Join(edges, "\n"))
+	}
+}
@@ -0,0 +1,15 @@
+package a_test
+
+import (
+	"testing"
+
+	"example.com/cycle/a"
+	"example.com/cycle/b"
+)
+
+// b imports a, the external test of a closes a cycle through b
+func TestA(t *testing.T) {
+	if b.B() != "b"+a.A() {
+		t.Fatal(b.B())
+	}
+}
@@ -0,0 +1,10 @@
+package c
+
+import (
+	"example.com/cycle/a"
+	"example.com/cycle/b"
+)
+
+func C() string {
+	return a.A() + b.B()
+}
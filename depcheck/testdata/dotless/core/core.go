@@ -0,0 +1,11 @@
+package core
+
+import (
+	"strings"
+
+	"app/util"
+)
+
+func Name() string {
+	return strings.ToUpper(util.Name())
+}
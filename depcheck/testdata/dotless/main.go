@@ -0,0 +1,7 @@
+package main
+
+import "app/core"
+
+func main() {
+	println(core.Name())
+}
@@ -0,0 +1,8 @@
+package util
+
+import "unsafe"
+
+func Name() string {
+	b := []byte("util")
+	return *(*string)(unsafe.Pointer(&b))
+}
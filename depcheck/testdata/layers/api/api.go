@@ -0,0 +1,11 @@
+package api
+
+import (
+	"fmt"
+
+	"example.com/layers/domain"
+)
+
+func Serve() {
+	fmt.Println(domain.Name())
+}
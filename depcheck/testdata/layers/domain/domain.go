@@ -0,0 +1,7 @@
+package domain
+
+import "example.com/layers/infra"
+
+func Name() string {
+	return infra.Name()
+}
@@ -0,0 +1,7 @@
+package fast
+
+import "unsafe"
+
+func Run() {
+	_ = unsafe.Sizeof(0)
+}
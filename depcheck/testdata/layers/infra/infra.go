@@ -0,0 +1,8 @@
+package infra
+
+import "unsafe"
+
+func Name() string {
+	b := []byte("infra")
+	return *(*string)(unsafe.Pointer(&b))
+}
@@ -0,0 +1,11 @@
+package main
+
+import (
+	"example.com/layers/api"
+	"example.com/layers/fast"
+)
+
+func main() {
+	api.Serve()
+	fast.Run()
+}
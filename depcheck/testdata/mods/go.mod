@@ -0,0 +1,7 @@
+module example.com/mods
+
+go 1.13
+
+require example.com/lib v1.0.0
+
+replace example.com/lib => ./lib
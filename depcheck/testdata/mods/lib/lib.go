@@ -0,0 +1,12 @@
+package lib
+
+import "strings"
+
+var greetings = map[string]string{
+	"en": "hello",
+	"fr": "bonjour",
+}
+
+func Greet(name string) string {
+	return strings.Title(greetings["en"]) + ", " + name
+}
@@ -1,34 +1,37 @@
-// Code generated by go-mock; DO NOT EDIT.
+// Code generated by go-inspect mock; DO NOT EDIT.
 
 package biz
 
 import (
-    "context"
-    "github.com/xhd2015/go-inspect/example/demo/biz"
-    _mock "github.com/xhd2015/go-inspect/mock"
+	"context"
+	"github.com/xhd2015/go-inspect/example/demo/biz"
+	_mock "github.com/xhd2015/go-inspect/mock"
 )
 
 const _SKIP_MOCK = true
 const FULL_PKG_NAME = "github.com/xhd2015/go-inspect/example/demo/biz"
 
-func Setup(ctx context.Context,setup func(m *M)) context.Context {
-    m:=M{}
-    setup(&m)
-    return _mock.WithMockSetup(ctx,FULL_PKG_NAME,m)
+func Setup(ctx context.Context, setup func(m *M)) context.Context {
+	m := M{}
+	setup(&m)
+	return _mock.WithMockSetup(ctx, FULL_PKG_NAME, m)
 }
 
-
 type M struct {
-    Run func(ctx context.Context, status int, _ string)(int, error)
-    Status struct{
-            Run func(c biz.Status,ctx context.Context, status int, _ string)(int, error)
-    }
+	Run    func(ctx context.Context, status int, _ string) (int, error)
+	Status struct {
+		Run func(c biz.Status, ctx context.Context, status int, _ string) (int, error)
+	}
 }
 
-/* prodives quick link */
-var _ = func() { type Pair [2]interface{};e:=M{};_ = map[string]interface{}{
-    "Run": Pair{e.Run,biz.Run},
-    "Status":map[string]interface{}{
-             "Run": Pair{e.Status.Run,((*biz.Status)(nil)).Run},
-    },
-}}
+/* provides quick link */
+var _ = func() {
+	type Pair [2]interface{}
+	e := M{}
+	_ = map[string]interface{}{
+		"Run": Pair{e.Run, biz.Run},
+		"Status": map[string]interface{}{
+			"Run": Pair{e.Status.Run, ((*biz.Status)(nil)).Run},
+		},
+	}
+}
@@ -0,0 +1,88 @@
+// Package graph exports directed graphs, such as import graphs
+// of depcheck and call graphs of analysis, as Graphviz DOT,
+// Mermaid and GraphML.
+package graph
+
+import (
+	"fmt"
+	"io"
+)
+
+type Graph struct {
+	Name  string
+	Nodes []*Node
+	Edges []*Edge
+}
+
+type Node struct {
+	ID    string
+	Label string // default ID
+	// Cluster groups nodes, e.g. by module or package,
+	// empty means the node is not in any group
+	Cluster string
+	// Highlight marks nodes matching a filter
+	Highlight bool
+}
+
+type Edge struct {
+	From  string
+	To    string
+	Label string // optional
+}
+
+// Format is the output format of Write
+type Format string
+
+const (
+	FormatDOT     Format = "dot"
+	FormatMermaid Format = "mermaid"
+	FormatGraphML Format = "graphml"
+)
+
+// Formats lists all supported formats
+var Formats = []Format{FormatDOT, FormatMermaid, FormatGraphML}
+
+// ParseFormat checks `s` is one of Formats
+func ParseFormat(s string) (Format, error) {
+	for _, format := range Formats {
+		if string(format) == s {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown graph format: %s, expect one of %v", s, Formats)
+}
+
+// Write writes `g` in `format`
+func Write(w io.Writer, g *Graph, format Format) error {
+	switch format {
+	case FormatDOT:
+		return WriteDOT(w, g)
+	case FormatMermaid:
+		return WriteMermaid(w, g)
+	case FormatGraphML:
+		return WriteGraphML(w, g)
+	default:
+		return fmt.Errorf("unknown graph format: %s", format)
+	}
+}
+
+func (c *Node) label() string {
+	if c.Label == "" {
+		return c.ID
+	}
+	return c.Label
+}
+
+// clusters returns names of clusters in the order they first
+// appear, and nodes of each cluster. Nodes not in any cluster
+// are under the empty name.
+func (c *Graph) clusters() (names []string, nodes map[string][]*Node) {
+	nodes = make(map[string][]*Node)
+	for _, n := range c.Nodes {
+		if n.Cluster != "" && nodes[n.Cluster] == nil {
+			names = append(names, n.Cluster)
+		}
+		nodes[n.Cluster] = append(nodes[n.Cluster], n)
+	}
+	return names, nodes
+}
@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func newTestGraph() *Graph {
+	return &Graph{
+		Name: "deps",
+		Nodes: []*Node{
+			{ID: "a", Cluster: "mod"},
+			{ID: "a/b", Label: "b", Cluster: "mod", Highlight: true},
+			{ID: "fmt"},
+		},
+		Edges: []*Edge{
+			{From: "a", To: "a/b"},
+			{From: "a/b", To: "fmt", Label: `"x"`},
+		},
+	}
+}
+
+func writeString(t *testing.T, format Format) string {
+	var buf bytes.Buffer
+	if err := Write(&buf, newTestGraph(), format); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func expectContains(t *testing.T, s string, subs ...string) {
+	for _, sub := range subs {
+		if !strings.Contains(s, sub) {
+			t.Fatalf("expect output contains %q, actual:\n%s", sub, s)
+		}
+	}
+}
+
+// go test -run TestWriteDOT -v ./graph
+func TestWriteDOT(t *testing.T) {
+	s := writeString(t, FormatDOT)
+	expectContains(t, s,
+		`digraph "deps" {`,
+		`subgraph "cluster_0" {`,
+		`label="mod";`,
+		`"a/b" [label="b", style=filled, fillcolor="#ffd54f"];`,
+		`  "fmt" [label="fmt"];`,
+		`"a/b" -> "fmt" [label="\"x\""];`,
+	)
+}
+
+// go test -run TestWriteMermaid -v ./graph
+func TestWriteMermaid(t *testing.T) {
+	s := writeString(t, FormatMermaid)
+	expectContains(t, s,
+		"flowchart LR\n",
+		`subgraph c0["mod"]`,
+		`n1["b"]`,
+		`n0 --> n1`,
+		`n1 -->|"#quot;x#quot;"| n2`,
+		"class n1 highlight",
+	)
+
+	var buf bytes.Buffer
+	g := newTestGraph()
+	g.Edges = append(g.Edges, &Edge{From: "a", To: "missing"})
+	if err := WriteMermaid(&buf, g); err == nil {
+		t.Fatalf("expect error of edge to missing node")
+	}
+}
+
+// go test -run TestWriteGraphML -v ./graph
+func TestWriteGraphML(t *testing.T) {
+	s := writeString(t, FormatGraphML)
+	expectContains(t, s,
+		`<graph id="deps" edgedefault="directed">`,
+		`<data key="cluster">mod</data>`,
+		`<data key="highlight">true</data>`,
+		`<edge id="e0" source="a" target="a/b"/>`,
+		`<data key="label">&#34;x&#34;</data>`,
+	)
+	if _, err := ParseFormat("svg"); err == nil {
+		t.Fatalf("expect svg not supported")
+	}
+}
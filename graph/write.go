@@ -0,0 +1,145 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// highlightColor fills highlighted nodes
+const highlightColor = "#ffd54f"
+
+// WriteDOT writes `g` as a Graphviz digraph, clusters
+// are subgraphs, and highlighted nodes are filled
+func WriteDOT(w io.Writer, g *Graph) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "digraph %s {\n", strconv.Quote(g.Name))
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+
+	writeNode := func(indent string, n *Node) {
+		attrs := "label=" + strconv.Quote(n.label())
+		if n.Highlight {
+			attrs += fmt.Sprintf(", style=filled, fillcolor=%q", highlightColor)
+		}
+		fmt.Fprintf(b, "%s%s [%s];\n", indent, strconv.Quote(n.ID), attrs)
+	}
+	names, nodes := g.clusters()
+	for i, name := range names {
+		fmt.Fprintf(b, "  subgraph %s {\n", strconv.Quote(fmt.Sprintf("cluster_%d", i)))
+		fmt.Fprintf(b, "    label=%s;\n", strconv.Quote(name))
+		for _, n := range nodes[name] {
+			writeNode("    ", n)
+		}
+		b.WriteString("  }\n")
+	}
+	for _, n := range nodes[""] {
+		writeNode("  ", n)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(b, "  %s -> %s", strconv.Quote(e.From), strconv.Quote(e.To))
+		if e.Label != "" {
+			fmt.Fprintf(b, " [label=%s]", strconv.Quote(e.Label))
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+	return b.Flush()
+}
+
+// WriteMermaid writes `g` as a Mermaid flowchart, clusters
+// are subgraphs, and highlighted nodes are of class highlight
+func WriteMermaid(w io.Writer, g *Graph) error {
+	b := bufio.NewWriter(w)
+	b.WriteString("flowchart LR\n")
+
+	// ids in mermaid are restricted, use n0, n1...
+	ids := make(map[string]string, len(g.Nodes))
+	for i, n := range g.Nodes {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+	}
+	writeNode := func(indent string, n *Node) {
+		fmt.Fprintf(b, "%s%s[\"%s\"]\n", indent, ids[n.ID], mermaidEscape(n.label()))
+	}
+	names, nodes := g.clusters()
+	for i, name := range names {
+		fmt.Fprintf(b, "  subgraph c%d[\"%s\"]\n", i, mermaidEscape(name))
+		for _, n := range nodes[name] {
+			writeNode("    ", n)
+		}
+		b.WriteString("  end\n")
+	}
+	for _, n := range nodes[""] {
+		writeNode("  ", n)
+	}
+	for _, e := range g.Edges {
+		from, to := ids[e.From], ids[e.To]
+		if from == "" || to == "" {
+			return fmt.Errorf("edge %s -> %s: node not found", e.From, e.To)
+		}
+		if e.Label != "" {
+			fmt.Fprintf(b, "  %s -->|\"%s\"| %s\n", from, mermaidEscape(e.Label), to)
+		} else {
+			fmt.Fprintf(b, "  %s --> %s\n", from, to)
+		}
+	}
+	var highlights []string
+	for _, n := range g.Nodes {
+		if n.Highlight {
+			highlights = append(highlights, ids[n.ID])
+		}
+	}
+	if len(highlights) > 0 {
+		fmt.Fprintf(b, "  classDef highlight fill:%s\n", highlightColor)
+		fmt.Fprintf(b, "  class %s highlight\n", strings.Join(highlights, ","))
+	}
+	return b.Flush()
+}
+
+// mermaidEscape escapes `s` inside double quotes
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(s)
+}
+
+// WriteGraphML writes `g` as GraphML, the cluster and
+// highlight of nodes are data of keys cluster and highlight
+func WriteGraphML(w io.Writer, g *Graph) error {
+	b := bufio.NewWriter(w)
+	b.WriteString(xml.Header)
+	b.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	b.WriteString(`  <key id="label" for="all" attr.name="label" attr.type="string"/>` + "\n")
+	b.WriteString(`  <key id="cluster" for="node" attr.name="cluster" attr.type="string"/>` + "\n")
+	b.WriteString(`  <key id="highlight" for="node" attr.name="highlight" attr.type="boolean"><default>false</default></key>` + "\n")
+	fmt.Fprintf(b, "  <graph id=\"%s\" edgedefault=\"directed\">\n", xmlEscape(g.Name))
+	for _, n := range g.Nodes {
+		fmt.Fprintf(b, "    <node id=\"%s\">\n", xmlEscape(n.ID))
+		fmt.Fprintf(b, "      <data key=\"label\">%s</data>\n", xmlEscape(n.label()))
+		if n.Cluster != "" {
+			fmt.Fprintf(b, "      <data key=\"cluster\">%s</data>\n", xmlEscape(n.Cluster))
+		}
+		if n.Highlight {
+			b.WriteString("      <data key=\"highlight\">true</data>\n")
+		}
+		b.WriteString("    </node>\n")
+	}
+	for i, e := range g.Edges {
+		fmt.Fprintf(b, "    <edge id=\"e%d\" source=\"%s\" target=\"%s\"", i, xmlEscape(e.From), xmlEscape(e.To))
+		if e.Label == "" {
+			b.WriteString("/>\n")
+			continue
+		}
+		fmt.Fprintf(b, ">\n      <data key=\"label\">%s</data>\n    </edge>\n", xmlEscape(e.Label))
+	}
+	b.WriteString("  </graph>\n")
+	b.WriteString("</graphml>\n")
+	return b.Flush()
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
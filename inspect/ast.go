@@ -57,6 +57,9 @@ type FuncContext interface {
 	Recv() FieldContext
 	ParseRecvInfo() (ptr bool, typeName string)
 	Type() FuncType
+	// TypeParams of generic func, methods
+	// have them in the receiver instead
+	TypeParams() FieldListContext
 
 	RangeVars(fn func(i int, f FieldVar) bool)
 
@@ -67,6 +70,7 @@ type FuncType interface {
 	Pkg() Pkg
 	AST() *ast.FuncType
 	ASTNode() ast.Node
+	TypeParams() FieldListContext
 	Args() FieldListContext
 	Results() FieldListContext
 }
@@ -122,11 +126,17 @@ type Expr interface {
 	ASTNode() ast.Node
 
 	// RefersToQualified reports whether this expr referes
-	// to a qualified ident after unwrapping alias(if any)
+	// to a qualified ident after unwrapping alias(if any).
+	// It panics with ErrTypesNotAvailable if types are not loaded.
 	RefersToQualified(pkgPath string, name string) bool
 
-	// ResolveType try to resolve this expr to a declared type
+	// ResolveType try to resolve this expr to a declared type.
+	// It panics with ErrTypesNotAvailable if types are not loaded.
 	ResolveType() TypeContext
+
+	// TryResolveType is like ResolveType, but returns
+	// ErrTypesNotAvailable instead of panicking
+	TryResolveType() (TypeContext, error)
 }
 
 type fileContent struct {
@@ -283,11 +293,11 @@ func (c *funcImpl) QuanlifiedName() string {
 	if rcv == nil {
 		return c.Name()
 	}
-	ptr, n := rcv.TypeExpr().ResolveType().GetNamedOrPtr()
+	ptr, n := recvTypeInfo(rcv.TypeExpr())
 	if ptr {
-		return "*" + n.Obj().Name() + "." + c.Name()
+		return "*" + n + "." + c.Name()
 	}
-	return n.Obj().Name() + "." + c.Name()
+	return n + "." + c.Name()
 }
 
 // Body implements FuncContext
@@ -315,27 +325,64 @@ func (c *funcImpl) ParseRecvInfo() (ptr bool, typeName string) {
 		panic(fmt.Errorf("method must have exactly 1 recv,found:%d", c.Recv().VarLen()))
 	}
 	c.Recv().RangeVars(func(i int, f FieldVar) bool {
-		var name *types.Named
-		ptr, name = f.TypeExpr().ResolveType().GetNamedOrPtr()
-		if name != nil {
-			typeName = name.Obj().Name()
-		}
+		ptr, typeName = recvTypeInfo(f.TypeExpr())
 		return true
 	})
 	return
 }
 
+// recvTypeInfo resolves the receiver type by types if loaded,
+// otherwise by syntax, which does not follow aliases
+func recvTypeInfo(typeExpr Expr) (ptr bool, typeName string) {
+	t, err := typeExpr.TryResolveType()
+	if err != nil {
+		return parseRecvType(typeExpr.AST())
+	}
+	ptr, name := t.GetNamedOrPtr()
+	if name != nil {
+		typeName = name.Obj().Name()
+	}
+	return
+}
+
+// parseRecvType parses receivers like T, *T, (*T) and *T[K]
+func parseRecvType(expr ast.Expr) (ptr bool, typeName string) {
+	for {
+		switch e := expr.(type) {
+		case *ast.Ident:
+			return ptr, e.Name
+		case *ast.StarExpr:
+			ptr = true
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		default:
+			expr = indexListX(expr)
+			if expr == nil {
+				return ptr, ""
+			}
+		}
+	}
+}
+
 // Type implements FuncContext
 func (c *funcImpl) Type() FuncType {
 	return NewFuncType(c.file.Pkg(), c.ast.Type)
 }
+
+// TypeParams implements FuncContext
+func (c *funcImpl) TypeParams() FieldListContext {
+	return c.Type().TypeParams()
+}
 func (c *funcImpl) RangeVars(fn func(i int, f FieldVar) bool) {
 	idx := -1
 	done := false
 	tf := func(_ int, f FieldVar) bool {
 		idx++
-		done = fn(idx, f)
-		return done
+		done = !fn(idx, f)
+		return !done
 	}
 	recv := c.Recv()
 	if recv != nil {
@@ -380,6 +427,11 @@ func (c *funcType) ASTNode() ast.Node {
 	return c.ast
 }
 
+// TypeParams implements FuncType
+func (c *funcType) TypeParams() FieldListContext {
+	return NewFieldList(c.pkg, typeParamsOf(c.ast))
+}
+
 // Args implements FuncType
 func (c *funcType) Args() FieldListContext {
 	return NewFieldList(c.pkg, c.ast.Params)
@@ -548,18 +600,24 @@ func (c *fieldList) Index(i int) FieldContext {
 
 // Len implements FieldListContext
 func (c *fieldList) Len() int {
+	if c.ast == nil {
+		return 0
+	}
 	return len(c.ast.List)
 }
 
 // RangeVars implements FieldListContext
 func (c *fieldList) RangeVars(fn func(i int, f FieldVar) bool) {
+	if c.ast == nil {
+		return
+	}
 	idx := -1
 	done := false
 	for i, _ := range c.ast.List {
 		c.Index(i).RangeVars(func(_ int, f FieldVar) bool {
 			idx++
-			done = fn(idx, f)
-			return done
+			done = !fn(idx, f)
+			return !done
 		})
 		if done {
 			return
@@ -633,16 +691,31 @@ func (c *expr) ASTNode() ast.Node {
 
 // Pkg implements Expr
 func (c *expr) Pkg() Pkg {
-	return c.Pkg()
+	return c.pkg
 }
 
 // RefersToQualified implements Expr
 func (c *expr) RefersToQualified(pkgPath string, name string) bool {
+	if !HasTypes(c.pkg) {
+		panic(ErrTypesNotAvailable)
+	}
 	// TODO: also check alias
 	return util.TokenHasQualifiedName(c.pkg.GoPkg(), c.ast, pkgPath, name)
 }
 
 // ResolveType implements Expr
 func (c *expr) ResolveType() TypeContext {
-	return NewType(c.pkg.GoPkg().TypesInfo.TypeOf(c.ast))
+	t, err := c.TryResolveType()
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// TryResolveType implements Expr
+func (c *expr) TryResolveType() (TypeContext, error) {
+	if !HasTypes(c.pkg) {
+		return nil, ErrTypesNotAvailable
+	}
+	return NewType(c.pkg.GoPkg().TypesInfo.TypeOf(c.ast)), nil
 }
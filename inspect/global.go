@@ -5,6 +5,7 @@ import (
 	"go/ast"
 	"go/token"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"unsafe"
@@ -14,6 +15,10 @@ import (
 	"github.com/xhd2015/go-inspect/inspect/util"
 )
 
+// Global is safe for concurrent use: modules, packages and their
+// AST and types are read-only once loaded, and the file contents
+// and Registry wrappers created lazily are guarded. The AST must
+// not be modified.
 type Global interface {
 	// code of an ast Node
 	FileSet() *token.FileSet
@@ -52,6 +57,11 @@ type LoadInfo interface {
 	FileSet() *token.FileSet
 	MainModule() Module
 
+	// MainModules returns all main modules, sorted by path.
+	// there are more than one in go.work workspace mode,
+	// otherwise it only contains MainModule
+	MainModules() []Module
+
 	// StarterPkgs returns the pkgs
 	// specified by args,
 	// if test packages are loaded,
@@ -155,7 +165,16 @@ func NewGlobal(fset *token.FileSet, root string, pkgs []*packages.Package) Globa
 		starterPkgs = append(starterPkgs, loadedPkg)
 	}
 	// main
-	mainGoMod := extractSingleMod(pkgs)
+	mainGoMod := extractSingleMod(pkgs, root)
+	var mainMods []Module
+	for _, m := range modMap {
+		if m.ModInfo().Main {
+			mainMods = append(mainMods, m)
+		}
+	}
+	sort.Slice(mainMods, func(i, j int) bool {
+		return mainMods[i].Path() < mainMods[j].Path()
+	})
 
 	g.modMap = modMap
 	g.pkgMap = pkgMap
@@ -165,6 +184,7 @@ func NewGlobal(fset *token.FileSet, root string, pkgs []*packages.Package) Globa
 		fset:      fset,
 		startPkgs: starterPkgs,
 		mainMod:   modMap[mainGoMod.Path],
+		mainMods:  mainMods,
 	}
 	g.registry = regBuilder.build()
 	return g
@@ -316,11 +336,15 @@ type loadInfo struct {
 	fset      *token.FileSet
 	startPkgs []Pkg
 	mainMod   Module
+	mainMods  []Module
 }
 
 var _ LoadInfo = ((*loadInfo)(nil))
 
-func extractSingleMod(starterPkgs []*packages.Package) *packages.Module {
+// extractSingleMod returns the module of starter packages.
+// In workspace mode, they can be in different main modules, then the
+// one containing `root` is returned.
+func extractSingleMod(starterPkgs []*packages.Package, root string) *packages.Module {
 	// debug
 	// for _, p := range starterPkgs {
 	// 	fmt.Printf("starter pkg:%v\n", p.PkgPath)
@@ -346,7 +370,13 @@ func extractSingleMod(starterPkgs []*packages.Package) *packages.Module {
 		}
 		// check consistence
 		if resMod != mod && resMod.Path != mod.Path {
-			panic(fmt.Errorf("package %s has different module %v, want a single module:%v", p.PkgPath, mod, resMod))
+			if !resMod.Main || !mod.Main {
+				panic(fmt.Errorf("package %s has different module %v, want a single module:%v", p.PkgPath, mod, resMod))
+			}
+			// go.work workspace
+			if root != "" && (root == mod.Dir || strings.HasPrefix(root, mod.Dir+"/")) {
+				resMod = mod
+			}
 		}
 	}
 	if resMod == nil {
@@ -369,6 +399,11 @@ func (c *loadInfo) MainModule() Module {
 	return c.mainMod
 }
 
+// MainModules implements LoadInfo
+func (c *loadInfo) MainModules() []Module {
+	return c.mainMods
+}
+
 func (c *loadInfo) StarterPkgs() []Pkg {
 	return c.startPkgs
 }
@@ -17,7 +17,9 @@ type LoadOptions struct {
 	ProjectDir string
 	ForTest    bool
 	BuildFlags []string // see FlagBuilder
-	LoadMode   []packages.LoadMode
+	// LoadMode default loads everything, including types,
+	// see LoadModeSyntax for a syntax-only mode
+	LoadMode []packages.LoadMode
 }
 
 func LoadPackages(args []string, opts *LoadOptions) (inspect.Global, error) {
@@ -97,7 +99,7 @@ func GetPkgModule(p *packages.Package) *packages.Module {
 
 func normalizePkgName(pkg *packages.Package) string {
 	name := pkg.Name
-	if name == "" {
+	if name == "" && pkg.Types != nil {
 		name = pkg.Types.Name()
 	}
 	return name
@@ -105,7 +107,7 @@ func normalizePkgName(pkg *packages.Package) string {
 
 func normalizePkgPath(pkg *packages.Package) string {
 	pkgPath := pkg.PkgPath
-	if pkgPath == "" {
+	if pkgPath == "" && pkg.Types != nil {
 		pkgPath = pkg.Types.Path()
 	}
 
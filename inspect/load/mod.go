@@ -6,13 +6,17 @@ import (
 
 // packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles | packages.NeedTypesSizes | packages.NeedSyntax | packages.NeedDeps | packages.NeedImports | packages.NeedTypes | packages.NeedTypesInfo | packages.NeedModule
 
+// LoadModeSyntax parses packages and their dependencies without
+// type-checking them, which is much cheaper than the default mode.
+// Pkg.TypePkg() and Pkg.GoPkg().TypesInfo are nil, type-dependent
+// APIs of inspect fail with inspect.ErrTypesNotAvailable.
 var LoadModeSyntax = []packages.LoadMode{
 	packages.NeedName,
-	// packages.NeedFiles, // fill the GoFiles fields
-	// packages.NeedCompiledGoFiles,
+	packages.NeedFiles,           // required by NeedSyntax
+	packages.NeedCompiledGoFiles, // files to be parsed
 	// packages.NeedTypesSizes,
 	packages.NeedSyntax,
-	// packages.NeedDeps,
+	packages.NeedDeps,    // dependencies may be rewritten as well
 	packages.NeedImports, // required when you need to do MustImports, this will ensure an initial import list is correctly created
 	// packages.NeedTypes,
 	// packages.NeedTypesInfo,
@@ -1,6 +1,7 @@
 package inspect
 
 import (
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/types"
@@ -12,6 +13,10 @@ import (
 	"github.com/xhd2015/go-inspect/inspect/util"
 )
 
+// ErrTypesNotAvailable is returned, or panicked with, by type-dependent
+// APIs when packages are loaded without types, see load.LoadModeSyntax
+var ErrTypesNotAvailable = errors.New("types not available: packages are loaded in syntax-only mode")
+
 type Module interface {
 	Global() Global
 	Path() string // the path after replaced(if any)
@@ -38,6 +43,7 @@ type Pkg interface {
 	Dir() string  // absolute path to directory
 	Name() string // name
 	GoPkg() *packages.Package
+	// TypePkg is nil if types are not loaded, see HasTypes
 	TypePkg() *types.Package
 
 	// TestPkg returns the {PkgPath}.test
@@ -211,6 +217,13 @@ func (c *pkg) TypePkg() *types.Package {
 	return c.goPkg.Types
 }
 
+// HasTypes reports whether `pkg` is type-checked, which
+// is false if it is loaded with load.LoadModeSyntax
+func HasTypes(pkg Pkg) bool {
+	goPkg := pkg.GoPkg()
+	return goPkg.Types != nil && goPkg.TypesInfo != nil
+}
+
 // Name implements Pkg
 func (c *pkg) Name() string {
 	return c.goPkg.Name
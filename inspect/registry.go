@@ -22,6 +22,8 @@ import (
 // the ast Node to their convienent wrapper
 // types appear here meaning they have special support
 // it is effectively an AST node factory.
+// It is safe for concurrent use, wrappers created
+// lazily are guarded.
 type Registry interface {
 	// File reverse the look up
 	// background: any AST Node must belong to a certain file
@@ -85,6 +87,8 @@ func (c *registryBuilder) build() Registry {
 
 type registry struct {
 	parentMap map[ast.Node]ast.Node
+	// nodeMap is filled lazily, guarded by nodeMutex
+	nodeMutex sync.Mutex
 	nodeMap   map[ast.Node]Node
 	pkgMap    map[*ast.Package]Pkg
 	fileMap   map[*ast.File]FileContext
@@ -128,6 +132,8 @@ func (c *registry) FuncDecl(node *ast.FuncDecl) FuncContext {
 	if node == nil {
 		return nil
 	}
+	c.nodeMutex.Lock()
+	defer c.nodeMutex.Unlock()
 	f := c.nodeMap[node]
 	if f == nil {
 		f := NewFunc(c.fileMap[c.mustFileOf(node)], node)
@@ -142,6 +148,8 @@ func (c *registry) FuncType(node *ast.FuncType) FuncType {
 	if node == nil {
 		return nil
 	}
+	c.nodeMutex.Lock()
+	defer c.nodeMutex.Unlock()
 	f := c.nodeMap[node]
 	if f == nil {
 		f := NewFuncType(c.fileMap[c.mustFileOf(node)].Pkg(), node)
@@ -0,0 +1,94 @@
+//go:build go1.18
+// +build go1.18
+
+package inspect
+
+import (
+	"errors"
+	"go/ast"
+	"testing"
+
+	"github.com/xhd2015/go-inspect/inspect"
+	"github.com/xhd2015/go-inspect/inspect/load"
+)
+
+// go test -run TestSyntaxOnly -v ./inspect/test
+func TestSyntaxOnly(t *testing.T) {
+	g, err := load.LoadPackages([]string{"./"}, &load.LoadOptions{
+		ProjectDir: "../testdata/generic",
+		LoadMode:   load.LoadModeSyntax,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := g.GetPkg("github.com/xhd2015/go-inspect/inspect/testdata/generic")
+	if pkg == nil {
+		t.Fatalf("package not found")
+	}
+	if inspect.HasTypes(pkg) || pkg.TypePkg() != nil {
+		t.Fatalf("expect no types")
+	}
+
+	var add inspect.FuncContext
+	pkg.RangeFiles(func(i int, f inspect.FileContext) bool {
+		for _, decl := range f.AST().Decls {
+			if fnDecl, ok := decl.(*ast.FuncDecl); ok && fnDecl.Name.Name == "Add" {
+				add = g.Registry().FuncDecl(fnDecl)
+			}
+		}
+		return true
+	})
+	if add == nil {
+		t.Fatalf("func Add not parsed")
+	}
+
+	// receivers are parsed by syntax
+	ptr, typeName := add.ParseRecvInfo()
+	if !ptr || typeName != "List" {
+		t.Fatalf("expect recv *List, actual: ptr=%v type=%s", ptr, typeName)
+	}
+	if add.QuanlifiedName() != "*List.Add" {
+		t.Fatalf("expect *List.Add, actual: %s", add.QuanlifiedName())
+	}
+
+	_, err = add.Recv().TypeExpr().TryResolveType()
+	if !errors.Is(err, inspect.ErrTypesNotAvailable) {
+		t.Fatalf("expect ErrTypesNotAvailable, actual: %v", err)
+	}
+	func() {
+		defer func() {
+			e, _ := recover().(error)
+			if !errors.Is(e, inspect.ErrTypesNotAvailable) {
+				t.Fatalf("expect panic with ErrTypesNotAvailable, actual: %v", e)
+			}
+		}()
+		add.Recv().TypeExpr().ResolveType()
+	}()
+}
+
+// go test -run TestSyntaxOnlyDeps -v ./inspect/test
+func TestSyntaxOnlyDeps(t *testing.T) {
+	g, err := load.LoadPackages([]string{"./"}, &load.LoadOptions{
+		ProjectDir: "../testdata/hello",
+		LoadMode:   load.LoadModeSyntax,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// dependencies are parsed as well
+	runtime := g.GetPkg("runtime")
+	if runtime == nil {
+		t.Fatalf("package runtime not loaded")
+	}
+	if !runtime.Module().IsStd() {
+		t.Fatalf("expect runtime in std module")
+	}
+	n := 0
+	runtime.RangeFiles(func(i int, f inspect.FileContext) bool {
+		n++
+		return true
+	})
+	if n == 0 {
+		t.Fatalf("expect files of runtime parsed")
+	}
+}
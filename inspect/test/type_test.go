@@ -0,0 +1,79 @@
+//go:build go1.18
+// +build go1.18
+
+package inspect
+
+import (
+	"go/ast"
+	"testing"
+
+	"github.com/xhd2015/go-inspect/inspect"
+	"github.com/xhd2015/go-inspect/inspect/load"
+)
+
+// go test -run TestGenericTypeExpr -v ./inspect/test
+func TestGenericTypeExpr(t *testing.T) {
+	g, err := load.LoadPackages([]string{"./"}, &load.LoadOptions{
+		ProjectDir: "../testdata/generic",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := g.GetPkg("github.com/xhd2015/go-inspect/inspect/testdata/generic")
+	if pkg == nil {
+		t.Fatalf("package not found")
+	}
+	scope := pkg.TypePkg().Scope()
+
+	sum := inspect.NewTypeExpr(scope.Lookup("Sum").Type())
+	if len(sum.TypeParams) != 1 {
+		t.Fatalf("expect Sum has 1 type param, actual: %d", len(sum.TypeParams))
+	}
+	param := sum.TypeParams[0]
+	if param.Kind != inspect.TypeParam || param.Name != "T" {
+		t.Fatalf("expect type param T, actual: kind=%v name=%s", param.Kind, param.Name)
+	}
+	// the constraint is the named interface Number
+	underlying := inspect.NewTypeExpr(scope.Lookup("Number").Type().Underlying())
+	if underlying.Kind != inspect.Interface || len(underlying.Embeddeds) != 1 {
+		t.Fatalf("expect Number embeds a type set, actual: %+v", underlying)
+	}
+	union := underlying.Embeddeds[0]
+	if union.Kind != inspect.Union || union.String() != "~int|~int64|float64" {
+		t.Fatalf("expect union ~int|~int64|float64, actual: %s", union.String())
+	}
+	if sum.Args[0].Type.String() != "*generic.List[T]" {
+		t.Fatalf("expect arg *generic.List[T], actual: %s", sum.Args[0].Type.String())
+	}
+
+	ints := inspect.NewTypeExpr(scope.Lookup("Ints").Type())
+	if ints.String() != "*generic.List[int]" {
+		t.Fatalf("expect *generic.List[int], actual: %s", ints.String())
+	}
+	if ints.Elem.TypeArgs[0].Kind != inspect.Basic {
+		t.Fatalf("expect basic type arg, actual: %v", ints.Elem.TypeArgs[0].Kind)
+	}
+
+	ns := inspect.NewTypeExpr(scope.Lookup("NumberStringer").Type().Underlying())
+	if len(ns.Embeddeds) != 2 || len(ns.Methods) != 1 {
+		t.Fatalf("expect 2 embeddeds and 1 method, actual: %d, %d", len(ns.Embeddeds), len(ns.Methods))
+	}
+
+	var typeParams []string
+	pkg.RangeFiles(func(i int, f inspect.FileContext) bool {
+		for _, decl := range f.AST().Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			g.Registry().FuncDecl(fn).TypeParams().RangeVars(func(i int, v inspect.FieldVar) bool {
+				typeParams = append(typeParams, fn.Name.Name+":"+v.Name())
+				return true
+			})
+		}
+		return true
+	})
+	if len(typeParams) != 1 || typeParams[0] != "Sum:T" {
+		t.Fatalf("expect type params [Sum:T], actual: %v", typeParams)
+	}
+}
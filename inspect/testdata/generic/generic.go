@@ -0,0 +1,32 @@
+package generic
+
+type Number interface {
+	~int | ~int64 | float64
+}
+
+type Stringer interface {
+	String() string
+}
+
+type NumberStringer interface {
+	Number
+	Stringer
+}
+
+type List[T any] struct {
+	items []T
+}
+
+func (c *List[T]) Add(item T) {
+	c.items = append(c.items, item)
+}
+
+func Sum[T Number](list *List[T]) T {
+	var sum T
+	for _, item := range list.items {
+		sum += item
+	}
+	return sum
+}
+
+var Ints = &List[int]{}
@@ -0,0 +1,3 @@
+module github.com/xhd2015/go-inspect/inspect/testdata/generic
+
+go 1.18
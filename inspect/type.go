@@ -22,6 +22,8 @@ const (
 	Array     Kind = 8
 	Map       Kind = 9
 	Chan      Kind = 10
+	TypeParam Kind = 11
+	Union     Kind = 12 // type set of a constraint, like ~int | string
 )
 
 // TypeExpr represents Type appeared inside a package.
@@ -37,12 +39,28 @@ type TypeExpr struct {
 	Fields  []*StructFieldExpr
 	Methods []*Arg // interface type
 
+	// interface: embedded interfaces and type sets,
+	// a type set is either a Union or a single type
+	Embeddeds []*TypeExpr
+
 	// func or method
 	Recv    *Arg
 	Args    []*Arg
 	Results []*Arg
 
-	// named type
+	// type parameters of generic func or named type,
+	// each has Kind TypeParam
+	TypeParams []*TypeExpr
+	// type arguments of instantiated named type
+	TypeArgs []*TypeExpr
+
+	// type parameter
+	Constraint *TypeExpr
+
+	// union
+	Terms []*TypeTerm
+
+	// named type or type parameter
 	PkgPath      string // valid when named type
 	ShortPkgPath string
 	Name         string
@@ -61,28 +79,42 @@ type Arg struct {
 	Type *TypeExpr
 }
 
+// TypeTerm is a term of Union, Tilde is true for ~T
+type TypeTerm struct {
+	Tilde bool
+	Type  *TypeExpr
+}
+
 func NewTypeExpr(t types.Type) *TypeExpr {
 	return buildTypeExpr(t, make(map[types.Type]*TypeExpr))
 }
 
 func buildTypeExpr(t types.Type, m map[types.Type]*TypeExpr) *TypeExpr {
+	t = unalias(t)
 	if m[t] != nil {
 		return m[t]
 	}
 
 	exp := &TypeExpr{}
-	var kind Kind
+	// types may refer to themselves, i.e. methods of interfaces
+	// and constraints of type parameters
+	m[t] = exp
 	switch t := t.(type) {
 	case *types.Basic:
-		kind = Basic
+		exp.Kind = Basic
 		exp.Name = t.Name()
 	case *types.Named:
-		kind = Named
-		exp.PkgPath = t.Obj().Pkg().Path()
+		exp.Kind = Named
 		exp.Name = t.Obj().Name()
-		exp.ShortPkgPath = t.Obj().Pkg().Name()
+		// error has no package
+		if t.Obj().Pkg() != nil {
+			exp.PkgPath = t.Obj().Pkg().Path()
+			exp.ShortPkgPath = t.Obj().Pkg().Name()
+		}
 		exp.Expr = t.String()
+		exp.TypeParams, exp.TypeArgs = namedTypeParamsAndArgs(t, m)
 	case *types.Struct:
+		exp.Kind = Struct
 		fields := make([]*StructFieldExpr, 0, t.NumFields())
 		for i := 0; i < t.NumFields(); i++ {
 			f := t.Field(i)
@@ -95,8 +127,8 @@ func buildTypeExpr(t types.Type, m map[types.Type]*TypeExpr) *TypeExpr {
 		}
 		exp.Fields = fields
 	case *types.Interface:
-		kind = Interface
-		// TODO: embedded types
+		exp.Kind = Interface
+		// methods include those of embedded interfaces
 		methods := make([]*Arg, 0, t.NumMethods())
 		for i := 0; i < t.NumMethods(); i++ {
 			fn := t.Method(i)
@@ -106,34 +138,44 @@ func buildTypeExpr(t types.Type, m map[types.Type]*TypeExpr) *TypeExpr {
 			})
 		}
 		exp.Methods = methods
+		if t.NumEmbeddeds() > 0 {
+			embeddeds := make([]*TypeExpr, 0, t.NumEmbeddeds())
+			for i := 0; i < t.NumEmbeddeds(); i++ {
+				embeddeds = append(embeddeds, buildTypeExpr(t.EmbeddedType(i), m))
+			}
+			exp.Embeddeds = embeddeds
+		}
 	case *types.Pointer:
-		kind = Ptr
+		exp.Kind = Ptr
 		exp.Elem = buildTypeExpr(t.Elem(), m)
 	case *types.Signature:
-		kind = Func
+		exp.Kind = Func
+		exp.TypeParams = signatureTypeParams(t, m)
 		if t.Recv() != nil {
 			exp.Recv = parseArg(t.Recv(), m)
 		}
 		exp.Args = parseArgs(t.Params(), m)
 		exp.Results = parseArgs(t.Results(), m)
 	case *types.Array:
-		kind = Array
+		exp.Kind = Array
 		exp.Len = int(t.Len())
 		exp.Elem = buildTypeExpr(t.Elem(), m)
 	case *types.Slice:
-		kind = Slice
+		exp.Kind = Slice
 		exp.Elem = buildTypeExpr(t.Elem(), m)
 	case *types.Map:
-		kind = Map
+		exp.Kind = Map
 		exp.Key = buildTypeExpr(t.Key(), m)
 		exp.Elem = buildTypeExpr(t.Elem(), m)
 	case *types.Chan:
-		kind = Chan
+		exp.Kind = Chan
 		exp.Elem = buildTypeExpr(t.Elem(), m)
 	default:
-		panic(fmt.Errorf("unrecognized type:%T", t))
+		// type parameters and unions
+		if !buildGenericTypeExpr(t, exp, m) {
+			panic(fmt.Errorf("unrecognized type:%T", t))
+		}
 	}
-	exp.Kind = kind
 	return exp
 }
 func parseArgs(args *types.Tuple, m map[types.Type]*TypeExpr) []*Arg {
@@ -169,9 +211,27 @@ func (c *TypeExpr) traverseNoRepeat(fn func(subType *TypeExpr), seen map[*TypeEx
 		for _, field := range c.Fields {
 			field.Type.traverseNoRepeat(fn, seen)
 		}
+	case Named:
+		for _, arg := range c.TypeArgs {
+			arg.traverseNoRepeat(fn, seen)
+		}
 	case Interface:
-
+		for _, method := range c.Methods {
+			method.Type.traverseNoRepeat(fn, seen)
+		}
+		for _, embedded := range c.Embeddeds {
+			embedded.traverseNoRepeat(fn, seen)
+		}
+	case TypeParam:
+		c.Constraint.traverseNoRepeat(fn, seen)
+	case Union:
+		for _, term := range c.Terms {
+			term.Type.traverseNoRepeat(fn, seen)
+		}
 	case Func:
+		for _, param := range c.TypeParams {
+			param.traverseNoRepeat(fn, seen)
+		}
 		if c.Recv != nil {
 			c.Recv.Type.traverseNoRepeat(fn, seen)
 		}
@@ -222,6 +282,9 @@ func (c *TypeExpr) IsPtr() bool {
 }
 func (c *TypeExpr) String() string {
 	if c.Name != "" {
+		if len(c.TypeArgs) > 0 {
+			return c.shortRef() + "[" + joinTypeExprs(c.TypeArgs, ",") + "]"
+		}
 		return c.shortRef()
 	}
 	switch c.Kind {
@@ -249,12 +312,39 @@ func (c *TypeExpr) String() string {
 			right = ")"
 		}
 		return fmt.Sprintf("func(%s) %s%s%s", strings.Join(args, ","), left, strings.Join(results, ","), right)
+	case Interface:
+		elems := make([]string, 0, len(c.Embeddeds)+len(c.Methods))
+		for _, embedded := range c.Embeddeds {
+			elems = append(elems, embedded.String())
+		}
+		for _, method := range c.Methods {
+			elems = append(elems, method.Name+strings.TrimPrefix(method.Type.String(), "func"))
+		}
+		return "interface{" + strings.Join(elems, ";") + "}"
+	case Union:
+		terms := make([]string, 0, len(c.Terms))
+		for _, term := range c.Terms {
+			tilde := ""
+			if term.Tilde {
+				tilde = "~"
+			}
+			terms = append(terms, tilde+term.Type.String())
+		}
+		return strings.Join(terms, "|")
 	// case reflect.Chan: // TODO
 	default:
 		panic(fmt.Errorf("unhandled kind:%v", c.Kind))
 	}
 }
 
+func joinTypeExprs(list []*TypeExpr, sep string) string {
+	strs := make([]string, 0, len(list))
+	for _, t := range list {
+		strs = append(strs, t.String())
+	}
+	return strings.Join(strs, sep)
+}
+
 func TraverseTypes(t []types.Type, fn func(t types.Type) bool) {
 	m := make(map[types.Type]bool, len(t))
 	for _, x := range t {
@@ -281,6 +371,12 @@ func RefInvisible(rtype types.Type) bool {
 			// if n.Obj().IsAlias()
 			foundInvisible = true
 		}
+		// type arguments are referenced along with the name
+		for _, arg := range namedTypeArgs(n) {
+			if RefInvisible(arg) {
+				foundInvisible = true
+			}
+		}
 
 		// since it is named, so a name stop's traversing its underlying.
 		return false
@@ -291,6 +387,7 @@ func traverseType(t types.Type, fn func(t types.Type) bool, m map[types.Type]boo
 	if t == nil {
 		return
 	}
+	t = unalias(t)
 	if m[t] {
 		return
 	}
@@ -304,15 +401,20 @@ func traverseType(t types.Type, fn func(t types.Type) bool, m map[types.Type]boo
 	case *types.Named:
 		// underlying?
 		traverseType(t.Underlying(), fn, m)
+		for _, arg := range namedTypeArgs(t) {
+			traverseType(arg, fn, m)
+		}
 	case *types.Struct:
 		for i := 0; i < t.NumFields(); i++ {
 			traverseType(t.Field(i).Type(), fn, m)
 		}
 	case *types.Interface:
-		// TODO: embedded types
 		for i := 0; i < t.NumMethods(); i++ {
 			traverseType(t.Method(i).Type(), fn, m)
 		}
+		for i := 0; i < t.NumEmbeddeds(); i++ {
+			traverseType(t.EmbeddedType(i), fn, m)
+		}
 	case *types.Pointer:
 		traverseType(t.Elem(), fn, m)
 	case *types.Signature:
@@ -335,7 +437,10 @@ func traverseType(t types.Type, fn func(t types.Type) bool, m map[types.Type]boo
 	case *types.Chan:
 		traverseType(t.Elem(), fn, m)
 	default:
-		panic(fmt.Errorf("unrecognized type:%T", t))
+		// type parameters and unions
+		if !traverseGenericType(t, fn, m) {
+			panic(fmt.Errorf("unrecognized type:%T", t))
+		}
 	}
 }
 
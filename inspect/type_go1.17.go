@@ -0,0 +1,39 @@
+//go:build !go1.18
+// +build !go1.18
+
+package inspect
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// before go1.18 there is no type parameter
+
+func buildGenericTypeExpr(t types.Type, exp *TypeExpr, m map[types.Type]*TypeExpr) bool {
+	return false
+}
+
+func namedTypeParamsAndArgs(t *types.Named, m map[types.Type]*TypeExpr) (params []*TypeExpr, args []*TypeExpr) {
+	return nil, nil
+}
+
+func signatureTypeParams(t *types.Signature, m map[types.Type]*TypeExpr) []*TypeExpr {
+	return nil
+}
+
+func namedTypeArgs(t *types.Named) []types.Type {
+	return nil
+}
+
+func traverseGenericType(t types.Type, fn func(t types.Type) bool, m map[types.Type]bool) bool {
+	return false
+}
+
+func typeParamsOf(node *ast.FuncType) *ast.FieldList {
+	return nil
+}
+
+func indexListX(expr ast.Expr) ast.Expr {
+	return nil
+}
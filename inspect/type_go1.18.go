@@ -0,0 +1,100 @@
+//go:build go1.18
+// +build go1.18
+
+package inspect
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// buildGenericTypeExpr fills `exp` if `t` is a type parameter or union
+func buildGenericTypeExpr(t types.Type, exp *TypeExpr, m map[types.Type]*TypeExpr) bool {
+	switch t := t.(type) {
+	case *types.TypeParam:
+		exp.Kind = TypeParam
+		exp.Name = t.Obj().Name()
+		exp.Expr = t.String()
+		exp.Constraint = buildTypeExpr(t.Constraint(), m)
+	case *types.Union:
+		exp.Kind = Union
+		terms := make([]*TypeTerm, 0, t.Len())
+		for i := 0; i < t.Len(); i++ {
+			term := t.Term(i)
+			terms = append(terms, &TypeTerm{
+				Tilde: term.Tilde(),
+				Type:  buildTypeExpr(term.Type(), m),
+			})
+		}
+		exp.Terms = terms
+	default:
+		return false
+	}
+	return true
+}
+
+func namedTypeParamsAndArgs(t *types.Named, m map[types.Type]*TypeExpr) (params []*TypeExpr, args []*TypeExpr) {
+	params = buildTypeParams(t.TypeParams(), m)
+	for _, arg := range namedTypeArgs(t) {
+		args = append(args, buildTypeExpr(arg, m))
+	}
+	return
+}
+
+func signatureTypeParams(t *types.Signature, m map[types.Type]*TypeExpr) []*TypeExpr {
+	// methods of generic types have receiver type parameters instead
+	if t.RecvTypeParams().Len() > 0 {
+		return buildTypeParams(t.RecvTypeParams(), m)
+	}
+	return buildTypeParams(t.TypeParams(), m)
+}
+
+func buildTypeParams(list *types.TypeParamList, m map[types.Type]*TypeExpr) []*TypeExpr {
+	if list.Len() == 0 {
+		return nil
+	}
+	params := make([]*TypeExpr, 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		params = append(params, buildTypeExpr(list.At(i), m))
+	}
+	return params
+}
+
+func namedTypeArgs(t *types.Named) []types.Type {
+	args := t.TypeArgs()
+	if args.Len() == 0 {
+		return nil
+	}
+	list := make([]types.Type, 0, args.Len())
+	for i := 0; i < args.Len(); i++ {
+		list = append(list, args.At(i))
+	}
+	return list
+}
+
+// traverseGenericType traverses `t` if it is a type parameter or union
+func traverseGenericType(t types.Type, fn func(t types.Type) bool, m map[types.Type]bool) bool {
+	switch t := t.(type) {
+	case *types.TypeParam:
+		traverseType(t.Constraint(), fn, m)
+	case *types.Union:
+		for i := 0; i < t.Len(); i++ {
+			traverseType(t.Term(i).Type(), fn, m)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func typeParamsOf(node *ast.FuncType) *ast.FieldList {
+	return node.TypeParams
+}
+
+// indexListX returns X of `expr` if it is an *ast.IndexListExpr, like T[K, V]
+func indexListX(expr ast.Expr) ast.Expr {
+	if e, ok := expr.(*ast.IndexListExpr); ok {
+		return e.X
+	}
+	return nil
+}
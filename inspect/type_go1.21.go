@@ -0,0 +1,10 @@
+//go:build !go1.22
+// +build !go1.22
+
+package inspect
+
+import "go/types"
+
+func unalias(t types.Type) types.Type {
+	return t
+}
@@ -0,0 +1,12 @@
+//go:build go1.22
+// +build go1.22
+
+package inspect
+
+import "go/types"
+
+// unalias returns the actual type of an alias like any,
+// which is represented by *types.Alias since go1.22
+func unalias(t types.Type) types.Type {
+	return types.Unalias(t)
+}
@@ -2,6 +2,7 @@ package util
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/xhd2015/go-inspect/sh"
 )
@@ -78,3 +79,49 @@ func GoListModule(dir string, modulePath string) (*ListModule, error) {
 	}
 	return listMod, nil
 }
+
+type GoWorkUse struct {
+	DiskPath   string
+	ModulePath string
+}
+
+type GoWork struct {
+	Go        string
+	Toolchain string
+	Use       []*GoWorkUse
+	Replace   []Replace
+}
+
+// GetGoWorkFile returns the go.work file used in `dir`,
+// empty if not in workspace mode
+func GetGoWorkFile(dir string) (string, error) {
+	var cmds []string
+	if dir != "" {
+		cmds = append(cmds, fmt.Sprintf("cd %s", sh.Quote(dir)))
+	}
+	cmds = append(cmds, "go env GOWORK")
+	stdout, _, err := sh.RunBashWithOpts(cmds, sh.RunBashOptions{
+		NeedStdOut: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	goWork := strings.TrimSpace(stdout)
+	if goWork == "off" {
+		return "", nil
+	}
+	return goWork, nil
+}
+
+func GetGoWork(goWorkFile string) (*GoWork, error) {
+	gowork := &GoWork{}
+	_, _, err := sh.RunBashWithOpts([]string{
+		fmt.Sprintf("go work edit -json %s", sh.Quote(goWorkFile)),
+	}, sh.RunBashOptions{
+		StdoutToJSON: gowork,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return gowork, nil
+}
@@ -0,0 +1,234 @@
+// Package mock is the runtime of functions rewritten by plugin/mock.
+// Mocks are set up per package into a context by generated Setup
+// functions, and found by rewritten functions from their first
+// context.Context argument.
+package mock
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// StubInfo describes a rewritten function, it is
+// generated once per function by the rewriter.
+type StubInfo struct {
+	PkgName string // full package path
+
+	// Owner is the receiver type name of a
+	// method, empty for functions
+	Owner    string
+	OwnerPtr bool
+
+	Name string
+}
+
+func (c *StubInfo) String() string {
+	if c.Owner == "" {
+		return c.PkgName + "." + c.Name
+	}
+	if c.OwnerPtr {
+		return fmt.Sprintf("%s.(*%s).%s", c.PkgName, c.Owner, c.Name)
+	}
+	return c.PkgName + "." + c.Owner + "." + c.Name
+}
+
+// Filter tells interceptors how the call will be handled by next
+type Filter interface {
+	// HasMock reports whether a mock set up in ctx
+	// will be called instead of the original function
+	HasMock() bool
+}
+
+// Interceptor is called around every rewritten function.
+// `inst` is the receiver, nil for functions.
+// `req` holds pointers to the arguments, and `resp` holds
+// pointers to the results, both are []interface{}.
+// An interceptor must call next to proceed. The ctx passed
+// to next replaces the context.Context argument, if any.
+// A non-nil error is set to the last result if it is an error,
+// otherwise it panics.
+type Interceptor func(ctx context.Context, stubInfo *StubInfo, inst, req, resp interface{}, f Filter, next func(ctx context.Context) error) error
+
+var (
+	interceptorsMutex sync.Mutex
+	interceptors      atomic.Value // []Interceptor
+)
+
+// AddInterceptor adds a general interceptor, which is called
+// after interceptors added before.
+func AddInterceptor(interceptor Interceptor) {
+	interceptorsMutex.Lock()
+	defer interceptorsMutex.Unlock()
+	prev, _ := interceptors.Load().([]Interceptor)
+	list := make([]Interceptor, len(prev), len(prev)+1)
+	copy(list, prev)
+	interceptors.Store(append(list, interceptor))
+}
+
+type setupKey struct {
+	pkg string
+}
+
+// WithMockSetup associates mock `m` of package `pkg` into ctx,
+// `m` is the M struct generated for that package.
+// A later setup of the same package replaces the previous one.
+func WithMockSetup(ctx context.Context, pkg string, m interface{}) context.Context {
+	return context.WithValue(ctx, setupKey{pkg: pkg}, m)
+}
+
+// getMock returns the func field of `stub` in the setup, invalid if not set
+func getMock(ctx context.Context, stub *StubInfo) reflect.Value {
+	if ctx == nil {
+		return reflect.Value{}
+	}
+	m := ctx.Value(setupKey{pkg: stub.PkgName})
+	if m == nil {
+		return reflect.Value{}
+	}
+	v := reflect.ValueOf(m)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if stub.Owner != "" {
+		v = v.FieldByName(stub.Owner)
+		if !v.IsValid() || v.Kind() != reflect.Struct {
+			return reflect.Value{}
+		}
+	}
+	fn := v.FieldByName(stub.Name)
+	if !fn.IsValid() || fn.Kind() != reflect.Func || fn.IsNil() {
+		return reflect.Value{}
+	}
+	return fn
+}
+
+type filter bool
+
+func (c filter) HasMock() bool {
+	return bool(c)
+}
+
+var errType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Call is called at the entry of every rewritten function, `args` and
+// `results` are pointers to the arguments and results. The mock set up in
+// the first context.Context argument is called if there is any,
+// otherwise `original` is.
+func Call(stub *StubInfo, inst interface{}, args []interface{}, results []interface{}, original func()) {
+	var ctxArg *context.Context
+	if len(args) > 0 {
+		ctxArg, _ = args[0].(*context.Context)
+	}
+	var ctx context.Context
+	if ctxArg != nil {
+		ctx = *ctxArg
+	}
+	list, _ := interceptors.Load().([]Interceptor)
+	if len(list) == 0 {
+		// fast path
+		fn := getMock(ctx, stub)
+		if !fn.IsValid() {
+			original()
+			return
+		}
+		callMock(fn, stub, inst, args, results)
+		return
+	}
+
+	var next func(i int, ctx context.Context) error
+	next = func(i int, ctx context.Context) error {
+		if ctxArg != nil {
+			*ctxArg = ctx
+		}
+		fn := getMock(ctx, stub)
+		if i < len(list) {
+			return list[i](ctx, stub, inst, args, results, filter(fn.IsValid()), func(ctx context.Context) error {
+				return next(i+1, ctx)
+			})
+		}
+		if !fn.IsValid() {
+			original()
+			return nil
+		}
+		callMock(fn, stub, inst, args, results)
+		return nil
+	}
+	err := next(0, ctx)
+	if err == nil {
+		return
+	}
+	if len(results) > 0 {
+		last := reflect.ValueOf(results[len(results)-1]).Elem()
+		if last.Type() == errType {
+			last.Set(reflect.ValueOf(err))
+			return
+		}
+	}
+	panic(err)
+}
+
+func callMock(fn reflect.Value, stub *StubInfo, inst interface{}, args []interface{}, results []interface{}) {
+	fnType := fn.Type()
+	in := make([]reflect.Value, 0, len(args)+1)
+	if stub.Owner != "" {
+		in = append(in, argValue(reflect.ValueOf(inst), fnType.In(0)))
+	}
+	for _, arg := range args {
+		in = append(in, argValue(reflect.ValueOf(arg).Elem(), fnType.In(len(in))))
+	}
+	var out []reflect.Value
+	if fnType.IsVariadic() {
+		out = fn.CallSlice(in)
+	} else {
+		out = fn.Call(in)
+	}
+	for i, res := range results {
+		setResult(reflect.ValueOf(res).Elem(), out[i])
+	}
+}
+
+// argValue converts `v` to `t`, which is interface{}
+// for arguments involving type parameters
+func argValue(v reflect.Value, t reflect.Type) reflect.Value {
+	if !v.IsValid() {
+		return reflect.Zero(t)
+	}
+	if v.Type().AssignableTo(t) {
+		return v
+	}
+	if v.Kind() == reflect.Interface && v.IsNil() {
+		return reflect.Zero(t)
+	}
+	if v.Kind() == reflect.Slice && t.Kind() == reflect.Slice {
+		// variadic ...T passed as ...interface{}
+		if v.IsNil() {
+			return reflect.Zero(t)
+		}
+		s := reflect.MakeSlice(t, v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			s.Index(i).Set(argValue(v.Index(i), t.Elem()))
+		}
+		return s
+	}
+	return reflect.ValueOf(v.Interface())
+}
+
+// setResult sets `dst` to `v`, which may be
+// interface{} for results involving type parameters
+func setResult(dst reflect.Value, v reflect.Value) {
+	if v.Type().AssignableTo(dst.Type()) {
+		dst.Set(v)
+		return
+	}
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return
+		}
+		v = v.Elem()
+	}
+	dst.Set(v)
+}
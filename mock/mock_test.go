@@ -0,0 +1,100 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testM struct {
+	Run    func(ctx context.Context, n int) (int, error)
+	Status struct {
+		Sum func(c interface{}, ctx context.Context, nums ...interface{}) interface{}
+	}
+}
+
+var runStub = &StubInfo{PkgName: "test", Name: "Run"}
+var sumStub = &StubInfo{PkgName: "test", Owner: "Status", Name: "Sum"}
+
+// run is what the rewriter makes of `func run(ctx context.Context, n int) (int, error)`
+func run(ctx context.Context, n int) (r0 int, r1 error) {
+	Call(runStub, nil, []interface{}{&ctx, &n}, []interface{}{&r0, &r1}, func() {
+		r0, r1 = func() (int, error) { return n, nil }()
+	})
+	return
+}
+
+func sum(inst interface{}, ctx context.Context, nums ...int) (r0 int) {
+	Call(sumStub, inst, []interface{}{&ctx, &nums}, []interface{}{&r0}, func() {
+		r0 = func() int { return len(nums) }()
+	})
+	return
+}
+
+// go test -run TestCall -v ./mock
+func TestCall(t *testing.T) {
+	ctx := context.Background()
+	if n, _ := run(ctx, 1); n != 1 {
+		t.Fatalf("expect original 1, actual: %d", n)
+	}
+
+	m := testM{}
+	m.Run = func(ctx context.Context, n int) (int, error) {
+		return n * 10, nil
+	}
+	m.Status.Sum = func(c interface{}, ctx context.Context, nums ...interface{}) interface{} {
+		total := 0
+		for _, n := range nums {
+			total += n.(int)
+		}
+		return total
+	}
+	mockCtx := WithMockSetup(ctx, "test", m)
+	if n, _ := run(mockCtx, 2); n != 20 {
+		t.Fatalf("expect mock 20, actual: %d", n)
+	}
+	if n := sum(nil, mockCtx, 1, 2, 3); n != 6 {
+		t.Fatalf("expect mock 6, actual: %d", n)
+	}
+	if n := sum(nil, ctx, 1, 2, 3); n != 3 {
+		t.Fatalf("expect original 3, actual: %d", n)
+	}
+}
+
+// go test -run TestInterceptor -v ./mock
+func TestInterceptor(t *testing.T) {
+	prev, _ := interceptors.Load().([]Interceptor)
+	defer interceptors.Store(prev)
+
+	var calls []string
+	errFail := errors.New("fail")
+	AddInterceptor(func(ctx context.Context, stubInfo *StubInfo, inst, req, resp interface{}, f Filter, next func(ctx context.Context) error) error {
+		calls = append(calls, stubInfo.String())
+		n := *req.([]interface{})[1].(*int)
+		if n < 0 {
+			return errFail
+		}
+		if f.HasMock() {
+			calls = append(calls, "mock")
+		}
+		return next(ctx)
+	})
+	if n, err := run(context.Background(), 3); n != 3 || err != nil {
+		t.Fatalf("expect original 3, actual: %d %v", n, err)
+	}
+	// error is set to the last result
+	if _, err := run(context.Background(), -1); err != errFail {
+		t.Fatalf("expect %v, actual: %v", errFail, err)
+	}
+	ctx := WithMockSetup(context.Background(), "test", &testM{Run: func(ctx context.Context, n int) (int, error) {
+		return 0, nil
+	}})
+	if n, _ := run(ctx, 4); n != 0 {
+		t.Fatalf("expect mock 0, actual: %d", n)
+	}
+	expect := "test.Run,test.Run,test.Run,mock"
+	if actual := strings.Join(calls, ","); actual != expect {
+		t.Fatalf("expect calls %s, actual: %s", expect, actual)
+	}
+}
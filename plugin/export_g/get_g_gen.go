@@ -1,4 +1,4 @@
 // Code generated by github.com/xhd2015/go-vendor-pack/cmd/go-pack. DO NOT EDIT.
 package export_g
 
-var GETG_PACK = "H4sIAAAAAAAA/+xaXW/bNhTNa/grbp2H2oCrL8sxMKAbCjTI8tClaIq9DENMS1cUF4pUSSpOMPS/D5QUt4ibOmpntd10HiI74uUldXwOKZJMeYVKD/aKIAiC4ziur4vjRX0NouZ7/XFxvPkcBMFBEEZxGB1AsN9mNaiMpfogCG7yNArC+YPljKVZ9pl6No1vrz8ICpVWAoFxm1crL1GF3z4In6lnXJoSE+uXomJc+nhTKm0vmV/S5IoQpiD0wpgQje8qrh9ZCUPL4DrwAi9ykaWgSZfI5z+D5/meV38j3/rp/fho9O9dozZcSbOXHLv0H8bxPf3H0Xw26L8PdBc+kK46/9Z9HPAwmPJMVew3xy79fySbzfgfBYP++4BTtMfUXnPs9P9wi//5LB747wOOf8oQmikdL5zRw5gcXsLo0TY/IpNhJvaD4hplqvR+c9S6n8+7+P88nB/Aw2L8F/E/13/Dv/9B63vI0Z3/eD6PB/77wBb//q4H0R3d+Z8fx4uB/z7wIP8fjfVfm6M7/4tFOPDfCx7DfzvX++IcnfmPwmgY//tBB/7ruf6X5OjOfxTGs4H/PtCVf//NyYuXr068osOeUfD59/8wCqJ7/M/ixfD+3wuOoN5IISf1Ei/YHGGpK2l5gZ67M54sYZ3zJAduoJLNSjCmsLqFFDNaCesR8jbnBgp6hQaY0qqyXCIIlVABxipNGQJSw1GDVcCLUmCB0k5B8CsELsGisVwyQHnNtZLupkfISyWfWqgMgnX1cwmlVmmVWK6kR8gR/KrWoHGtuUVQGRhLZUp1CoKvYK30lfmFkAtE+KPU6i/3O24LXxqbXrqcHlN/jj9zcwKZ0oA31DV5uqKGJ1SI258IeQY0s6hBKJq6llOZwouLt9Aqhks2hRVmSiMkqrx1RTIu0EzBoIVlm+vCpkv3SKyukDxzPVyeojy/Ri3o7RLKnBqcAkMJeGM1rasAaniKLmp5ev7m/Pytb3Tit5QtoV3PaTmxmOSSv6sQUoUGpLIgEdOahqalNeNt9FPj+jAFo2DtItzjT3IqGTa/i4tcVSL9nRtuXzdplqBKR4eZuvos0nTqQhlK1NQ2YXWjHcmuiq3uecPK0TdFZ/9HrS8Lw7qsGe/w/2A+vz/+x1GwGPy/D9yt/zajQLv+OxKKjQjxfVjnKJ0Fp857S9TiFtbc5nC3IzhtvLnW+JoLASsEjYW6xhSovTMWz1VVajQok9qqPwR9KAQFUumM8s7SN5blvJyQrJIJcMnteAJ/k0OhmPdac2mz8egULXPj06uzi4uz306fwFkGt6oCg+3YUaAxlOEUuK3TmLq/LmSFLmXdQ9cxVdluZyGmUAqkBiHJMblyWfWmA4mSGWfeaELef7cu11n/7k/HDaNd+p+Fn5j/Dfv/veCT+h+Tw1ElDc1wRCaEXFMNp2jPilKAU+F4As1d77Xi0qJuxXki6UpgOp7ASinhRKrRVlpugp88B8kFed+WP92qyMXwbFP+eV3e/fOuJhd++P5+xePvWWHfN7rrv/uBwV36DxZb+p9Fw/jfC7qc/2s8ojn2Nxv09p/Al+i/64GhnfrfWv+bzeJh/O8FLf+NDRjP3nz1bs82dvEfHYf39/+CYFj/6wVHnU9t3zuCfXTkXgYFT7h9/MFQ0iXtcOR7j/gHAAD//+xbXW+CMBT9K8t5rqZF/Lqv22J80Bnj9rLwUOnVsaEsgtkS0/++FEh07mFT9pElnBdoAuecFm4h7b3LpBlHadZ8TJPzN/g+gTzY/zkMkfKo/NbR/59Sfue36z+q8vzT+N9hosOnWbTi29klCJ70/IZUDSUvZJ9kh/wuBK6iJacZCD1pQm36usWmy3PVkwtv3mWj221PhX3uQWCQjBID2mGUf1Pc2URnD6CvJRS+yzV30vy84VBnbECAdfwgqKbyITAtKk9A96eJ5FmLAndF1QMIxdQGgeHaRBsOM9BCxynbQOD6NYy3hkHrbRw7zbxmJde8ic2p/fsg7fo05pcDnv1sd3ShczPlbKOdP+fGinKU028cgnJtv6Ac5isCZxCP9YrdLa5lAyuqvQWDZG+0fPYVbR4rlH7zlg1sYP86MmvUqFHjZ/EWAAD//86MruQAPAAA"
+var GETG_PACK = "H4sIAAAAAAAA/+xabW/jNvL32/BTTJMXtfH30pJsx0CB/A8FGqQBupfFZq/3YrGIaWks8UKTKknlAYf97oehJDub9C5x72IHhZgAeuAMh5wff8Mxxdzwlcl6r1qiKIqOjye9qC6Pr1EyO17fh/o4icdJD6K2gdcslfPC9qLIGuPbd79Xnqtfd765tu/feFmZrFIIufRFteCpWY3uiiyJ4ukoN++kdiWmflSqKpd6hHelsf4qH5UivWYsNxDzeMKYxd8qaV/YSI4+h5uIR3xMmqUS6TaaJ/8PnI84D0+sHUVX/mip+c9v0DpptGtf75T/8WRdR/+9KB7PZuOO/7vg//bEB/ZyttY8b2115e2V3HBXrdqn3l74/+Q+TuJJ1PF/F/wnRvPctI97wT+On+CfTCcd/rvCX+QIdGVMrijDgz47uILDF4f5QzboMrH/OhPbz98N6szY9ul1CvFhNp0+oUhzfXofJ8l43INp20DH/1fjf43/aMP1tmKv+E+SWdLhvxf821jfCuwF/+n4eNrhv1f8H6z1rezu8J9Nxh3+bwb/JtdrVV4f/ySajccd/m8M/5Drt3qvi398fBx3+L9F/EcfT3/86f0pX23xzYj8cTyZPHFRc42nyewR/uMk6X7/7+T3/xEQqoydhi1e8AXC3FbayxVyqukP5nBbyLQA6aDS9ScgzGBxDxkuRaU8Z+xTIR2sxDU6yI01lZcaQZlUKHDeWNpfQOEkWvAG5KpUuELth6DkNYLU4NF5qXNAfSOt0VTJGfvJ6O89VA7BU/tSQ2lNVqVeGs0ZO4KfzS1YvLXSI5glOC90JmwGSi7g1thr9xfGLhHhc2nNP2gda4SvnM+uyCbPzZf+f6gcwNJYwDtBXR4uhJOpUOr+B8begVh6tKCMyKjnQmfw4+UnaBgjdT6EBS6NRUhNeU8iS6nQDcGhh3lj69Jnc3KJtxWyd+SJ+Rnqixu0StzPoSyEwyHkqAHvvBWhCRBOZkha87OLjxcXn0bOpqMGsjk0+zkNJh7TQsvfKoTMoANtPGjEjLSbngbEG+3vHY1hCM7ALWmQ+9NC6JwQQJhfFqZS2a/SSf+hNjMHUxIcbkgj9yiyIanmqNEKX6uFThPIBf7O8AKQZ+tJg3fSQ2HMNWN/l76A9svD8MGsNCQUz2lCCuVMMwU8ahoWAQRzmrn8Y6VP76T/2ZhrR9O4wYP6tJmlS6mlKwIwhiaOFVaiq2fm51y5L3361qjcAFKhwWIuHeGeKhS6Kh3cSNGYuwjW+ks9mHPW+9PGf7T2auXybfaMn4n/0XQaPY7/s2Ta7f/ucv+XsF3v/x4qkx8yNhrBbYGaQnBGsbdEq+7h9ltehtgcOH4rlYIFgsWVucEMhIeWs9RUadGhTkOo3ihthGCFQlOgbILjJmRRLGdsWekUpJa+P4B/sgNlcv7BSu2X/cMz+tYoHbw/v7w8/+vZd3C+hHtTgcNm7VihcyLHIUgfzLiw6lEEWSCZDCOkgZnKv+wwwsYBpULhENIC02uyatcDSI1eypwfDthX9qfhPzluG/I/z/94dhw/5n+SJB3/98b/Pjs4dPc6PWyuI+HNSobHSjuxRPriw26EhTP056tSAZGzP4C6ln8wUnu0IYK0S/C5dl4ohRmxjpKgJu+hTLIl0zritHGjWetJhZICzHiw+rTNhTGqCRGnWiwUZv1BeEmhwqKvrF739bsT0FKxr4382ZN+k45cruVPgjy9bFsi9YOvjxvuE89D/7Dpn3tfebwDciUPt9/WQu1W/qtQFcJoBJ+/UI/6g+C4Op9YpxwO5ksdssUFhiyHIlaBGoS+f5DPkGnHST+IS9fKSl3njQWGDlDUW2uF5If84LxUipQtLtE6siZ9aO1TgbASUj8wtY7PZGGTuTle+3WdDzWTI0Ttbz3DfzHpdX/ADjIyt3FM8BX/m1ZNdWnxZghX8MPJRob/YkTWH/B+67QBOwjmSYp+i6wrhhANQaHuUzOD/4vXgicgyhJ11g96Q6B6zvm/rV/qwYMR8EtvLNa6AfnRCB6mnA98v7gPft8kqo/md+2wh8r1GtfYfX7g9DPlahgSZ5K1IWenJ0fNhOGQm78+XYi2j//bHxh9Lv+LZsnmvo7/0WTanf98a+c/Cf/22OeYvfGBdeVF5Y/wf9sDY8/y//F9Mo6O447/u+B/g38dBhz3d/4FSr3e/xT/5HH+H0+mXf6/m/z/CLY9zfvoCP7REW3SKZlK//KDweyoO/K/tyP/35R/AQAA///sW02PokAQ/Subd24NjYha192N8aC7Me5eNhxaunSZIEwEM5OY/u+TAhyNc5j4kclMwuMAVKDfo9PddKry1nk3TYqy+1Dk2Un4rsc79T8dDM7nvw7Cj97/He6vxav45nyIf3LsIan8RbLhP4vvIPieH3a019GDb15Ivqb+AAo/kjUXJQjDeNXva597S2/lBcNwZGM2IxtaOwzZN0sojPNpbkF7TKt/ilz9NuV/EC5KrFUmI6Hmxy3HpmQLApy0D4IYj6Awr51HoH+XkcgaBIW/tesFhNqoAIVJZpMtxyVoZdKCXaTw8zlOd5ZB2S5NhbPyLFWcv1ILupFavmnGTyftHFe7swdFzZzLrRF9osapppeLO3bBOD8GdVf3oNCUe2qWSZUkuoJrZjYsrwizi5y6bWBMTZKBpHT2RnJwu+RztkZ7NShd5CJ3mEAtWrRo8UXxMgD0kXXuAD4A"
@@ -2,6 +2,6 @@ module github.com/xhd2015/go-inspect/plugin/export_g/pack
 
 go 1.14
 
-require github.com/xhd2015/go-inspect/plugin/getg v0.0.2
+require github.com/xhd2015/go-inspect/plugin/getg v0.0.3
 
 replace github.com/xhd2015/go-inspect/plugin/getg => ../../getg
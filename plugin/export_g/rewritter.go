@@ -2,9 +2,12 @@ package export_g
 
 import (
 	"fmt"
+	"go/ast"
+	"go/types"
 	"path"
 	"path/filepath"
 
+	"github.com/xhd2015/go-inspect/inspect"
 	"github.com/xhd2015/go-inspect/project"
 	"github.com/xhd2015/go-inspect/rewrite/session"
 	"github.com/xhd2015/go-vendor-pack/writefs"
@@ -12,21 +15,45 @@ import (
 
 //go:generate bash -ec "cd gen_pack && bash gen.sh"
 
+const GlsPkgPath = "github.com/xhd2015/go-inspect/plugin/gls"
+
+type Options struct {
+	// InheritGLS rewrites `go f(args)` to `go gls.Inherit(gls.Capture(), f)(args)`,
+	// so that new goroutines inherit goroutine local storage of their parent.
+	// It only takes effect when github.com/xhd2015/go-inspect/plugin/gls
+	// is imported by the project.
+	InheritGLS bool
+}
+
 func Use() {
+	UseWithOptions(nil)
+}
+
+func UseWithOptions(opts *Options) {
 	project.OnProjectRewrite(func(proj session.Project) project.Rewriter {
-		return NewRewritter()
+		return NewRewritterWithOptions(opts)
 	})
 }
 
 type rewritter struct {
 	project.Rewriter
+	opts *Options
 }
 
 var _ project.Rewriter = (*rewritter)(nil)
+var _ project.Cacheable = (*rewritter)(nil)
 
 func NewRewritter() project.Rewriter {
+	return NewRewritterWithOptions(nil)
+}
+
+func NewRewritterWithOptions(opts *Options) project.Rewriter {
+	if opts == nil {
+		opts = &Options{}
+	}
 	return &rewritter{
 		Rewriter: project.NewDefaultRewriter(&project.RewriteCallback{}),
+		opts:     opts,
 	}
 }
 
@@ -44,6 +71,90 @@ func (c *rewritter) AfterLoad(proj session.Project, session session.Session) {
 	}
 }
 
+// CacheKey implements project.Cacheable
+func (c *rewritter) CacheKey(proj session.Project) string {
+	return fmt.Sprintf("v1,gls=%v", c.inheritGLS(proj))
+}
+
+func (c *rewritter) inheritGLS(proj session.Project) bool {
+	return c.opts.InheritGLS && proj.Global().GetPkg(GlsPkgPath) != nil
+}
+
+// RewriteFile implements project.Rewriter
+func (c *rewritter) RewriteFile(proj session.Project, f inspect.FileContext, sess session.Session) {
+	if !c.inheritGLS(proj) {
+		return
+	}
+	RewriteGoStmts(f, func() session.GoRewriteEdit {
+		return sess.FileRewrite(f)
+	})
+}
+
+// RewriteGoStmts rewrites every `go f(args)` in `f` into
+// `go _gls.Inherit(_gls.Capture(), f)(args)`.
+// Calls to builtins and implicitly instantiated generic
+// functions are left unchanged.
+func RewriteGoStmts(f inspect.FileContext, getEdit func() session.GoRewriteEdit) bool {
+	info := f.Pkg().GoPkg().TypesInfo
+	var edit session.GoRewriteEdit
+	ast.Inspect(f.AST(), func(n ast.Node) bool {
+		goStmt, ok := n.(*ast.GoStmt)
+		if !ok {
+			return true
+		}
+		fun := goStmt.Call.Fun
+		if isBuiltinOrGeneric(info, fun) {
+			return true
+		}
+		if edit == nil {
+			edit = getEdit()
+		}
+		glsName := edit.MustImport(GlsPkgPath, "gls", "_gls", nil)
+		edit.Insert(fun.Pos(), fmt.Sprintf("%s.Inherit(%s.Capture(), ", glsName, glsName))
+		edit.Insert(fun.End(), ")")
+		return true
+	})
+	return edit != nil
+}
+
+func isBuiltinOrGeneric(info *types.Info, fun ast.Expr) bool {
+	if info == nil {
+		return false
+	}
+	for {
+		paren, ok := fun.(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		fun = paren.X
+	}
+	var id *ast.Ident
+	switch fun := fun.(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	}
+	if id == nil {
+		return false
+	}
+	obj := info.Uses[id]
+	if obj == nil {
+		return false
+	}
+	if _, ok := obj.(*types.Builtin); ok {
+		return true
+	}
+	// implicitly instantiated generic function,
+	// its type differs from the declared one
+	if _, ok := obj.(*types.Func); ok {
+		if t := info.TypeOf(id); t != nil && !types.Identical(t, obj.Type()) {
+			return true
+		}
+	}
+	return false
+}
+
 // GenOverlay implements project.Rewriter
 func (c *rewritter) GenOverlay(proj session.Project, session session.Session) {
 	g := proj.Global()
@@ -68,6 +179,12 @@ func (c *rewritter) GenOverlay(proj session.Project, session session.Session) {
 	// see: https://github.com/golang/go/blob/master/src/runtime/HACKING.md
 	edit.AddCode(`func Getcurg_GoInspectExported() *g { return getg().m.curg }`)
 
+	// hook goroutine exit, the hook runs on the exiting goroutine
+	// before it is switched to g0
+	edit.AddCode(`var goexitHook_GoInspectExported func()`)
+	edit.AddCode(`func SetGoexitHook_GoInspectExported(fn func()) { goexitHook_GoInspectExported = fn }`)
+	hookGoexit(runtimePkg, session.FileRewrite)
+
 	// add an extra package with name 0 to make it import earlier than others
 	pkgDir := proj.AllocExtraPkg("0_000_init_getg")
 	session.SetRewriteFile(path.Join(pkgDir, "export_g_runtime_impl.go"), `package init_getg
@@ -83,6 +200,8 @@ func init(){
 	getg.GetImpl = func() unsafe.Pointer { 
 		return unsafe.Pointer(runtime.Getcurg_GoInspectExported())
 	}
+	runtime.SetGoexitHook_GoInspectExported(getg.RunExitHooks)
+	getg.ExitHookInstalled = true
 }`)
 
 	// import from main
@@ -93,6 +212,27 @@ func init(){
 	removeGetgErrMsg(proj, session)
 }
 
+// hookGoexit calls goexitHook_GoInspectExported at the
+// beginning of runtime.goexit1
+func hookGoexit(runtimePkg inspect.Pkg, getEdit func(f inspect.FileContext) session.GoRewriteEdit) {
+	var found bool
+	runtimePkg.RangeFiles(func(i int, f inspect.FileContext) bool {
+		for _, decl := range f.AST().Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || fn.Body == nil || fn.Name.Name != "goexit1" {
+				continue
+			}
+			getEdit(f).Insert(fn.Body.Lbrace+1, "if goexitHook_GoInspectExported != nil { goexitHook_GoInspectExported() };")
+			found = true
+			return false
+		}
+		return true
+	})
+	if !found {
+		panic(fmt.Errorf("runtime.goexit1 not found"))
+	}
+}
+
 func removeGetgErrMsg(proj session.Project, session session.Session) {
 	var errMsgFileBase string
 	if proj.IsVendor() {
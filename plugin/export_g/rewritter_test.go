@@ -0,0 +1,130 @@
+package export_g
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/go-inspect/inspect"
+	"github.com/xhd2015/go-inspect/inspect/load"
+	"github.com/xhd2015/go-inspect/project"
+	"github.com/xhd2015/go-inspect/rewrite/session"
+	"github.com/xhd2015/go-inspect/rewrite/session/session_impl"
+)
+
+// go test -run TestRewriteGoStmts -v ./plugin/export_g
+func TestRewriteGoStmts(t *testing.T) {
+	g, err := load.LoadPackages([]string{"./testdata/gostmt"}, &load.LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := g.LoadInfo().StarterPkgs()[0]
+
+	var code string
+	pkg.RangeFiles(func(i int, f inspect.FileContext) bool {
+		edit := session_impl.NewGoRewrite(f)
+		ok := RewriteGoStmts(f, func() session.GoRewriteEdit {
+			return edit
+		})
+		if !ok {
+			t.Fatalf("expect %s rewritten", f.AbsPath())
+		}
+		code = edit.String()
+		return false
+	})
+
+	_, err = parser.ParseFile(token.NewFileSet(), "gostmt.go", code, 0)
+	if err != nil {
+		t.Fatalf("rewritten code not valid: %v\n%s", err, code)
+	}
+
+	expects := []string{
+		`import _gls "github.com/xhd2015/go-inspect/plugin/gls";`,
+		`go _gls.Inherit(_gls.Capture(), run)(1, "a", "b")`,
+		`go _gls.Inherit(_gls.Capture(), w.Run)(2)`,
+		`go _gls.Inherit(_gls.Capture(), func() {})()`,
+		`go _gls.Inherit(_gls.Capture(), fns[0])()`,
+		`go println("builtin")`,
+	}
+	for _, expect := range expects {
+		if !strings.Contains(code, expect) {
+			t.Fatalf("expect rewritten code contains %s, actual:\n%s", expect, code)
+		}
+	}
+}
+
+// go test -run TestHookGoexit -v ./plugin/export_g
+func TestHookGoexit(t *testing.T) {
+	g, err := load.LoadPackages([]string{"runtime"}, &load.LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	edits := make(map[inspect.FileContext]session.GoRewriteEdit)
+	hookGoexit(g.GetPkg("runtime"), func(f inspect.FileContext) session.GoRewriteEdit {
+		edits[f] = session_impl.NewGoRewrite(f)
+		return edits[f]
+	})
+	if len(edits) != 1 {
+		t.Fatalf("expect 1 file rewritten, actual: %d", len(edits))
+	}
+	for f, edit := range edits {
+		code := edit.String()
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, f.AbsPath(), code, 0)
+		if err != nil {
+			t.Fatalf("rewritten code not valid: %v", err)
+		}
+		var goexit1 *ast.FuncDecl
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == "goexit1" {
+				goexit1 = fn
+			}
+		}
+		if goexit1 == nil {
+			t.Fatalf("goexit1 not found in %s", f.AbsPath())
+		}
+		// the hook runs first, then the original body,
+		// which switches to g0 by mcall(goexit0)
+		stmt := goexit1.Body.List[0]
+		first := code[fset.Position(stmt.Pos()).Offset:fset.Position(stmt.End()).Offset]
+		expect := "if goexitHook_GoInspectExported != nil { goexitHook_GoInspectExported() }"
+		if first != expect {
+			t.Fatalf("expect goexit1 starts with %s, actual: %s", expect, first)
+		}
+		var origin *ast.FuncDecl
+		for _, decl := range f.AST().Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == "goexit1" {
+				origin = fn
+			}
+		}
+		if len(goexit1.Body.List) != len(origin.Body.List)+1 {
+			t.Fatalf("expect %d statements in goexit1, actual: %d", len(origin.Body.List)+1, len(goexit1.Body.List))
+		}
+	}
+}
+
+// go test -run TestGoexitReleaseGLS -v ./plugin/export_g
+func TestGoexitReleaseGLS(t *testing.T) {
+	Use()
+	output := filepath.Join(t.TempDir(), "goexit.bin")
+	_, err := project.TryRewrite([]string{"./"}, &project.RewriteOpts{
+		BuildOpts: &project.BuildOpts{
+			ProjectDir: "./testdata/goexit",
+			Output:     output,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(output).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(out)) != "released" {
+		t.Fatalf("expect gls released when goroutine exits, actual: %s", out)
+	}
+}
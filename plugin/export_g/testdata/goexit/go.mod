@@ -0,0 +1,13 @@
+module example.com/goexit
+
+go 1.18
+
+require (
+	github.com/xhd2015/go-inspect/plugin/getg v0.0.3
+	github.com/xhd2015/go-inspect/plugin/gls v0.0.0
+)
+
+replace (
+	github.com/xhd2015/go-inspect/plugin/getg => ../../../getg
+	github.com/xhd2015/go-inspect/plugin/gls => ../../../gls
+)
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/xhd2015/go-inspect/plugin/getg"
+	"github.com/xhd2015/go-inspect/plugin/gls"
+)
+
+// prints whether gls storage of a goroutine
+// is released when it exits
+func main() {
+	var exiting unsafe.Pointer
+	var result int32
+	// registered after gls, runs after gls releases the storage
+	getg.OnExit(func() {
+		if getg.G() != atomic.LoadPointer(&exiting) {
+			return
+		}
+		if _, ok := gls.Get("key"); ok {
+			atomic.StoreInt32(&result, 2)
+		} else {
+			atomic.StoreInt32(&result, 1)
+		}
+	})
+	go func() {
+		gls.Set("key", "value")
+		atomic.StorePointer(&exiting, getg.G())
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&result) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	switch atomic.LoadInt32(&result) {
+	case 1:
+		fmt.Println("released")
+	case 2:
+		fmt.Println("leaked")
+	default:
+		fmt.Println("no exit hook")
+	}
+}
@@ -0,0 +1,15 @@
+package gostmt
+
+type worker struct{}
+
+func (c *worker) Run(n int) {}
+
+func run(n int, args ...string) {}
+
+func Start(w *worker, fns []func()) {
+	go run(1, "a", "b")
+	go w.Run(2)
+	go func() {}()
+	go fns[0]()
+	go println("builtin")
+}
@@ -1,11 +1,17 @@
 package getg
 
 import (
+	"sync"
+	"sync/atomic"
 	"unsafe"
 )
 
 var GetImpl func() unsafe.Pointer
 
+// ExitHookInstalled is set to true by export_g
+// when runtime.goexit1 is hooked.
+var ExitHookInstalled bool
+
 func Enabled() bool {
 	return GetImpl != nil
 }
@@ -16,3 +22,28 @@ func G() unsafe.Pointer {
 	}
 	return GetImpl()
 }
+
+var exitHooksMutex sync.Mutex
+var exitHooks atomic.Value // []func()
+
+// OnExit registers `fn` to be called when any goroutine exits.
+// `fn` is called inside the exiting goroutine, so G() still
+// refers to it.
+// The main goroutine does not call exit hooks.
+func OnExit(fn func()) {
+	exitHooksMutex.Lock()
+	defer exitHooksMutex.Unlock()
+	prev, _ := exitHooks.Load().([]func())
+	hooks := make([]func(), 0, len(prev)+1)
+	hooks = append(hooks, prev...)
+	hooks = append(hooks, fn)
+	exitHooks.Store(hooks)
+}
+
+// RunExitHooks is called by the rewritten runtime.goexit1
+func RunExitHooks() {
+	hooks, _ := exitHooks.Load().([]func())
+	for _, hook := range hooks {
+		hook()
+	}
+}
@@ -0,0 +1,170 @@
+package gls
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"unsafe"
+
+	"github.com/xhd2015/go-inspect/plugin/getg"
+)
+
+// storage: g -> map[key]value
+// each map is only accessed by its owner goroutine,
+// so no extra lock is needed
+var storage sync.Map // unsafe.Pointer -> map[interface{}]interface{}
+
+func init() {
+	getg.OnExit(clearCurrent)
+}
+
+// Enabled reports whether gls works, it requires
+// the binary to be rewritten by plugin/export_g
+func Enabled() bool {
+	return getg.Enabled()
+}
+
+// Get the value associated with `key` in current goroutine
+func Get(key interface{}) (val interface{}, ok bool) {
+	g := getg.G()
+	if g == nil {
+		return nil, false
+	}
+	m := getMap(g)
+	if m == nil {
+		return nil, false
+	}
+	val, ok = m[key]
+	return
+}
+
+// Set associates `val` with `key` in current goroutine
+func Set(key interface{}, val interface{}) {
+	g := mustG()
+	m := getMap(g)
+	if m == nil {
+		m = make(map[interface{}]interface{}, 1)
+		storage.Store(g, m)
+	}
+	m[key] = val
+}
+
+// Delete removes `key` from current goroutine
+func Delete(key interface{}) {
+	g := getg.G()
+	if g == nil {
+		return
+	}
+	m := getMap(g)
+	if m == nil {
+		return
+	}
+	delete(m, key)
+	if len(m) == 0 {
+		storage.Delete(g)
+	}
+}
+
+// Clear removes all values of current goroutine.
+// If getg.ExitHookInstalled is true, this is
+// automatically called when goroutine exits.
+func Clear() {
+	clearCurrent()
+}
+
+func clearCurrent() {
+	g := getg.G()
+	if g == nil {
+		return
+	}
+	storage.Delete(g)
+}
+
+// Snapshot is a copy of all values of a goroutine,
+// used to propagate values to child goroutines
+type Snapshot map[interface{}]interface{}
+
+// Capture copies values of current goroutine
+func Capture() Snapshot {
+	g := getg.G()
+	if g == nil {
+		return nil
+	}
+	m := getMap(g)
+	if len(m) == 0 {
+		return nil
+	}
+	s := make(Snapshot, len(m))
+	for k, v := range m {
+		s[k] = v
+	}
+	return s
+}
+
+// Restore replaces values of current goroutine with `s`
+func (s Snapshot) Restore() {
+	if len(s) == 0 {
+		clearCurrent()
+		return
+	}
+	g := mustG()
+	m := make(map[interface{}]interface{}, len(s))
+	for k, v := range s {
+		m[k] = v
+	}
+	storage.Store(g, m)
+}
+
+// Go starts `fn` in a new goroutine which
+// inherits values of current goroutine
+func Go(fn func()) {
+	s := Capture()
+	go func() {
+		s.Restore()
+		fn()
+	}()
+}
+
+// Inherit wraps `fn` so that it restores `s` before calling
+// the original function. It is used by the go statement
+// rewrite of export_g:
+//
+//	go f(a, b)
+//
+// becomes
+//
+//	go gls.Inherit(gls.Capture(), f)(a, b)
+//
+// which keeps f, a and b evaluated in the parent goroutine.
+func Inherit[F any](s Snapshot, fn F) F {
+	if len(s) == 0 {
+		return fn
+	}
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		panic(fmt.Errorf("gls: Inherit requires a func, given: %T", fn))
+	}
+	return reflect.MakeFunc(v.Type(), func(args []reflect.Value) []reflect.Value {
+		s.Restore()
+		if v.Type().IsVariadic() {
+			return v.CallSlice(args)
+		}
+		return v.Call(args)
+	}).Interface().(F)
+}
+
+func getMap(g unsafe.Pointer) map[interface{}]interface{} {
+	v, ok := storage.Load(g)
+	if !ok {
+		return nil
+	}
+	return v.(map[interface{}]interface{})
+}
+
+func mustG() unsafe.Pointer {
+	g := getg.G()
+	if g == nil {
+		panic(fmt.Errorf("gls: getg is not enabled, please rewrite with github.com/xhd2015/go-inspect/plugin/export_g"))
+	}
+	return g
+}
@@ -0,0 +1,158 @@
+package gls
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/xhd2015/go-inspect/plugin/getg"
+)
+
+// without the export_g rewrite, goroutines are
+// identified by ids parsed from their stacks
+var fakeGs sync.Map // goroutine id -> unsafe.Pointer
+
+func init() {
+	getg.GetImpl = fakeG
+}
+
+func fakeG() unsafe.Pointer {
+	var buf [64]byte
+	// goroutine 18 [running]:
+	stack := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], []byte("goroutine "))
+	id, err := strconv.ParseInt(string(stack[:bytes.IndexByte(stack, ' ')]), 10, 64)
+	if err != nil {
+		panic(err)
+	}
+	g, _ := fakeGs.LoadOrStore(id, unsafe.Pointer(new(int64)))
+	return g.(unsafe.Pointer)
+}
+
+// run calls `fn` in a new goroutine started by `start`, and waits
+// until it exits, exit hooks are run like the rewritten runtime.goexit1,
+// which is tested by TestGoexitReleaseGLS of plugin/export_g
+func run(start func(fn func()), fn func()) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	start(func() {
+		defer wg.Done()
+		defer getg.RunExitHooks()
+		fn()
+	})
+	wg.Wait()
+}
+
+func goStmt(fn func()) {
+	go fn()
+}
+
+// cd plugin/gls && go test -run TestGetSetDeleteClear -v ./
+func TestGetSetDeleteClear(t *testing.T) {
+	run(goStmt, func() {
+		if _, ok := Get("a"); ok {
+			t.Errorf("expect a absent initially")
+		}
+		Set("a", 1)
+		Set("b", 2)
+		if v, ok := Get("a"); !ok || v != 1 {
+			t.Errorf("expect a=1, actual: %v %v", v, ok)
+		}
+		Delete("a")
+		if _, ok := Get("a"); ok {
+			t.Errorf("expect a deleted")
+		}
+		if v, ok := Get("b"); !ok || v != 2 {
+			t.Errorf("expect b=2, actual: %v %v", v, ok)
+		}
+		Clear()
+		if _, ok := Get("b"); ok {
+			t.Errorf("expect b cleared")
+		}
+		if getMap(getg.G()) != nil {
+			t.Errorf("expect storage released after Clear")
+		}
+	})
+}
+
+// cd plugin/gls && go test -run TestGoInherit -v ./
+func TestGoInherit(t *testing.T) {
+	run(goStmt, func() {
+		Set("trace_id", "abc")
+		var child interface{}
+		run(Go, func() {
+			child, _ = Get("trace_id")
+			Set("trace_id", "child")
+		})
+		if child != "abc" {
+			t.Errorf("expect child inherits abc, actual: %v", child)
+		}
+		if v, _ := Get("trace_id"); v != "abc" {
+			t.Errorf("expect parent unchanged by child, actual: %v", v)
+		}
+	})
+}
+
+// cd plugin/gls && go test -run TestInherit -v ./
+func TestInherit(t *testing.T) {
+	run(goStmt, func() {
+		Set("trace_id", "abc")
+
+		var plain string
+		add := func(a int, b int) {
+			v, _ := Get("trace_id")
+			plain = v.(string) + strconv.Itoa(a+b)
+		}
+		// go Inherit(Capture(), add)(1, 2)
+		addInherited := Inherit(Capture(), add)
+		run(goStmt, func() {
+			addInherited(1, 2)
+		})
+		if plain != "abc3" {
+			t.Errorf("expect plain func gets abc3, actual: %s", plain)
+		}
+
+		var variadic string
+		join := func(prefix string, nums ...int) {
+			v, _ := Get("trace_id")
+			variadic = prefix + v.(string)
+			for _, n := range nums {
+				variadic += strconv.Itoa(n)
+			}
+		}
+		joinInherited := Inherit(Capture(), join)
+		run(goStmt, func() {
+			joinInherited("x:", 1, 2, 3)
+		})
+		if variadic != "x:abc123" {
+			t.Errorf("expect variadic func gets x:abc123, actual: %s", variadic)
+		}
+	})
+}
+
+// cd plugin/gls && go test -run TestReleaseOnExit -v ./
+func TestReleaseOnExit(t *testing.T) {
+	var g unsafe.Pointer
+	run(goStmt, func() {
+		g = getg.G()
+		Set("a", 1)
+		if getMap(g) == nil {
+			t.Errorf("expect storage of running goroutine")
+		}
+	})
+	if getMap(g) != nil {
+		t.Fatalf("expect storage released after goroutine exits")
+	}
+
+	run(goStmt, func() {
+		Set("trace_id", "abc")
+		run(Go, func() {
+			g = getg.G()
+		})
+	})
+	if getMap(g) != nil {
+		t.Fatalf("expect storage of inherited goroutine released after it exits")
+	}
+}
@@ -0,0 +1,7 @@
+module github.com/xhd2015/go-inspect/plugin/gls
+
+go 1.18
+
+require github.com/xhd2015/go-inspect/plugin/getg v0.0.3
+
+replace github.com/xhd2015/go-inspect/plugin/getg => ../getg
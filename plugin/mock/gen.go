@@ -0,0 +1,408 @@
+package mock
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/xhd2015/go-inspect/inspect"
+)
+
+// MockPkgPath is the runtime imported by mock
+// files and rewritten functions
+const MockPkgPath = "github.com/xhd2015/go-inspect/mock"
+
+// DefaultDir is where mock files are generated, relative to the module dir
+const DefaultDir = "test/mock_gen"
+
+// skipMockConst is declared by generated mock packages,
+// which are neither mocked nor rewritten
+const skipMockConst = "_SKIP_MOCK"
+
+type GenOptions struct {
+	// Dir is where mock files are generated, relative
+	// to the dir of the module, default DefaultDir.
+	// A mock package mirrors the path of its package
+	// inside the module, e.g. test/mock_gen/biz for biz.
+	Dir string
+
+	// Filter decides whether mocks of a package are
+	// generated, by default all starter packages are.
+	Filter func(pkg inspect.Pkg) bool
+}
+
+// File is a generated mock file
+type File struct {
+	Pkg     inspect.Pkg
+	Path    string // absolute path
+	Content string
+}
+
+// Generate generates a mock file for each starter package of `g`.
+// Packages main, mock packages and packages without any exported
+// function or method are skipped.
+func Generate(g inspect.Global, opts *GenOptions) ([]*File, error) {
+	if opts == nil {
+		opts = &GenOptions{}
+	}
+	dir := opts.Dir
+	if dir == "" {
+		dir = DefaultDir
+	}
+	var files []*File
+	for _, pkg := range g.LoadInfo().StarterPkgs() {
+		if opts.Filter != nil && !opts.Filter(pkg) {
+			continue
+		}
+		file, err := genPkg(pkg, dir)
+		if err != nil {
+			return nil, fmt.Errorf("generate mock of %s: %w", pkg.Path(), err)
+		}
+		if file != nil {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// WriteFiles writes generated files, creating their dirs
+func WriteFiles(files []*File) error {
+	for _, file := range files {
+		err := os.MkdirAll(filepath.Dir(file.Path), 0755)
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(file.Path, []byte(file.Content), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isMockPkg reports whether `pkg` is generated by Generate
+func isMockPkg(pkg inspect.Pkg) bool {
+	if inspect.HasTypes(pkg) {
+		return pkg.TypePkg().Scope().Lookup(skipMockConst) != nil
+	}
+	// loaded without types
+	found := false
+	pkg.RangeFiles(func(i int, f inspect.FileContext) bool {
+		found = declaresConst(f.AST(), skipMockConst)
+		return !found
+	})
+	return found
+}
+
+func declaresConst(f *ast.File, name string) bool {
+	for _, decl := range f.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			for _, ident := range spec.(*ast.ValueSpec).Names {
+				if ident.Name == name {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// isMockable reports whether `fn` gets a mock: exported
+// functions, and exported methods of exported types
+func isMockable(fn inspect.FuncContext) bool {
+	decl := fn.AST()
+	if decl.Body == nil || !ast.IsExported(decl.Name.Name) {
+		return false
+	}
+	if fn.Recv() == nil {
+		return true
+	}
+	_, typeName := fn.ParseRecvInfo()
+	return ast.IsExported(typeName)
+}
+
+// mockFunc is a function or method of a mocked package
+type mockFunc struct {
+	name string
+	sig  *types.Signature
+	// method
+	owner    string
+	ownerPtr bool
+}
+
+func genPkg(pkg inspect.Pkg, dir string) (*File, error) {
+	mod := pkg.Module()
+	if pkg.Name() == "main" || mod == nil || isMockPkg(pkg) {
+		return nil, nil
+	}
+	if pkg.Path() != mod.Path() && !strings.HasPrefix(pkg.Path(), mod.Path()+"/") {
+		return nil, nil
+	}
+	rel := strings.TrimPrefix(strings.TrimPrefix(pkg.Path(), mod.Path()), "/")
+	mockPkgPath := path.Join(mod.Path(), filepath.ToSlash(dir), rel)
+	if !canImport(mockPkgPath, pkg.Path()) {
+		return nil, nil
+	}
+
+	funcs := collectFuncs(pkg)
+	if len(funcs) == 0 {
+		return nil, nil
+	}
+	gen := &generator{pkg: pkg, imports: newImportList()}
+	code, err := gen.gen(funcs)
+	if err != nil {
+		return nil, err
+	}
+	return &File{
+		Pkg:     pkg,
+		Path:    filepath.Join(mod.Dir(), dir, filepath.FromSlash(rel), "mock.go"),
+		Content: code,
+	}, nil
+}
+
+func collectFuncs(pkg inspect.Pkg) []*mockFunc {
+	g := pkg.Global()
+	typesInfo := pkg.GoPkg().TypesInfo
+	var funcs []*mockFunc
+	pkg.RangeFiles(func(i int, f inspect.FileContext) bool {
+		if f.IsTestGoFile() {
+			return true
+		}
+		for _, decl := range f.AST().Decls {
+			fnDecl, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			fn := g.Registry().FuncDecl(fnDecl)
+			if !isMockable(fn) {
+				continue
+			}
+			obj, ok := typesInfo.Defs[fnDecl.Name].(*types.Func)
+			if !ok {
+				continue
+			}
+			mf := &mockFunc{name: fn.Name(), sig: obj.Type().(*types.Signature)}
+			if fn.Recv() != nil {
+				mf.ownerPtr, mf.owner = fn.ParseRecvInfo()
+			}
+			funcs = append(funcs, mf)
+		}
+		return true
+	})
+	return funcs
+}
+
+// canImport checks the internal package rule
+func canImport(from string, to string) bool {
+	parts := strings.Split(to, "/")
+	for i, part := range parts {
+		if part != "internal" {
+			continue
+		}
+		parent := strings.Join(parts[:i], "/")
+		if parent != "" && from != parent && !strings.HasPrefix(from, parent+"/") {
+			return false
+		}
+	}
+	return true
+}
+
+type generator struct {
+	pkg     inspect.Pkg
+	imports *importList
+}
+
+func (c *generator) gen(funcs []*mockFunc) (string, error) {
+	// reserve the name, the package is imported only if referenced
+	c.imports.reserve(c.pkg.Path(), c.pkg.Name())
+	pkgName := func() string {
+		return c.imports.add(c.pkg.Path(), c.pkg.Name())
+	}
+
+	var owners []string
+	methods := make(map[string][]*mockFunc)
+	var fields bytes.Buffer
+	var links bytes.Buffer
+	for _, fn := range funcs {
+		if fn.owner != "" {
+			if methods[fn.owner] == nil {
+				owners = append(owners, fn.owner)
+			}
+			methods[fn.owner] = append(methods[fn.owner], fn)
+			continue
+		}
+		fmt.Fprintf(&fields, "%s %s\n", fn.name, c.funcType(fn))
+		if !hasTypeParam(fn.sig) {
+			fmt.Fprintf(&links, "%q: Pair{e.%s, %s.%s},\n", fn.name, fn.name, pkgName(), fn.name)
+		}
+	}
+	for _, owner := range owners {
+		fmt.Fprintf(&fields, "%s struct{\n", owner)
+		var ownerLinks bytes.Buffer
+		for _, fn := range methods[owner] {
+			fmt.Fprintf(&fields, "%s %s\n", fn.name, c.funcType(fn))
+			if !isGenericRecv(fn.sig) {
+				fmt.Fprintf(&ownerLinks, "%q: Pair{e.%s.%s, ((*%s.%s)(nil)).%s},\n", fn.name, owner, fn.name, pkgName(), owner, fn.name)
+			}
+		}
+		fields.WriteString("}\n")
+		if ownerLinks.Len() > 0 {
+			fmt.Fprintf(&links, "%q: map[string]interface{}{\n%s},\n", owner, ownerLinks.String())
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by go-inspect mock; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", c.pkg.Name())
+	b.WriteString("import (\n")
+	for _, imp := range c.imports.list {
+		if !imp.used {
+			continue
+		}
+		if imp.name == path.Base(imp.path) {
+			fmt.Fprintf(&b, "%q\n", imp.path)
+		} else {
+			fmt.Fprintf(&b, "%s %q\n", imp.name, imp.path)
+		}
+	}
+	fmt.Fprintf(&b, "_mock %q\n", MockPkgPath)
+	b.WriteString(")\n\n")
+	fmt.Fprintf(&b, "const %s = true\n", skipMockConst)
+	fmt.Fprintf(&b, "const FULL_PKG_NAME = %q\n\n", c.pkg.Path())
+	fmt.Fprintf(&b, "func Setup(ctx %s.Context, setup func(m *M)) %s.Context {\n", c.imports.context, c.imports.context)
+	b.WriteString("m := M{}\nsetup(&m)\nreturn _mock.WithMockSetup(ctx, FULL_PKG_NAME, m)\n}\n\n")
+	fmt.Fprintf(&b, "type M struct {\n%s}\n\n", fields.String())
+	b.WriteString("/* provides quick link */\n")
+	b.WriteString("var _ = func() {\ntype Pair [2]interface{}\n")
+	if links.Len() > 0 {
+		fmt.Fprintf(&b, "e := M{}\n_ = map[string]interface{}{\n%s}\n", links.String())
+	}
+	b.WriteString("}\n")
+
+	code, err := format.Source(b.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("format: %w\n%s", err, b.String())
+	}
+	return string(code), nil
+}
+
+// funcType returns the type of M's field, with the receiver as the first
+// argument. Types involving type parameters or invisible from the mock
+// package are interface{}.
+func (c *generator) funcType(fn *mockFunc) string {
+	var args []string
+	if fn.sig.Recv() != nil {
+		args = append(args, argName(fn.sig.Recv())+" "+c.typeString(fn.sig.Recv().Type()))
+	}
+	params := fn.sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		p := params.At(i)
+		typ := c.typeString(p.Type())
+		if fn.sig.Variadic() && i == params.Len()-1 {
+			typ = "..." + c.typeString(p.Type().(*types.Slice).Elem())
+		}
+		args = append(args, argName(p)+" "+typ)
+	}
+	results := fn.sig.Results()
+	resList := make([]string, 0, results.Len())
+	for i := 0; i < results.Len(); i++ {
+		resList = append(resList, c.typeString(results.At(i).Type()))
+	}
+	res := strings.Join(resList, ", ")
+	if len(resList) > 1 {
+		res = "(" + res + ")"
+	}
+	return strings.TrimSuffix(fmt.Sprintf("func(%s) %s", strings.Join(args, ", "), res), " ")
+}
+
+func argName(v *types.Var) string {
+	if v.Name() == "" {
+		return "_"
+	}
+	return v.Name()
+}
+
+func (c *generator) typeString(t types.Type) string {
+	if hasTypeParam(t) || inspect.RefInvisible(t) {
+		return "interface{}"
+	}
+	return types.TypeString(t, func(pkg *types.Package) string {
+		return c.imports.add(pkg.Path(), pkg.Name())
+	})
+}
+
+func hasTypeParam(t types.Type) bool {
+	found := false
+	inspect.NewTypeExpr(t).Traverse(func(subType *inspect.TypeExpr) {
+		if subType.Kind == inspect.TypeParam {
+			found = true
+		}
+	})
+	return found
+}
+
+func isGenericRecv(sig *types.Signature) bool {
+	return sig.Recv() != nil && hasTypeParam(sig.Recv().Type())
+}
+
+type importSpec struct {
+	name string
+	path string
+	used bool
+}
+
+type importList struct {
+	specs map[string]*importSpec // by path
+	used  map[string]bool        // names
+	list  []*importSpec
+
+	context string // name of package context
+}
+
+func newImportList() *importList {
+	c := &importList{
+		specs: make(map[string]*importSpec),
+		used:  make(map[string]bool),
+	}
+	// declared by the mock file
+	for _, name := range []string{"_mock", skipMockConst, "FULL_PKG_NAME", "Setup", "M", "Pair", "e"} {
+		c.used[name] = true
+	}
+	c.context = c.add("context", "context")
+	return c
+}
+
+// add returns the name of imported `pkgPath`
+func (c *importList) add(pkgPath string, name string) string {
+	spec := c.reserve(pkgPath, name)
+	spec.used = true
+	return spec.name
+}
+
+// reserve gives `pkgPath` a name without importing it
+func (c *importList) reserve(pkgPath string, name string) *importSpec {
+	if spec, ok := c.specs[pkgPath]; ok {
+		return spec
+	}
+	n := name
+	for i := 2; c.used[n]; i++ {
+		n = fmt.Sprintf("%s%d", name, i)
+	}
+	c.used[n] = true
+	spec := &importSpec{name: n, path: pkgPath}
+	c.specs[pkgPath] = spec
+	c.list = append(c.list, spec)
+	return spec
+}
@@ -0,0 +1,65 @@
+package mock
+
+import (
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/go-inspect/inspect/load"
+)
+
+// go test -run TestGenerate -v ./plugin/mock
+func TestGenerate(t *testing.T) {
+	g, err := load.LoadPackages([]string{"./..."}, &load.LoadOptions{
+		ProjectDir: "./testdata/simple",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := Generate(g, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// internal/dep has nothing to mock
+	if len(files) != 1 {
+		t.Fatalf("expect 1 file, actual: %d", len(files))
+	}
+	file := files[0]
+	if !strings.HasSuffix(filepath.ToSlash(file.Path), "testdata/simple/test/mock_gen/biz/mock.go") {
+		t.Fatalf("unexpected file: %s", file.Path)
+	}
+	code := file.Content
+	_, err = parser.ParseFile(token.NewFileSet(), "mock.go", code, 0)
+	if err != nil {
+		t.Fatalf("generated code not valid: %v\n%s", err, code)
+	}
+
+	expects := []string{
+		"package biz\n",
+		`_mock "github.com/xhd2015/go-inspect/mock"`,
+		"func Setup(ctx context.Context, setup func(m *M)) context.Context {",
+		"Run   func(ctx context.Context, status int, _ string) (int, error)",
+		"Sum   func(ctx context.Context, base int, nums ...int) int",
+		// invisible from the mock package
+		"Dep   func(ctx context.Context) interface{}",
+		// generics
+		"First func(ctx context.Context, list interface{}) interface{}",
+		"Add func(c interface{}, ctx context.Context, items ...interface{})",
+		// embedded
+		"Name func(c *biz.Base, ctx context.Context) string",
+		"Run func(_ biz.Status, ctx context.Context, status int, _ string) (int, error)",
+		`"Run": Pair{e.Status.Run, ((*biz.Status)(nil)).Run},`,
+	}
+	for _, expect := range expects {
+		if !strings.Contains(code, expect) {
+			t.Fatalf("expect generated code contains %s, actual:\n%s", expect, code)
+		}
+	}
+	for _, unexpect := range []string{"notExported", "internal/dep", "First,", "List\": map"} {
+		if strings.Contains(code, unexpect) {
+			t.Fatalf("expect generated code not contains %s, actual:\n%s", unexpect, code)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package mock
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+
+	"github.com/xhd2015/go-inspect/inspect"
+	"github.com/xhd2015/go-inspect/project"
+	"github.com/xhd2015/go-inspect/rewrite/session"
+)
+
+type Options struct {
+	// Filter decides whether a mockable function
+	// should be rewritten, by default all are.
+	Filter func(fn inspect.FuncContext) bool
+}
+
+// Use enables mocking of exported functions and methods in packages
+// selected by the project's package filter. It takes effect only
+// when the runtime github.com/xhd2015/go-inspect/mock is imported
+// by the project, usually by generated mock packages.
+func Use() {
+	UseWithOptions(nil)
+}
+
+func UseWithOptions(opts *Options) {
+	project.OnProjectRewrite(func(proj session.Project) project.Rewriter {
+		return NewRewritter(opts)
+	})
+}
+
+type rewritter struct {
+	project.Rewriter
+	opts *Options
+}
+
+var _ project.Rewriter = (*rewritter)(nil)
+var _ project.Cacheable = (*rewritter)(nil)
+var _ project.Syntactic = (*rewritter)(nil)
+
+func NewRewritter(opts *Options) project.Rewriter {
+	if opts == nil {
+		opts = &Options{}
+	}
+	return &rewritter{
+		Rewriter: project.NewDefaultRewriter(&project.RewriteCallback{}),
+		opts:     opts,
+	}
+}
+
+// CacheKey implements project.Cacheable,
+// a custom Filter cannot be cached
+func (c *rewritter) CacheKey(proj session.Project) string {
+	if c.opts.Filter != nil {
+		return ""
+	}
+	return fmt.Sprintf("v1,mock=%v", proj.Global().GetPkg(MockPkgPath) != nil)
+}
+
+// SyntaxOnly implements project.Syntactic,
+// a custom Filter may need types
+func (c *rewritter) SyntaxOnly(proj session.Project) bool {
+	return c.opts.Filter == nil
+}
+
+// RewriteFile implements project.Rewriter
+func (c *rewritter) RewriteFile(proj session.Project, f inspect.FileContext, sess session.Session) {
+	if f.IsTestGoFile() || proj.Global().GetPkg(MockPkgPath) == nil || isMockPkg(f.Pkg()) {
+		return
+	}
+	RewriteFile(f, func() session.GoRewriteEdit {
+		return sess.FileRewrite(f)
+	}, c.opts.Filter)
+}
+
+// RewriteFile makes every mockable function of `f` dispatch through
+// the runtime at its entry, which calls the mock set up in its
+// context.Context argument, or the original body wrapped in a closure.
+// `getEdit` is called lazily, only when there is at least one function
+// to be rewritten. Like plugin/trace, line numbers are kept unchanged.
+func RewriteFile(f inspect.FileContext, getEdit func() session.GoRewriteEdit, filter func(fn inspect.FuncContext) bool) bool {
+	var edit session.GoRewriteEdit
+	var mockName string
+	suffix := project.ShortHashFile(f)
+
+	idx := 0
+	for _, decl := range f.AST().Decls {
+		fnDecl, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		fn := f.Global().Registry().FuncDecl(fnDecl)
+		if !isMockable(fn) || (filter != nil && !filter(fn)) {
+			continue
+		}
+		if edit == nil {
+			edit = getEdit()
+			mockName = edit.MustImport(MockPkgPath, "mock", "_mock", nil)
+		}
+		rewriteFunc(fn, edit, mockName, fmt.Sprintf("_mock_fn_%s_%d", suffix, idx))
+		idx++
+	}
+	return edit != nil
+}
+
+func rewriteFunc(fn inspect.FuncContext, edit session.GoRewriteEdit, mockName string, fnVar string) {
+	g := fn.File().Global()
+
+	recv := "nil"
+	var owner string
+	var ownerPtr bool
+	if fn.Recv() != nil {
+		fn.Recv().RangeVars(func(i int, f inspect.FieldVar) bool {
+			recv = ensureName(f, "_mock_recv", edit)
+			return true
+		})
+		ownerPtr, owner = fn.ParseRecvInfo()
+	}
+	var args []string
+	fn.Type().Args().RangeVars(func(i int, f inspect.FieldVar) bool {
+		args = append(args, "&"+ensureName(f, fmt.Sprintf("_mock_a%d", i), edit))
+		return true
+	})
+
+	var results []string
+	resultList := fn.AST().Type.Results
+	var resultTypes string
+	if resultList != nil && len(resultList.List) > 0 {
+		// the original body returns through a closure
+		// declaring the same results
+		resultTypes = g.CodeSlice(resultList.Pos(), resultList.End())
+		needParen := !resultList.Opening.IsValid()
+		if needParen {
+			edit.Insert(resultList.Pos(), "(")
+		}
+		fn.Type().Results().RangeVars(func(i int, f inspect.FieldVar) bool {
+			results = append(results, ensureName(f, fmt.Sprintf("_mock_r%d", i), edit))
+			return true
+		})
+		if needParen {
+			edit.Insert(resultList.End(), ")")
+		}
+	}
+
+	body := fn.AST().Body
+	call := fmt.Sprintf("%s.Call(%s, %s, %s, %s, func() {", mockName, fnVar, recv, interfaceList(args), interfaceList(addrs(results)))
+	if len(results) == 0 {
+		edit.Insert(body.Lbrace+1, call)
+		edit.Insert(body.Rbrace, "}); ")
+	} else {
+		edit.Insert(body.Lbrace+1, fmt.Sprintf("%s %s = func() %s {", call, strings.Join(results, ", "), resultTypes))
+		edit.Insert(body.Rbrace, "}() }); return ")
+	}
+
+	edit.Append(fmt.Sprintf("\nvar %s = &%s.StubInfo{PkgName: %q, Owner: %q, OwnerPtr: %v, Name: %q}\n",
+		fnVar, mockName, fn.File().Pkg().Path(), owner, ownerPtr, fn.Name(),
+	))
+}
+
+// ensureName gives unnamed and blank vars a name so
+// that they can be referenced
+func ensureName(f inspect.FieldVar, name string, edit session.GoRewriteEdit) string {
+	if f.Name() != "" && f.Name() != "_" {
+		return f.Name()
+	}
+	f.Rename(name, edit)
+	return name
+}
+
+func addrs(names []string) []string {
+	list := make([]string, 0, len(names))
+	for _, name := range names {
+		list = append(list, "&"+name)
+	}
+	return list
+}
+
+func interfaceList(list []string) string {
+	if len(list) == 0 {
+		return "nil"
+	}
+	return "[]interface{}{" + strings.Join(list, ", ") + "}"
+}
@@ -0,0 +1,71 @@
+package mock
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/go-inspect/inspect"
+	"github.com/xhd2015/go-inspect/inspect/load"
+	"github.com/xhd2015/go-inspect/rewrite/session"
+	"github.com/xhd2015/go-inspect/rewrite/session/session_impl"
+)
+
+// go test -run TestRewriteFile -v ./plugin/mock
+func TestRewriteFile(t *testing.T) {
+	g, err := load.LoadPackages([]string{"./biz"}, &load.LoadOptions{
+		ProjectDir: "./testdata/simple",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := g.LoadInfo().StarterPkgs()[0]
+
+	var code string
+	pkg.RangeFiles(func(i int, f inspect.FileContext) bool {
+		edit := session_impl.NewGoRewrite(f)
+		ok := RewriteFile(f, func() session.GoRewriteEdit {
+			return edit
+		}, nil)
+		if !ok {
+			t.Fatalf("expect %s rewritten", f.AbsPath())
+		}
+		code = edit.String()
+		return false
+	})
+
+	_, err = parser.ParseFile(token.NewFileSet(), "biz.go", code, 0)
+	if err != nil {
+		t.Fatalf("rewritten code not valid: %v\n%s", err, code)
+	}
+
+	expects := []string{
+		`import _mock "github.com/xhd2015/go-inspect/mock";`,
+		`func Run(ctx context.Context, status int, _mock_a2 string) (_mock_r0  int, _mock_r1  error) {_mock.Call(_mock_fn_`,
+		`, nil, []interface{}{&ctx, &status, &_mock_a2}, []interface{}{&_mock_r0, &_mock_r1}, func() { _mock_r0, _mock_r1 = func() (int, error) {`,
+		`}() }); return }`,
+		`, []interface{}{&ctx, &base, &nums}, []interface{}{&sum}, func() { sum = func() (sum int) {`,
+		`func (_mock_recv  Status) Run(`,
+		`func (c *List[T]) Add(ctx context.Context, items ...T) {_mock.Call(_mock_fn_`,
+		`, c, []interface{}{&ctx, &items}, nil, func() {`,
+		`PkgName: "github.com/xhd2015/go-inspect/plugin/mock/testdata/simple/biz", Owner: "Base", OwnerPtr: true, Name: "Name"}`,
+	}
+	for _, expect := range expects {
+		if !strings.Contains(code, expect) {
+			t.Fatalf("expect rewritten code contains %s, actual:\n%s", expect, code)
+		}
+	}
+	for _, unexpect := range []string{"func notExported() {_mock", "func (c status) Run() {_mock"} {
+		if strings.Contains(code, unexpect) {
+			t.Fatalf("expect %s not rewritten, actual:\n%s", unexpect, code)
+		}
+	}
+
+	// line numbers are kept
+	origLines := strings.Count(g.FileCode(pkg.GoPkg().GoFiles[0]), "\n")
+	newLines := strings.Split(code, "\n")
+	if !strings.HasPrefix(newLines[origLines-2], "func (c status) Run() {") {
+		t.Fatalf("expect line %d to be status.Run, actual: %s", origLines-1, newLines[origLines-2])
+	}
+}
@@ -0,0 +1,60 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xhd2015/go-inspect/plugin/mock/testdata/simple/biz/internal/dep"
+)
+
+func Run(ctx context.Context, status int, _ string) (int, error) {
+	fmt.Printf("biz.Run: %v\n", status)
+	return 0, nil
+}
+
+func Sum(ctx context.Context, base int, nums ...int) (sum int) {
+	sum = base
+	for _, n := range nums {
+		sum += n
+	}
+	return
+}
+
+func Dep(ctx context.Context) dep.DepStatus {
+	return dep.DepStatus(1)
+}
+
+func notExported() {
+}
+
+type Base struct{}
+
+func (c *Base) Name(ctx context.Context) string {
+	return "base"
+}
+
+// Status embeds Base, Name is mocked through Base
+type Status struct {
+	Base
+}
+
+func (Status) Run(ctx context.Context, status int, _ string) (int, error) {
+	return status, nil
+}
+
+type List[T any] struct {
+	items []T
+}
+
+func (c *List[T]) Add(ctx context.Context, items ...T) {
+	c.items = append(c.items, items...)
+}
+
+func First[T any](ctx context.Context, list *List[T]) T {
+	return list.items[0]
+}
+
+type status int
+
+func (c status) Run() {
+}
@@ -0,0 +1,3 @@
+module github.com/xhd2015/go-inspect/plugin/mock/testdata/simple
+
+go 1.18
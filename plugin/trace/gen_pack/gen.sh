@@ -0,0 +1,15 @@
+#!/usr/bin/env bash
+set -e
+
+# this standalone package is required because
+# we do not want to bring extra dependency to main go.mod
+
+go get github.com/xhd2015/go-vendor-pack@latest
+
+go run github.com/xhd2015/go-vendor-pack/cmd/go-pack pack \
+    -pkg trace \
+    -var TRACER_PACK \
+    -o ../tracer_gen.go \
+    -run-go-mod-tidy \
+    -run-go-mod-vendor \
+    ../pack
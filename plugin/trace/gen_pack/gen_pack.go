@@ -0,0 +1,3 @@
+package gen_pack
+
+import _ "github.com/xhd2015/go-vendor-pack/cmd/go-pack"
@@ -0,0 +1,5 @@
+module github.com/xhd2015/go-inspect/plugin/trace/gen_pack
+
+go 1.13
+
+require github.com/xhd2015/go-vendor-pack v1.0.8
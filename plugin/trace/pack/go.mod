@@ -0,0 +1,7 @@
+module github.com/xhd2015/go-inspect/plugin/trace/pack
+
+go 1.14
+
+require github.com/xhd2015/go-inspect/plugin/tracer v0.0.1
+
+replace github.com/xhd2015/go-inspect/plugin/tracer => ../../tracer
@@ -0,0 +1,5 @@
+package pack
+
+import (
+	_ "github.com/xhd2015/go-inspect/plugin/tracer"
+)
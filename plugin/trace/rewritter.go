@@ -0,0 +1,195 @@
+package trace
+
+import (
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"strings"
+
+	"github.com/xhd2015/go-inspect/inspect"
+	"github.com/xhd2015/go-inspect/project"
+	"github.com/xhd2015/go-inspect/rewrite/session"
+)
+
+//go:generate bash -ec "cd gen_pack && bash gen.sh"
+
+const TracerPkgPath = "github.com/xhd2015/go-inspect/plugin/tracer"
+
+type Options struct {
+	// Filter decides whether a function should be traced,
+	// by default all functions with body are traced.
+	Filter func(fn inspect.FuncContext) bool
+
+	// IncludeTestFiles also trace functions inside _test.go files
+	IncludeTestFiles bool
+}
+
+// Use enables tracing of all functions in packages
+// selected by the project's package filter.
+func Use() {
+	UseWithOptions(nil)
+}
+
+func UseWithOptions(opts *Options) {
+	project.OnProjectRewrite(func(proj session.Project) project.Rewriter {
+		return NewRewritter(opts)
+	})
+}
+
+type rewritter struct {
+	project.Rewriter
+	opts *Options
+}
+
+var _ project.Rewriter = (*rewritter)(nil)
+var _ project.Cacheable = (*rewritter)(nil)
+var _ project.Syntactic = (*rewritter)(nil)
+
+func NewRewritter(opts *Options) project.Rewriter {
+	if opts == nil {
+		opts = &Options{}
+	}
+	return &rewritter{
+		Rewriter: project.NewDefaultRewriter(&project.RewriteCallback{}),
+		opts:     opts,
+	}
+}
+
+// AfterLoad implements project.Rewriter
+func (c *rewritter) AfterLoad(proj session.Project, session session.Session) {
+	// unpack tracer
+	err := session.ImportPackedModulesBase64(TRACER_PACK)
+	if err != nil {
+		panic(fmt.Errorf("import tracer: %w", err))
+	}
+}
+
+// CacheKey implements project.Cacheable,
+// a custom Filter cannot be cached
+func (c *rewritter) CacheKey(proj session.Project) string {
+	if c.opts.Filter != nil {
+		return ""
+	}
+	return fmt.Sprintf("v1,test=%v", c.opts.IncludeTestFiles)
+}
+
+// SyntaxOnly implements project.Syntactic,
+// a custom Filter may need types
+func (c *rewritter) SyntaxOnly(proj session.Project) bool {
+	return c.opts.Filter == nil
+}
+
+// RewriteFile implements project.Rewriter
+func (c *rewritter) RewriteFile(proj session.Project, f inspect.FileContext, sess session.Session) {
+	if !c.opts.IncludeTestFiles && f.IsTestGoFile() {
+		return
+	}
+	RewriteFile(f, func() session.GoRewriteEdit {
+		return sess.FileRewrite(f)
+	}, c.opts.Filter)
+}
+
+// RewriteFile injects enter and exit hooks into every function of `f`.
+// `getEdit` is called lazily, only when there is at least one function
+// to be traced, so untouched files are not rewritten.
+// Line numbers of the original code are kept unchanged: the hooks
+// are inserted on the same line as the function body's `{`, and
+// function descriptors are appended to the end of the file.
+func RewriteFile(f inspect.FileContext, getEdit func() session.GoRewriteEdit, filter func(fn inspect.FuncContext) bool) bool {
+	var edit session.GoRewriteEdit
+	var tracerName string
+	suffix := project.ShortHashFile(f)
+
+	idx := 0
+	for _, decl := range f.AST().Decls {
+		fnDecl, ok := decl.(*ast.FuncDecl)
+		if !ok || fnDecl.Body == nil || fnDecl.Name.Name == "_" {
+			continue
+		}
+		fn := f.Global().Registry().FuncDecl(fnDecl)
+		if filter != nil && !filter(fn) {
+			continue
+		}
+		if edit == nil {
+			edit = getEdit()
+			tracerName = edit.MustImport(TracerPkgPath, "tracer", "_tracer", nil)
+		}
+		rewriteFunc(fn, edit, tracerName, fmt.Sprintf("_trace_fn_%s_%d", suffix, idx))
+		idx++
+	}
+	return edit != nil
+}
+
+func rewriteFunc(fn inspect.FuncContext, edit session.GoRewriteEdit, tracerName string, fnVar string) {
+	var args []string
+	var results []string
+
+	if recv := fn.Recv(); recv != nil {
+		recv.RangeVars(func(i int, f inspect.FieldVar) bool {
+			args = append(args, ensureName(f, "_trace_recv", edit))
+			return true
+		})
+	}
+	fn.Type().Args().RangeVars(func(i int, f inspect.FieldVar) bool {
+		args = append(args, ensureName(f, fmt.Sprintf("_trace_a%d", i), edit))
+		return true
+	})
+
+	resultList := fn.AST().Type.Results
+	if resultList != nil && len(resultList.List) > 0 {
+		// a single unnamed result has no parentheses,
+		// naming it requires them.
+		needParen := !resultList.Opening.IsValid()
+		if needParen {
+			edit.Insert(resultList.Pos(), "(")
+		}
+		fn.Type().Results().RangeVars(func(i int, f inspect.FieldVar) bool {
+			results = append(results, ensureName(f, fmt.Sprintf("_trace_r%d", i), edit))
+			return true
+		})
+		if needParen {
+			edit.Insert(resultList.End(), ")")
+		}
+	}
+
+	g := fn.File().Global()
+	pos := g.FileSet().Position(fn.AST().Pos())
+
+	code := fmt.Sprintf("_trace_call := %s.Enter(%s%s); defer func() { if _trace_e := recover(); _trace_e != nil { _trace_call.ExitPanic(_trace_e); panic(_trace_e) }; _trace_call.Exit(%s) }();",
+		tracerName, fnVar, prefixComma(args), strings.Join(results, ", "),
+	)
+	edit.Insert(fn.AST().Body.Lbrace+1, code)
+
+	edit.Append(fmt.Sprintf("\nvar %s = &%s.Func{Pkg: %q, File: %q, Line: %d, Name: %q, Args: %s, Results: %s}\n",
+		fnVar, tracerName, fn.File().Pkg().Path(), filepath.Base(pos.Filename), pos.Line, fn.QuanlifiedName(),
+		formatStrings(args), formatStrings(results),
+	))
+}
+
+// ensureName gives unnamed and blank vars a name so
+// that they can be referenced
+func ensureName(f inspect.FieldVar, name string, edit session.GoRewriteEdit) string {
+	if f.Name() != "" && f.Name() != "_" {
+		return f.Name()
+	}
+	f.Rename(name, edit)
+	return name
+}
+
+func prefixComma(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	return ", " + strings.Join(names, ", ")
+}
+
+func formatStrings(names []string) string {
+	if len(names) == 0 {
+		return "nil"
+	}
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, fmt.Sprintf("%q", name))
+	}
+	return "[]string{" + strings.Join(quoted, ", ") + "}"
+}
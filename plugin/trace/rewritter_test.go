@@ -0,0 +1,62 @@
+package trace
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/go-inspect/inspect"
+	"github.com/xhd2015/go-inspect/inspect/load"
+	"github.com/xhd2015/go-inspect/rewrite/session"
+	"github.com/xhd2015/go-inspect/rewrite/session/session_impl"
+)
+
+// go test -run TestRewriteFile -v ./plugin/trace
+func TestRewriteFile(t *testing.T) {
+	g, err := load.LoadPackages([]string{"./testdata/simple"}, &load.LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := g.LoadInfo().StarterPkgs()[0]
+
+	var code string
+	pkg.RangeFiles(func(i int, f inspect.FileContext) bool {
+		edit := session_impl.NewGoRewrite(f)
+		ok := RewriteFile(f, func() session.GoRewriteEdit {
+			return edit
+		}, nil)
+		if !ok {
+			t.Fatalf("expect %s rewritten", f.AbsPath())
+		}
+		code = edit.String()
+		return false
+	})
+
+	_, err = parser.ParseFile(token.NewFileSet(), "simple.go", code, 0)
+	if err != nil {
+		t.Fatalf("rewritten code not valid: %v\n%s", err, code)
+	}
+
+	expects := []string{
+		`import _tracer "github.com/xhd2015/go-inspect/plugin/tracer";`,
+		`func Run(ctx context.Context, status int, _trace_a2 string) (_trace_r0  int, _trace_r1  error) {_trace_call := _tracer.Enter(`,
+		`_trace_call.Exit(_trace_r0, _trace_r1) }();`,
+		`func (_trace_recv  Status) Name() (_trace_r0  string) {`,
+		`func (c *Status) Sum(base int, nums ...int) (sum int) {_trace_call := _tracer.Enter(`,
+		`Name: "*Status.Sum", Args: []string{"c", "base", "nums"}, Results: []string{"sum"}}`,
+		`_trace_call.Exit() }();`,
+	}
+	for _, expect := range expects {
+		if !strings.Contains(code, expect) {
+			t.Fatalf("expect rewritten code contains %s, actual:\n%s", expect, code)
+		}
+	}
+
+	// line numbers are kept
+	origLines := strings.Count(g.FileCode(pkg.GoPkg().GoFiles[0]), "\n")
+	newLines := strings.Split(code, "\n")
+	if !strings.HasPrefix(newLines[origLines-2], "func noResult() {") {
+		t.Fatalf("expect line %d to be noResult, actual: %s", origLines-1, newLines[origLines-2])
+	}
+}
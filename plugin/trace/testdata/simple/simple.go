@@ -0,0 +1,28 @@
+package simple
+
+import (
+	"context"
+	"fmt"
+)
+
+func Run(ctx context.Context, status int, _ string) (int, error) {
+	fmt.Printf("simple.Run: %v\n", status)
+	return 0, nil
+}
+
+type Status int
+
+func (Status) Name() string {
+	return "status"
+}
+
+func (c *Status) Sum(base int, nums ...int) (sum int) {
+	sum = base
+	for _, n := range nums {
+		sum += n
+	}
+	return
+}
+
+func noResult() {
+}
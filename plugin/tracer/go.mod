@@ -0,0 +1,3 @@
+module github.com/xhd2015/go-inspect/plugin/tracer
+
+go 1.13
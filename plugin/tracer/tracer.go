@@ -0,0 +1,111 @@
+package tracer
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Func describes a traced function, it is
+// generated once per function by the rewriter.
+type Func struct {
+	Pkg  string
+	File string
+	Line int
+
+	// Name is the function name, for methods, the
+	// receiver type is included: (*T).Name or T.Name
+	Name string
+
+	// names of receiver(if any) and args
+	Args []string
+	// names of results
+	Results []string
+}
+
+// Call represents a single invocation of a traced function
+type Call struct {
+	Func *Func
+
+	// receiver(if any) followed by arguments
+	Args []interface{}
+	// Results are set before OnExit is called
+	Results []interface{}
+
+	Start    time.Time
+	Duration time.Duration
+
+	// Panic is the recovered value when the function panics,
+	// the panic is re-raised after OnExit returns.
+	Panic    interface{}
+	Panicked bool
+
+	// Data can be used by interceptor to associate
+	// extra information between OnEnter and OnExit
+	Data interface{}
+}
+
+type Interceptor struct {
+	OnEnter func(call *Call)
+	OnExit  func(call *Call)
+}
+
+var interceptor atomic.Value // *Interceptor
+
+// SetInterceptor replaces the current interceptor,
+// nil disables tracing.
+func SetInterceptor(i *Interceptor) {
+	interceptor.Store(i)
+}
+
+func getInterceptor() *Interceptor {
+	i, _ := interceptor.Load().(*Interceptor)
+	return i
+}
+
+// Enter is called at the beginning of each traced function.
+// It returns nil if no interceptor is installed, all methods
+// of *Call are safe to be called with nil.
+func Enter(fn *Func, args ...interface{}) *Call {
+	i := getInterceptor()
+	if i == nil {
+		return nil
+	}
+	call := &Call{
+		Func:  fn,
+		Args:  args,
+		Start: time.Now(),
+	}
+	if i.OnEnter != nil {
+		i.OnEnter(call)
+	}
+	return call
+}
+
+// Exit is called when the traced function returns normally
+func (c *Call) Exit(results ...interface{}) {
+	if c == nil {
+		return
+	}
+	c.Results = results
+	c.finish()
+}
+
+// ExitPanic is called when the traced function panics, the
+// caller is responsible for re-panic after this returns.
+func (c *Call) ExitPanic(e interface{}) {
+	if c == nil {
+		return
+	}
+	c.Panic = e
+	c.Panicked = true
+	c.finish()
+}
+
+func (c *Call) finish() {
+	c.Duration = time.Since(c.Start)
+	i := getInterceptor()
+	if i == nil || i.OnExit == nil {
+		return
+	}
+	i.OnExit(c)
+}
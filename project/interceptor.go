@@ -1,6 +1,9 @@
 package project
 
 import (
+	"reflect"
+	"runtime"
+
 	"github.com/xhd2015/go-inspect/inspect"
 	"github.com/xhd2015/go-inspect/rewrite/session"
 )
@@ -54,3 +57,13 @@ func OnRewriteFile(fn func(proj session.Project, f inspect.FileContext, session
 func OnFinish(fn func(proj session.Project, err error, result *RewriteResult)) {
 	finishListeners = append(finishListeners, fn)
 }
+
+// originOf names the edits made by listener `fn` by its
+// function, e.g. github.com/xhd2015/go-inspect/plugin/trace.Use.func1
+func originOf(fn interface{}) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return ""
+	}
+	return f.Name()
+}
@@ -6,6 +6,12 @@ import (
 	"github.com/xhd2015/go-inspect/rewrite/session"
 )
 
+// Rewriter hooks into the rewrite of a project.
+// RewritePackage and RewriteFile may be called concurrently
+// for different packages when RewriteOpts.Concurrency > 1,
+// other callbacks are always called serially. The session,
+// its edits and inspect.Global, e.g. Registry().FuncDecl,
+// are safe to use from them, other shared state is not.
 type Rewriter interface {
 	BeforeLoad(proj session.Project, session session.Session)
 	InitSession(proj session.Project, session session.Session)
@@ -16,6 +22,24 @@ type Rewriter interface {
 
 	Finish(proj session.Project, err error, result *RewriteResult)
 }
+
+// Cacheable is optionally implemented by a Rewriter whose
+// RewritePackage and RewriteFile only depend on the package
+// being rewritten, its dependencies and what the returned key covers.
+// CacheKey is called after AfterLoad, an empty key disables the cache.
+type Cacheable interface {
+	CacheKey(proj session.Project) string
+}
+
+// Syntactic is optionally implemented by a Rewriter declaring
+// whether it only needs syntax. When every rewriter returns true and
+// no listener other than BeforeLoad is registered, packages are
+// loaded without types. A Rewriter not implementing it needs types.
+// SyntaxOnly is called after BeforeLoad.
+type Syntactic interface {
+	SyntaxOnly(proj session.Project) bool
+}
+
 type RewriteCallback struct {
 	BeforeLoad     func(proj session.Project, session session.Session)
 	InitSession    func(proj session.Project, session session.Session)
@@ -86,6 +110,26 @@ func (c *options) SetRewriteStd(rewriteStd bool) {
 	c.underlyingOpts.RewriteStd = rewriteStd
 }
 
+// CacheKey implements Options
+func (c *options) CacheKey() string {
+	return c.underlyingOpts.CacheKey
+}
+
+// SetCacheKey implements Options
+func (c *options) SetCacheKey(key string) {
+	c.underlyingOpts.CacheKey = key
+}
+
+// SyntaxOnly implements Options
+func (c *options) SyntaxOnly() bool {
+	return c.underlyingOpts.SyntaxOnly
+}
+
+// SetSyntaxOnly implements Options
+func (c *options) SetSyntaxOnly(syntaxOnly bool) {
+	c.underlyingOpts.SyntaxOnly = syntaxOnly
+}
+
 // Force implements Options
 func (c *options) Force() bool {
 	return c.underlyingOpts.Force
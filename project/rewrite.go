@@ -8,6 +8,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/xhd2015/go-inspect/inspect"
 	"github.com/xhd2015/go-inspect/inspect/util"
@@ -19,13 +20,34 @@ import (
 type EditCallbackFn = session.EditCallbackFn
 type BuildOpts = rewrite.BuildOpts
 type RewriteOpts = rewrite.RewriteOpts
+type TestOptions = rewrite.TestOptions
+type Error = rewrite.Error
+type SourceMap = rewrite.SourceMap
+type BuildStrategy = rewrite.BuildStrategy
+
+const (
+	BuildStrategyCopy    = rewrite.BuildStrategyCopy
+	BuildStrategyOverlay = rewrite.BuildStrategyOverlay
+)
 
 type RewriteResult struct {
 	*rewrite.BuildResult
 }
 
 func Rewrite(loadArgs []string, opts *RewriteOpts) *RewriteResult {
+	res, err := TryRewrite(loadArgs, opts)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// TryRewrite is like Rewrite, but returns error instead of
+// panicking, errors of the rewrite pipeline are *Error.
+func TryRewrite(loadArgs []string, opts *RewriteOpts) (*RewriteResult, error) {
 	var extraCallbacks []Rewriter
+	// origins of extraCallbacks
+	var extraOrigins []string
 	return doRewrite(loadArgs, &RewriteCallbackOpts{
 		RewriteOpts: opts,
 		RewriteCallback: &RewriteCallback{
@@ -34,6 +56,7 @@ func Rewrite(loadArgs []string, opts *RewriteOpts) *RewriteResult {
 					callback := f(proj)
 					if callback != nil {
 						extraCallbacks = append(extraCallbacks, callback)
+						extraOrigins = append(extraOrigins, originOf(f))
 					}
 				}
 				for _, f := range beforeLoadListeners {
@@ -42,6 +65,9 @@ func Rewrite(loadArgs []string, opts *RewriteOpts) *RewriteResult {
 				for _, callback := range extraCallbacks {
 					callback.BeforeLoad(proj, session)
 				}
+				if !session.Options().SyntaxOnly() {
+					session.Options().SetSyntaxOnly(syntaxOnlyOf(proj, extraCallbacks))
+				}
 			},
 			InitSession: func(proj session.Project, session session.Session) {
 				for _, f := range initSesssionListeners {
@@ -58,29 +84,34 @@ func Rewrite(loadArgs []string, opts *RewriteOpts) *RewriteResult {
 				for _, callback := range extraCallbacks {
 					callback.AfterLoad(proj, session)
 				}
+				if session.Options().CacheKey() == "" {
+					session.Options().SetCacheKey(cacheKeyOf(proj, extraCallbacks))
+				}
 			},
+			// edits are recorded with the listener or rewriter
+			// making them, to report conflicting edits
 			GenOverlay: func(proj session.Project, session session.Session) {
 				for _, f := range genOverlayListeners {
-					f(proj, session)
+					f(proj, session_impl.OriginSession(session, originOf(f)))
 				}
-				for _, callback := range extraCallbacks {
-					callback.GenOverlay(proj, session)
+				for i, callback := range extraCallbacks {
+					callback.GenOverlay(proj, session_impl.OriginSession(session, extraOrigins[i]))
 				}
 			},
 			RewritePackage: func(proj session.Project, pkg inspect.Pkg, session session.Session) {
 				for _, f := range rewritePackageListeners {
-					f(proj, pkg, session)
+					f(proj, pkg, session_impl.OriginSession(session, originOf(f)))
 				}
-				for _, callback := range extraCallbacks {
-					callback.RewritePackage(proj, pkg, session)
+				for i, callback := range extraCallbacks {
+					callback.RewritePackage(proj, pkg, session_impl.OriginSession(session, extraOrigins[i]))
 				}
 			},
 			RewriteFile: func(proj session.Project, file inspect.FileContext, session session.Session) {
 				for _, f := range rewriteFileListeners {
-					f(proj, file, session)
+					f(proj, file, session_impl.OriginSession(session, originOf(f)))
 				}
-				for _, callback := range extraCallbacks {
-					callback.RewriteFile(proj, file, session)
+				for i, callback := range extraCallbacks {
+					callback.RewriteFile(proj, file, session_impl.OriginSession(session, extraOrigins[i]))
 				}
 			},
 			Finish: func(proj session.Project, err error, result *RewriteResult) {
@@ -95,42 +126,83 @@ func Rewrite(loadArgs []string, opts *RewriteOpts) *RewriteResult {
 	})
 }
 
+// cacheKeyOf combines keys of all rewriters, returns
+// empty if any rewriter is not cacheable
+func cacheKeyOf(proj session.Project, rewriters []Rewriter) string {
+	if len(rewritePackageListeners) > 0 || len(rewriteFileListeners) > 0 || len(rewriters) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(rewriters))
+	for _, rewriter := range rewriters {
+		c, ok := rewriter.(Cacheable)
+		if !ok {
+			return ""
+		}
+		key := c.CacheKey(proj)
+		if key == "" {
+			return ""
+		}
+		keys = append(keys, fmt.Sprintf("%T:%s", rewriter, key))
+	}
+	return strings.Join(keys, ";")
+}
+
+// syntaxOnlyOf reports whether no rewriter needs types,
+// listeners may use types anywhere after BeforeLoad
+func syntaxOnlyOf(proj session.Project, rewriters []Rewriter) bool {
+	if len(initSesssionListeners) > 0 || len(afterLoadListeners) > 0 || len(genOverlayListeners) > 0 ||
+		len(rewritePackageListeners) > 0 || len(rewriteFileListeners) > 0 || len(finishListeners) > 0 || len(rewriters) == 0 {
+		return false
+	}
+	for _, rewriter := range rewriters {
+		c, ok := rewriter.(Syntactic)
+		if !ok || !c.SyntaxOnly(proj) {
+			return false
+		}
+	}
+	return true
+}
+
 type RewriteCallbackOpts struct {
 	*RewriteOpts
 	*RewriteCallback
 }
 
 func RewriteNoInterceptors(loadArgs []string, opts *RewriteCallbackOpts) *RewriteResult {
+	res, err := doRewrite(loadArgs, opts)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// TryRewriteNoInterceptors is like RewriteNoInterceptors,
+// but returns error instead of panicking
+func TryRewriteNoInterceptors(loadArgs []string, opts *RewriteCallbackOpts) (*RewriteResult, error) {
 	return doRewrite(loadArgs, opts)
 }
 
 // Rewrite always rewrite same module, though it can be
 // extended to rewrite other modules
-func doRewrite(loadArgs []string, opts *RewriteCallbackOpts) *RewriteResult {
+func doRewrite(loadArgs []string, opts *RewriteCallbackOpts) (res *RewriteResult, err error) {
 	var proj *project
-	var res *RewriteResult
 	defer func() {
-		if opts != nil && opts.RewriteCallback != nil && opts.RewriteCallback.Finish != nil {
-			var e interface{}
-			var err error
-			if e = recover(); e != nil {
-				if a, ok := e.(error); ok {
-					err = a
-				} else {
-					err = fmt.Errorf("%v", e)
-				}
+		if e := recover(); e != nil {
+			if a, ok := e.(error); ok {
+				err = a
+			} else {
+				err = fmt.Errorf("%v", e)
 			}
+			res = nil
+		}
+		if opts != nil && opts.RewriteCallback != nil && opts.RewriteCallback.Finish != nil {
 			opts.RewriteCallback.Finish(proj, err, res)
-			if e != nil {
-				// panic out again
-				panic(e)
-			}
 		}
 	}()
-	proj, res = doRewriteNoCheckPanic(loadArgs, opts)
-	return res
+	proj, res, err = doRewriteNoCheckPanic(loadArgs, opts)
+	return
 }
-func doRewriteNoCheckPanic(loadArgs []string, opts *RewriteCallbackOpts) (proj *project, result *RewriteResult) {
+func doRewriteNoCheckPanic(loadArgs []string, opts *RewriteCallbackOpts) (proj *project, result *RewriteResult, err error) {
 	if opts == nil {
 		opts = &RewriteCallbackOpts{}
 	}
@@ -145,25 +217,11 @@ func doRewriteNoCheckPanic(loadArgs []string, opts *RewriteCallbackOpts) (proj *
 	}
 
 	buildOpts := opts.RewriteOpts.BuildOpts
-	rewriteName := opts.RewriteName
-	if rewriteName == "" {
-		rewriteName = "go-inspect"
-	}
-	rewriteBase := opts.RewriteOpts.RewriteRoot
-	if rewriteBase == "" {
-		rewriteBase = os.TempDir()
-	}
-
-	projectAbsDir, err := util.ToAbsPath(buildOpts.ProjectDir)
+	projectAbsDir, rewriteMetaRoot, err := rewriteMetaRootOf(opts.RewriteOpts)
 	if err != nil {
-		panic(fmt.Errorf("get abs dir err:%v", err))
+		return nil, nil, err
 	}
 
-	dg := md5.New()
-	dg.Write([]byte(projectAbsDir))
-	// rewriteMetaRoot = {rewriteBase}/{rewriteName}/{path_md5}
-	rewriteMetaRoot := rewrite.GetRewriteRoot(filepath.Join(rewriteBase, rewriteName), hex.EncodeToString(dg.Sum(nil)))
-
 	if opts.RewriteOpts.OnRewriteMetaRoot != nil {
 		opts.RewriteOpts.OnRewriteMetaRoot(rewriteMetaRoot)
 	}
@@ -220,11 +278,15 @@ func doRewriteNoCheckPanic(loadArgs []string, opts *RewriteCallbackOpts) (proj *
 		// called first
 		FilterPkgsFn: func(g inspect.Global, session session.Session) func(func(p inspect.Pkg, pkgFlag rewrite.PkgFlag) bool) {
 			pkgFilter := session.Options().GetPackageFilter()
-			mod := g.LoadInfo().MainModule()
+			// more than one in go.work workspace
+			mainMods := make(map[inspect.Module]bool)
+			for _, mod := range g.LoadInfo().MainModules() {
+				mainMods[mod] = true
+			}
 			return func(f func(p inspect.Pkg, pkgFlag rewrite.PkgFlag) bool) {
 				g.RangePkg(func(pkg inspect.Pkg) bool {
-					// rewrite for the same module
-					if pkg.Module() == mod {
+					// rewrite for the main modules
+					if mainMods[pkg.Module()] {
 						f(pkg, rewrite.BitStarterMod)
 					} else {
 						if pkgFilter != nil && pkgFilter(pkg) {
@@ -304,16 +366,25 @@ func doRewriteNoCheckPanic(loadArgs []string, opts *RewriteCallbackOpts) (proj *
 		Debug:     buildOpts.Debug,
 		Output:    buildOpts.Output,
 		SkipBuild: opts.SkipBuild,
+		DryRun:    opts.DryRun,
+		Test:      buildOpts.Test,
+
+		Concurrency: opts.Concurrency,
+		EditPolicy:  opts.EditPolicy,
+
+		BuildStrategy: opts.BuildStrategy,
+		SyntaxOnly:    opts.SyntaxOnly,
 
-		ForTest:    buildOpts.ForTest,
+		ForTest:    buildOpts.ForTest || buildOpts.Test != nil,
 		GoFlags:    buildOpts.GoFlags,
 		BuildFlags: buildOpts.BuildFlags,
+		GCFlags:    buildOpts.GCFlags,
 
 		DisableTrimPath: buildOpts.DisableTrimPath,
 		GoBinary:        buildOpts.GoBinary,
 	})
 	if err != nil {
-		panic(err)
+		return
 	}
 
 	result = &RewriteResult{
@@ -322,6 +393,47 @@ func doRewriteNoCheckPanic(loadArgs []string, opts *RewriteCallbackOpts) (proj *
 	return
 }
 
+// rewriteMetaRootOf returns the absolute project dir
+// and the meta root its rewrite goes into
+func rewriteMetaRootOf(opts *RewriteOpts) (projectAbsDir string, rewriteMetaRoot string, err error) {
+	var projectDir string
+	if opts.BuildOpts != nil {
+		projectDir = opts.BuildOpts.ProjectDir
+	}
+	rewriteName := opts.RewriteName
+	if rewriteName == "" {
+		rewriteName = "go-inspect"
+	}
+	rewriteBase := opts.RewriteRoot
+	if rewriteBase == "" {
+		rewriteBase = os.TempDir()
+	}
+
+	projectAbsDir, err = util.ToAbsPath(projectDir)
+	if err != nil {
+		return "", "", fmt.Errorf("get abs dir err:%v", err)
+	}
+
+	dg := md5.New()
+	dg.Write([]byte(projectAbsDir))
+	// rewriteMetaRoot = {rewriteBase}/{rewriteName}/{path_md5}
+	rewriteMetaRoot = rewrite.GetRewriteRoot(filepath.Join(rewriteBase, rewriteName), hex.EncodeToString(dg.Sum(nil)))
+	return projectAbsDir, rewriteMetaRoot, nil
+}
+
+// LoadSourceMap returns the source map saved by the last
+// rewrite of the project, nil if there is none.
+func LoadSourceMap(opts *RewriteOpts) (*SourceMap, error) {
+	if opts == nil {
+		opts = &RewriteOpts{}
+	}
+	_, rewriteMetaRoot, err := rewriteMetaRootOf(opts)
+	if err != nil {
+		return nil, err
+	}
+	return rewrite.LoadSourceMap(rewriteMetaRoot)
+}
+
 func hasVendorDir(projectAbsDir string) bool {
 	vendorDir := path.Join(projectAbsDir, "vendor")
 	stat, err := os.Stat(vendorDir)
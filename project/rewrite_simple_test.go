@@ -15,7 +15,6 @@ func TestRewriteSimpleProject(t *testing.T) {
 				fmt.Printf("before load\n")
 			},
 			GenOverlay: func(proj session.Project, session session.Session) {
-				fmt.Printf("GenOverlay load\n")
 			},
 		})
 	})
@@ -0,0 +1,40 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/xhd2015/go-inspect/inspect"
+	"github.com/xhd2015/go-inspect/rewrite/session"
+)
+
+// go test -run TestRewriteSyntaxOnly -v ./project
+func TestRewriteSyntaxOnly(t *testing.T) {
+	var files int
+	_, err := TryRewriteNoInterceptors([]string{}, &RewriteCallbackOpts{
+		RewriteOpts: &RewriteOpts{
+			BuildOpts: &BuildOpts{
+				ProjectDir: "./testdata/simple",
+				Force:      true,
+			},
+			SkipBuild:  true,
+			SyntaxOnly: true,
+		},
+		RewriteCallback: &RewriteCallback{
+			BeforeLoad:  func(proj session.Project, session session.Session) {},
+			InitSession: func(proj session.Project, session session.Session) {},
+			AfterLoad:   func(proj session.Project, session session.Session) {},
+			RewriteFile: func(proj session.Project, f inspect.FileContext, session session.Session) {
+				if inspect.HasTypes(f.Pkg()) {
+					t.Errorf("expect %s loaded without types", f.Pkg().Path())
+				}
+				files++
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files == 0 {
+		t.Fatalf("expect files rewritten")
+	}
+}
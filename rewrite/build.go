@@ -19,12 +19,23 @@ func Build(args []string, opts *BuildOptions) (result *BuildResult, err error) {
 	return build(args, opts)
 }
 
+// BuildRewrite rewrites and builds packages,
+// errors are returned as *Error
 func BuildRewrite(args []string, ctrl Controller, rewritter Visitor, opts *BuildRewriteOptions) (*BuildResult, error) {
 	return buildRewrite(args, ctrl, rewritter, opts)
 }
 
 type BuildResult struct {
 	Output string
+
+	// Diff is only set in dry run
+	Diff *RewriteDiff
+
+	// Test is only set in test mode
+	Test *TestResult
+
+	// SourceMap maps rewritten files back to the original
+	SourceMap *SourceMap
 }
 
 func buildRewrite(args []string, ctrl Controller, rewritter Visitor, opts *BuildRewriteOptions) (*BuildResult, error) {
@@ -38,10 +49,16 @@ func buildRewrite(args []string, ctrl Controller, rewritter Visitor, opts *Build
 	}
 	res, err := GenRewrite(args, rewriteRoot, ctrl, rewritter, opts)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	// gc to expire all GenRewrite's stuffs
 	runtime.GC()
+	if opts.DryRun {
+		return &BuildResult{
+			Output: "dry-run",
+			Diff:   res.Diff,
+		}, nil
+	}
 	if opts.SkipBuild {
 		return &BuildResult{
 			Output: "skipped",
@@ -53,14 +70,35 @@ func buildRewrite(args []string, ctrl Controller, rewritter Visitor, opts *Build
 		RebaseRoot:      rewriteRoot,
 		MappedMod:       res.MappedMod,
 		NewGoROOT:       res.UseNewGOROOT,
+		GoWork:          res.GoWork,
+		Overlay:         res.Overlay,
+		ModFile:         res.ModFile,
+		SourceMap:       res.SourceMap,
 		Debug:           opts.Debug,
 		Output:          opts.Output,
 		ForTest:         opts.ForTest,
-		GoFlags:         opts.BuildFlags,
+		GoFlags:         append(append([]string(nil), opts.GoFlags...), opts.BuildFlags...),
+		GCFlags:         opts.GCFlags,
 		DisableTrimPath: opts.DisableTrimPath,
 		GoBinary:        opts.GoBinary,
 	}
-	return build(args, buildOpts)
+	if opts.Test != nil {
+		testRes, err := test(args, buildOpts, opts.Test)
+		if err != nil {
+			return nil, phaseError(PhaseBuild, err)
+		}
+		return &BuildResult{
+			Output:    "test",
+			Test:      testRes,
+			SourceMap: res.SourceMap,
+		}, nil
+	}
+	buildRes, err := build(args, buildOpts)
+	if err != nil {
+		return nil, phaseError(PhaseBuild, err)
+	}
+	buildRes.SourceMap = res.SourceMap
+	return buildRes, nil
 }
 
 func build(args []string, opts *BuildOptions) (result *BuildResult, err error) {
@@ -69,12 +107,7 @@ func build(args []string, opts *BuildOptions) (result *BuildResult, err error) {
 	}
 	verbose := opts.Verbose
 	debug := opts.Debug
-	mappedMod := opts.MappedMod
-	newGoROOT := opts.NewGoROOT
 	forTest := opts.ForTest
-	goFlags := opts.GoFlags
-	disableTrimPath := opts.DisableTrimPath
-	goBinary := opts.GoBinary
 	// project root
 	projectRoot, err := util.ToAbsPath(opts.ProjectRoot)
 	if err != nil {
@@ -105,6 +138,66 @@ func build(args []string, opts *BuildOptions) (result *BuildResult, err error) {
 		}
 	}
 
+	goCmd, err := prepareGoCmd(opts)
+	if err != nil {
+		return
+	}
+	cmdList := goCmd.cmdList
+
+	outputFlags := ""
+	if output != "" {
+		outputFlags = fmt.Sprintf(`-o %s`, sh.Quote(output))
+	}
+	buildCmd := "build"
+	if forTest {
+		buildCmd = "test -c"
+	}
+	cmdList = append(cmdList, fmt.Sprintf(`%s %s %s %s%s %s`, goCmd.goBinary, buildCmd, outputFlags, goCmd.gcflagsQuoted, goCmd.goFlagsSpace, sh.JoinArgs(args)))
+
+	_, _, err = sh.RunBashWithOpts(cmdList, sh.RunBashOptions{
+		Verbose:   verbose,
+		FilterCmd: setTargetEnv,
+	})
+	if err != nil {
+		log.Printf("build %s failed", output)
+		err = opts.SourceMap.translateError(fmt.Errorf("build %s err:%v", output, err), goCmd.workDir)
+		return
+	}
+
+	if verbose {
+		log.Printf("build successful: %s", output)
+	}
+
+	result = &BuildResult{
+		Output: output,
+	}
+	return
+}
+
+// goCmd is the common part of commands running
+// go inside the rewrite root
+type goCmd struct {
+	// cmdList changes dir to the rewritten project
+	// and sets up environment
+	cmdList       []string
+	workDir       string
+	goBinary      string
+	gcflagsQuoted string
+	goFlagsSpace  string
+}
+
+func prepareGoCmd(opts *BuildOptions) (*goCmd, error) {
+	debug := opts.Debug
+	mappedMod := opts.MappedMod
+	newGoROOT := opts.NewGoROOT
+	goFlags := opts.GoFlags
+	disableTrimPath := opts.DisableTrimPath
+	goBinary := opts.GoBinary
+	projectRoot, err := util.ToAbsPath(opts.ProjectRoot)
+	if err != nil {
+		return nil, err
+	}
+
 	var gcflagList []string
 
 	// rebaseRoot dir is errous:
@@ -117,8 +210,7 @@ func build(args []string, opts *BuildOptions) (result *BuildResult, err error) {
 	if opts.RebaseRoot != "" {
 		rebaseRoot, err = util.ToAbsPath(opts.RebaseRoot)
 		if err != nil {
-			err = fmt.Errorf("get absolute path failed:%v %v", opts.RebaseRoot, err)
-			return
+			return nil, fmt.Errorf("get absolute path failed:%v %v", opts.RebaseRoot, err)
 		}
 	}
 	if debug {
@@ -135,7 +227,16 @@ func build(args []string, opts *BuildOptions) (result *BuildResult, err error) {
 		return fmt.Sprintf("%s=>%s", from, to)
 	}
 	workDir := projectRoot
-	if rebaseRoot != "" {
+	if opts.Overlay != "" {
+		// files are served by overlay, the original
+		// project is built, nothing to trim
+		overlayFlags := []string{"-overlay=" + opts.Overlay}
+		if opts.ModFile != "" {
+			overlayFlags = append(overlayFlags, "-modfile="+opts.ModFile)
+		}
+		goFlags = append(overlayFlags, goFlags...)
+		newGoROOT = ""
+	} else if rebaseRoot != "" {
 		workDir = filepath.Join(rebaseRoot, projectRoot)
 		if !disableTrimPath {
 			trimList := []string{fmtTrimPath(workDir, projectRoot)}
@@ -145,9 +246,12 @@ func build(args []string, opts *BuildOptions) (result *BuildResult, err error) {
 			gcflagList = append(gcflagList, fmt.Sprintf("-trimpath=%s", strings.Join(trimList, ";")))
 		}
 	}
-	outputFlags := ""
-	if output != "" {
-		outputFlags = fmt.Sprintf(`-o %s`, sh.Quote(output))
+	for _, value := range opts.GCFlags {
+		args, err := gcflagsArgs(value)
+		if err != nil {
+			return nil, err
+		}
+		gcflagList = append(gcflagList, args...)
 	}
 	var gcflagsQuoted string
 	if len(gcflagList) > 0 {
@@ -172,44 +276,46 @@ func build(args []string, opts *BuildOptions) (result *BuildResult, err error) {
 		goCachePath = filepath.Join(filepath.Dir(rebaseRoot), "go-build-cache")
 	}
 	cmdList = append(cmdList, fmt.Sprintf("export GOCACHE=%s", sh.Quote(goCachePath)))
-	buildCmd := "build"
-	if forTest {
-		buildCmd = "test -c"
+	if opts.GoWork != "" {
+		cmdList = append(cmdList, fmt.Sprintf("export GOWORK=%s", sh.Quote(opts.GoWork)))
 	}
 	goFlagsSpace := ""
 	if len(goFlags) > 0 {
 		goFlagsSpace = " " + sh.Quotes(goFlags...)
 	}
-	goCmd := "go"
-	if goBinary != "" {
-		goCmd = goBinary
+	if goBinary == "" {
+		goBinary = "go"
 	}
-	cmdList = append(cmdList, fmt.Sprintf(`%s %s %s %s%s %s`, goCmd, buildCmd, outputFlags, gcflagsQuoted, goFlagsSpace, sh.JoinArgs(args)))
+	return &goCmd{
+		cmdList:       cmdList,
+		workDir:       workDir,
+		goBinary:      goBinary,
+		gcflagsQuoted: gcflagsQuoted,
+		goFlagsSpace:  goFlagsSpace,
+	}, nil
+}
 
-	_, _, err = sh.RunBashWithOpts(cmdList, sh.RunBashOptions{
-		Verbose: verbose,
-		FilterCmd: func(cmd *exec.Cmd) {
-			cmd.Env = os.Environ()
-			if targetGOOS := os.Getenv("TARGET_GOOS"); targetGOOS != "" {
-				cmd.Env = append(cmd.Env, "GOOS="+targetGOOS)
-			}
-			if targetGOARCH := os.Getenv("TARGET_GOARCH"); targetGOARCH != "" {
-				cmd.Env = append(cmd.Env, "GOARCH="+targetGOARCH)
-			}
-		},
-	})
-	if err != nil {
-		log.Printf("build %s failed", output)
-		err = fmt.Errorf("build %s err:%v", output, err)
-		return
+// gcflagsArgs splits `value` of -gcflags into arguments,
+// with the package pattern all removed
+func gcflagsArgs(value string) ([]string, error) {
+	// a value starting with - has no pattern
+	if value != "" && !strings.HasPrefix(value, "-") {
+		idx := strings.Index(value, "=")
+		if idx < 0 || value[:idx] != "all" {
+			return nil, fmt.Errorf("-gcflags=%s: only pattern all is supported", value)
+		}
+		value = value[idx+1:]
 	}
+	return strings.Fields(value), nil
+}
 
-	if verbose {
-		log.Printf("build successful: %s", output)
+// setTargetEnv allows cross compiling by TARGET_GOOS and TARGET_GOARCH
+func setTargetEnv(cmd *exec.Cmd) {
+	cmd.Env = os.Environ()
+	if targetGOOS := os.Getenv("TARGET_GOOS"); targetGOOS != "" {
+		cmd.Env = append(cmd.Env, "GOOS="+targetGOOS)
 	}
-
-	result = &BuildResult{
-		Output: output,
+	if targetGOARCH := os.Getenv("TARGET_GOARCH"); targetGOARCH != "" {
+		cmd.Env = append(cmd.Env, "GOARCH="+targetGOARCH)
 	}
-	return
 }
@@ -0,0 +1,42 @@
+package rewrite
+
+import (
+	"strings"
+	"testing"
+)
+
+// go test -run TestPrepareGoCmdGCFlags -v ./rewrite
+func TestPrepareGoCmdGCFlags(t *testing.T) {
+	rebaseRoot := t.TempDir()
+	goCmd, err := prepareGoCmd(&BuildOptions{
+		ProjectRoot: "./testdata/simple",
+		RebaseRoot:  rebaseRoot,
+		Debug:       true,
+		GoFlags:     []string{"-ldflags=-s"},
+		GCFlags:     []string{"all=-m", "-d=checkptr"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// user flags must not override -trimpath and debug flags
+	flags := goCmd.gcflagsQuoted
+	if strings.Count(flags, "-gcflags") != 1 || !strings.HasPrefix(flags, "-gcflags=all=") {
+		t.Fatalf("expect a single -gcflags=all=, actual: %s", flags)
+	}
+	for _, flag := range []string{"-N", "-l", "-trimpath=" + rebaseRoot, "-m", "-d=checkptr"} {
+		if !strings.Contains(flags, flag) {
+			t.Fatalf("expect %s in %s", flag, flags)
+		}
+	}
+	if strings.Contains(goCmd.goFlagsSpace, "-gcflags") {
+		t.Fatalf("expect no -gcflags in go flags, actual: %s", goCmd.goFlagsSpace)
+	}
+
+	_, err = prepareGoCmd(&BuildOptions{
+		ProjectRoot: "./testdata/simple",
+		GCFlags:     []string{"example.com/...=-m"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "only pattern all") {
+		t.Fatalf("expect pattern not supported, actual: %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package rewrite
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/xhd2015/go-inspect/inspect"
+	session_pkg "github.com/xhd2015/go-inspect/rewrite/session"
+	"github.com/xhd2015/go-inspect/rewrite/session/session_impl"
+)
+
+// bump this when the format of cache or
+// the way edits are replayed changes
+const rewriteCacheVersion = "v1"
+
+// RewriteCacheDir is the sub directory of RewriteMetaRoot
+// holding cached edits of each package
+const RewriteCacheDir = "rewrite-cache"
+
+// rewriteCacheEntry is the edits recorded while
+// visiting a package, together with the key
+// it was made for.
+type rewriteCacheEntry struct {
+	Key     string                `json:"key"`
+	Pkg     string                `json:"pkg"`
+	Journal *session_impl.Journal `json:"journal"`
+}
+
+type rewriteCache struct {
+	dir string
+	// baseKey covers the rewriter identity, its options
+	// and the go environment
+	baseKey string
+
+	g inspect.Global
+	// package id -> key
+	depKeys map[string]string
+	mutex   sync.Mutex
+}
+
+func newRewriteCache(dir string, g inspect.Global, opts *BuildRewriteOptions) *rewriteCache {
+	h := md5.New()
+	writeKeyParts(h,
+		rewriteCacheVersion,
+		opts.CacheKey,
+		runtime.Version(),
+		g.GOROOT(),
+		fmt.Sprintf("test=%v", opts.ForTest),
+		strings.Join(opts.GoFlags, " "),
+	)
+	return &rewriteCache{
+		dir:     dir,
+		baseKey: hex.EncodeToString(h.Sum(nil)),
+		g:       g,
+		depKeys: make(map[string]string),
+	}
+}
+
+// PkgKey computes key of a package from the content
+// of its files and keys of all its dependencies.
+func (c *rewriteCache) PkgKey(p inspect.Pkg) string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	h := md5.New()
+	writeKeyParts(h, c.baseKey, p.GoPkg().ID)
+	p.RangeFiles(func(i int, f inspect.FileContext) bool {
+		writeKeyParts(h, f.AbsPath(), c.g.FileCode(f.AbsPath()))
+		return true
+	})
+	c.writeImports(h, p.GoPkg())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *rewriteCache) writeImports(h io.Writer, p *packages.Package) {
+	imports := make([]string, 0, len(p.Imports))
+	for imp := range p.Imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	for _, imp := range imports {
+		writeKeyParts(h, imp, c.depKey(p.Imports[imp]))
+	}
+}
+
+func (c *rewriteCache) depKey(p *packages.Package) string {
+	if key, ok := c.depKeys[p.ID]; ok {
+		return key
+	}
+	// mark visiting, import cycle is not
+	// possible but be defensive
+	c.depKeys[p.ID] = ""
+
+	var key string
+	mod := p.Module
+	if mod == nil {
+		// std packages are covered by go version and GOROOT
+		key = p.PkgPath
+	} else if mod.Replace == nil && mod.Version != "" {
+		// module cache is read only
+		key = p.PkgPath + "@" + mod.Version
+	} else {
+		h := md5.New()
+		writeKeyParts(h, p.PkgPath)
+		for _, file := range p.GoFiles {
+			writeKeyParts(h, file, c.g.FileCode(file))
+		}
+		c.writeImports(h, p)
+		key = hex.EncodeToString(h.Sum(nil))
+	}
+	c.depKeys[p.ID] = key
+	return key
+}
+
+func writeKeyParts(h io.Writer, parts ...string) {
+	for _, part := range parts {
+		// length prefixed to avoid ambiguity
+		fmt.Fprintf(h, "%d:%s;", len(part), part)
+	}
+}
+
+func (c *rewriteCache) entryFile(p inspect.Pkg) string {
+	h := md5.Sum([]byte(p.GoPkg().ID))
+	return filepath.Join(c.dir, hex.EncodeToString(h[:])+".json")
+}
+
+// Load returns the cached journal of `p` if
+// its key is still `key`.
+func (c *rewriteCache) Load(p inspect.Pkg, key string) *session_impl.Journal {
+	data, err := ioutil.ReadFile(c.entryFile(p))
+	if err != nil {
+		return nil
+	}
+	var entry rewriteCacheEntry
+	err = json.Unmarshal(data, &entry)
+	if err != nil || entry.Key != key || entry.Journal == nil {
+		return nil
+	}
+	return entry.Journal
+}
+
+// Save records `journal` as the result of visiting `p`
+func (c *rewriteCache) Save(p inspect.Pkg, key string, journal *session_impl.Journal) error {
+	if journal == nil {
+		journal = &session_impl.Journal{}
+	}
+	data, err := json.Marshal(&rewriteCacheEntry{
+		Key:     key,
+		Pkg:     p.GoPkg().ID,
+		Journal: journal,
+	})
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(c.dir, 0755)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.entryFile(p), data, 0644)
+}
+
+// visitAllCached is like visitAll, but packages whose key
+// is unchanged since last run are not visited, instead their
+// recorded edits are replayed on `session`.
+func visitAllCached(pkgs func(func(pkg inspect.Pkg) bool), session session_pkg.Session, visitor Visitor, cache *rewriteCache, force bool, workers int) (hit int, miss int, err error) {
+	var hitCount, missCount int64
+	err = rangePkgsParallel(pkgs, workers, func(p inspect.Pkg) error {
+		key := cache.PkgKey(p)
+		if !force {
+			journal := cache.Load(p, key)
+			if journal != nil {
+				err := session_impl.ReplayJournal(session, journal)
+				if err != nil {
+					return fmt.Errorf("replay cached rewrite of %s: %w", p.Path(), err)
+				}
+				atomic.AddInt64(&hitCount, 1)
+				return nil
+			}
+		}
+		atomic.AddInt64(&missCount, 1)
+		journalSession, journal := session_impl.JournalSession(session)
+		err := visitPkg(p, journalSession, visitor)
+		if err != nil {
+			return err
+		}
+		saveErr := cache.Save(p, key, journal)
+		if saveErr != nil {
+			log.Printf("WARN save rewrite cache of %s: %v", p.Path(), saveErr)
+		}
+		return nil
+	})
+	return int(hitCount), int(missCount), err
+}
@@ -0,0 +1,137 @@
+package rewrite
+
+import (
+	"fmt"
+	"go/ast"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/go-inspect/inspect"
+	"github.com/xhd2015/go-inspect/rewrite/session"
+	"github.com/xhd2015/go-inspect/rewrite/session/session_impl"
+)
+
+type testDirs struct {
+	projectRoot string
+	metaRoot    string
+}
+
+var _ session.SessionDirs = (*testDirs)(nil)
+
+func (c *testDirs) ProjectRoot() string     { return c.projectRoot }
+func (c *testDirs) RewriteMetaRoot() string { return c.metaRoot }
+func (c *testDirs) RewriteMetaSubPath(subPath string) string {
+	return filepath.Join(c.metaRoot, subPath)
+}
+func (c *testDirs) RewriteRoot() string { return filepath.Join(c.metaRoot, "src") }
+func (c *testDirs) RewriteProjectRoot() string {
+	return filepath.Join(c.RewriteRoot(), c.projectRoot)
+}
+func (c *testDirs) RewriteProjectVendorRoot() string {
+	return filepath.Join(c.RewriteRoot(), "vendor")
+}
+
+func withTestDirs(projectDir string, metaRoot string) func(opts *BuildRewriteOptions, sess session.Session) {
+	return func(opts *BuildRewriteOptions, sess session.Session) {
+		absDir, err := filepath.Abs(projectDir)
+		if err != nil {
+			panic(err)
+		}
+		session_impl.OnSessionDirs(sess, &testDirs{projectRoot: absDir, metaRoot: metaRoot})
+	}
+}
+
+// go test -run TestRewriteCache -v ./rewrite
+func TestRewriteCache(t *testing.T) {
+	projectDir := t.TempDir()
+	metaRoot := t.TempDir()
+	for _, file := range []string{"go.mod", "main.go"} {
+		data, err := ioutil.ReadFile(filepath.Join("testdata/simple", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(filepath.Join(projectDir, file), data, 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var visits int
+	var content string
+	rewrite := func() {
+		visits = 0
+		content = ""
+		ctrl := &ControllerFuncs{
+			BeforeLoadFn: withTestDirs(projectDir, metaRoot),
+			GenOverlayFn: func(g inspect.Global, sess session.Session) {
+				sess.Gen(&session.EditCallbackFn{
+					Rewrites: func(f inspect.FileContext, c string) bool {
+						content = c
+						return true
+					},
+				})
+			},
+		}
+		vis := &Visitors{
+			VisitFn: func(n ast.Node, sess session.Session) bool {
+				if file, ok := n.(*ast.File); ok {
+					visits++
+					f := sess.Global().Registry().File(file)
+					edit := sess.FileRewrite(f)
+					fmtPkg := edit.MustImport("fmt", "fmt", "", nil)
+					edit.Insert(file.Name.End(), ";var _ = 1")
+					edit.AddAnaymouseInit(fmt.Sprintf(`;var _ = func() bool { %s.Printf("hello");return true;}`, fmtPkg))
+					return false
+				}
+				return true
+			},
+		}
+		_, err := GenRewrite([]string{"./"}, filepath.Join(metaRoot, "src"), ctrl, vis, &BuildRewriteOptions{
+			ProjectDir: projectDir,
+			CacheKey:   "test",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rewrite()
+	if visits != 1 {
+		t.Fatalf("expect first rewrite visits 1 file, actual: %d", visits)
+	}
+	firstContent := content
+
+	rewrite()
+	if visits != 0 {
+		t.Fatalf("expect cached rewrite visits no file, actual: %d", visits)
+	}
+	if content != firstContent {
+		t.Fatalf("expect cached content:\n%s\nactual:\n%s", firstContent, content)
+	}
+
+	// change the file
+	mainFile := filepath.Join(projectDir, "main.go")
+	data, err := ioutil.ReadFile(mainFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(mainFile, []byte(string(data)+"\nfunc extra() {}\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rewrite()
+	if visits != 1 {
+		t.Fatalf("expect changed file to be visited, actual visits: %d", visits)
+	}
+	if !strings.Contains(content, "func extra() {}") {
+		t.Fatalf("expect rewritten content contains the change, actual:\n%s", content)
+	}
+
+	_, err = os.Stat(filepath.Join(metaRoot, RewriteCacheDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,79 @@
+package rewrite
+
+import (
+	"errors"
+	"go/ast"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/go-inspect/code/edit"
+	"github.com/xhd2015/go-inspect/inspect"
+	"github.com/xhd2015/go-inspect/rewrite/session"
+	"github.com/xhd2015/go-inspect/rewrite/session/session_impl"
+)
+
+// go test -run TestRewriteEditConflict -v ./rewrite
+func TestRewriteEditConflict(t *testing.T) {
+	metaRoot := t.TempDir()
+	rewrite := func(policy edit.Policy, cacheKey string) (string, error) {
+		var content string
+		ctrl := &ControllerFuncs{
+			BeforeLoadFn: withTestDirs("./testdata/simple", metaRoot),
+			GenOverlayFn: func(g inspect.Global, sess session.Session) {
+				sess.Gen(&session.EditCallbackFn{
+					Rewrites: func(f inspect.FileContext, c string) bool {
+						content = c
+						return true
+					},
+				})
+			},
+		}
+		vis := &Visitors{
+			VisitFn: func(n ast.Node, sess session.Session) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok || len(call.Args) != 1 {
+					return true
+				}
+				lit, ok := call.Args[0].(*ast.BasicLit)
+				if !ok || lit.Value != `"world\n"` {
+					return true
+				}
+				f := sess.Global().Registry().FileOf(call)
+				// a plugin wraps the call, another one replaces its argument
+				outer := session_impl.OriginSession(sess, "wrap")
+				outer.FileRewrite(f).Replace(call.Pos(), call.End(), `wrap(fmt.Printf("world\n"))`)
+				inner := session_impl.OriginSession(sess, "mock")
+				inner.FileRewrite(f).Replace(lit.Pos(), lit.End(), `"mocked\n"`)
+				return false
+			},
+		}
+		_, err := GenRewrite([]string{"./"}, filepath.Join(metaRoot, "src"), ctrl, vis, &BuildRewriteOptions{
+			ProjectDir: "./testdata/simple",
+			EditPolicy: policy,
+			CacheKey:   cacheKey,
+		})
+		return content, err
+	}
+
+	_, err := rewrite(edit.PolicyConflict, "")
+	var conflict *edit.ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expect conflict error, actual: %v", err)
+	}
+	if conflict.ConflictOrigin != "wrap" || conflict.Origin != "mock" || !strings.HasSuffix(conflict.File, "main.go") {
+		t.Fatalf("expect main.go: wrap conflicts with mock, actual: %v", conflict)
+	}
+
+	// replayed from cache for the second time
+	for _, cacheKey := range []string{"", "test", "test"} {
+		content, err := rewrite(edit.PolicyCompose, cacheKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := `wrap(fmt.Printf("mocked\n"))`
+		if !strings.Contains(content, expect) {
+			t.Fatalf("cache=%q expect content contains %s, actual:\n%s", cacheKey, expect, content)
+		}
+	}
+}
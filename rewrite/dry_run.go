@@ -0,0 +1,124 @@
+package rewrite
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/xhd2015/go-vendor-pack/writefs/memfs"
+
+	"github.com/xhd2015/go-inspect/code/diff"
+)
+
+type FileDiffKind string
+
+const (
+	// FileModified a file copied from source and then rewritten
+	FileModified FileDiffKind = "modified"
+	// FileGenerated a file without original source, like
+	// outputs of PackageEdit or source imported modules
+	FileGenerated FileDiffKind = "generated"
+)
+
+type FileDiff struct {
+	Kind FileDiffKind
+	// File is the path relative to rewrite root, which is
+	// the same as the original absolute path for copied files
+	File string
+	// Source the original file, empty for generated files
+	Source string
+	// Diff in unified format
+	Diff string
+}
+
+// RewriteDiff is the result of a dry run, it contains
+// every file that differs from its original.
+type RewriteDiff struct {
+	Files []*FileDiff
+}
+
+// String returns diffs of all files
+func (c *RewriteDiff) String() string {
+	if c == nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, f := range c.Files {
+		sb.WriteString(f.Diff)
+	}
+	return sb.String()
+}
+
+// Generated returns files without original source
+func (c *RewriteDiff) Generated() []string {
+	if c == nil {
+		return nil
+	}
+	var files []string
+	for _, f := range c.Files {
+		if f.Kind == FileGenerated {
+			files = append(files, f.File)
+		}
+	}
+	return files
+}
+
+// genRewriteDiff compares every file in `fs` with its source,
+// `destSources` maps file in `fs` to its source file
+func genRewriteDiff(fs *memfs.MemFS, rewriteRoot string, destSources map[string]string) (*RewriteDiff, error) {
+	var files []string
+	fs.TraversePath(func(path string, e memfs.MemFileInfo) bool {
+		if !e.IsDir() {
+			files = append(files, path)
+		}
+		return true
+	})
+	sort.Strings(files)
+
+	res := &RewriteDiff{}
+	for _, file := range files {
+		content, err := readMemFile(fs, file)
+		if err != nil {
+			return nil, err
+		}
+		relFile := strings.TrimPrefix(file, strings.TrimSuffix(rewriteRoot, "/"))
+		src, ok := destSources[file]
+		if !ok {
+			res.Files = append(res.Files, &FileDiff{
+				Kind: FileGenerated,
+				File: relFile,
+				Diff: diff.Unified("/dev/null", relFile, "", content),
+			})
+			continue
+		}
+		srcContent, err := ioutil.ReadFile(src)
+		if err != nil {
+			return nil, fmt.Errorf("read source: %w", err)
+		}
+		d := diff.Unified(src, src, string(srcContent), content)
+		if d == "" {
+			continue
+		}
+		res.Files = append(res.Files, &FileDiff{
+			Kind:   FileModified,
+			File:   relFile,
+			Source: src,
+			Diff:   d,
+		})
+	}
+	return res, nil
+}
+
+func readMemFile(fs *memfs.MemFS, file string) (string, error) {
+	r, err := fs.OpenFileRead(file)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", file, err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,87 @@
+package rewrite
+
+import (
+	"go/ast"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/go-inspect/inspect"
+	"github.com/xhd2015/go-inspect/rewrite/session"
+)
+
+// go test -run TestRewriteDryRun -v ./rewrite
+func TestRewriteDryRun(t *testing.T) {
+	metaRoot := t.TempDir()
+	rewriteRoot := filepath.Join(metaRoot, "src")
+
+	ctrl := &ControllerFuncs{
+		BeforeLoadFn: withTestDirs("./testdata/simple", metaRoot),
+		GenOverlayFn: func(g inspect.Global, sess session.Session) {
+			mainPkg := g.LoadInfo().StarterPkgs()[0]
+			edit := sess.PackageEdit(mainPkg, "extra")
+			edit.AddCode("var extra = 1")
+			sess.Gen(&session.EditCallbackFn{
+				Rewrites: func(f inspect.FileContext, content string) bool {
+					err := sess.SetRewriteFile(f.AbsPath(), content)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return true
+				},
+				Pkg: func(p inspect.Pkg, kind, realName, content string) bool {
+					err := sess.SetRewriteFile(filepath.Join(p.Dir(), realName+".go"), content)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return true
+				},
+			})
+		},
+	}
+	vis := &Visitors{
+		VisitFn: func(n ast.Node, sess session.Session) bool {
+			if file, ok := n.(*ast.File); ok {
+				f := sess.Global().Registry().File(file)
+				sess.FileRewrite(f).Append("\nvar rewritten = true\n")
+				return false
+			}
+			return true
+		},
+	}
+	res, err := GenRewrite([]string{"./"}, rewriteRoot, ctrl, vis, &BuildRewriteOptions{
+		ProjectDir: "./testdata/simple",
+		DryRun:     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Diff == nil || len(res.Diff.Files) != 2 {
+		t.Fatalf("expect 2 files in diff, actual: %s", res.Diff.String())
+	}
+
+	modified := res.Diff.Files[0]
+	generated := res.Diff.Files[1]
+	if modified.Kind != FileModified {
+		modified, generated = generated, modified
+	}
+	if modified.Kind != FileModified || !strings.HasSuffix(modified.Source, "main.go") {
+		t.Fatalf("expect main.go modified, actual: %+v", modified)
+	}
+	if !strings.Contains(modified.Diff, "\n+var rewritten = true\n") {
+		t.Fatalf("expect diff contains added var, actual:\n%s", modified.Diff)
+	}
+	if generated.Kind != FileGenerated || !strings.HasSuffix(generated.File, "extra.go") {
+		t.Fatalf("expect extra.go generated, actual: %+v", generated)
+	}
+	if !strings.HasPrefix(generated.Diff, "--- /dev/null\n") || !strings.Contains(generated.Diff, "\n+var extra = 1\n") {
+		t.Fatalf("expect generated diff, actual:\n%s", generated.Diff)
+	}
+
+	// nothing synced to rewrite root
+	_, err = os.Stat(filepath.Join(metaRoot, "src-md5.json"))
+	if !os.IsNotExist(err) {
+		t.Fatalf("expect no digest written in dry run, actual err: %v", err)
+	}
+}
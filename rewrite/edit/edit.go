@@ -1,6 +1,10 @@
 package edit
 
-import "go/token"
+import (
+	"go/token"
+
+	code_edit "github.com/xhd2015/go-inspect/code/edit"
+)
 
 // Edit represents
 type Edit interface {
@@ -11,3 +15,16 @@ type Edit interface {
 
 	String() string
 }
+
+// Originator is optionally implemented by Edit, edits made through
+// the returned Edit are recorded with `origin`, which is reported
+// when they conflict with others.
+type Originator interface {
+	WithOrigin(origin string) Edit
+}
+
+// Mapper is optionally implemented by Edit, mapping
+// offsets of the edited content back to the original
+type Mapper interface {
+	Mapping() *code_edit.Mapping
+}
@@ -0,0 +1,86 @@
+package rewrite
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+// Phase of the rewrite pipeline
+type Phase string
+
+const (
+	PhaseLoad    Phase = "load"
+	PhaseVisit   Phase = "visit"
+	PhaseOverlay Phase = "overlay"
+	PhaseCopy    Phase = "copy"
+	PhaseGoMod   Phase = "gomod"
+	PhaseBuild   Phase = "build"
+)
+
+// Error is returned by GenRewrite and BuildRewrite, it
+// names the phase failed, and the package or file
+// involved if known.
+// Panics of controllers and visitors are recovered
+// into Error too.
+type Error struct {
+	Phase Phase
+	Pkg   string
+	File  string
+	Err   error
+
+	// Stack is set when recovered from a panic
+	Stack string
+}
+
+func (c *Error) Error() string {
+	var sb strings.Builder
+	sb.WriteString(string(c.Phase))
+	if c.Pkg != "" {
+		sb.WriteString(" ")
+		sb.WriteString(c.Pkg)
+	}
+	if c.File != "" {
+		sb.WriteString(" ")
+		sb.WriteString(c.File)
+	}
+	sb.WriteString(": ")
+	sb.WriteString(c.Err.Error())
+	return sb.String()
+}
+
+func (c *Error) Unwrap() error {
+	return c.Err
+}
+
+// PhaseOf returns the phase of `err` if it is an Error
+func PhaseOf(err error) Phase {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Phase
+	}
+	return ""
+}
+
+// phaseError makes `err` an Error of `phase`,
+// Error returned by inner phase is kept.
+func phaseError(phase Phase, err error) error {
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	return &Error{Phase: phase, Err: err}
+}
+
+// recoverError converts a recovered value into Error
+func recoverError(phase Phase, e interface{}) *Error {
+	if err, ok := e.(*Error); ok {
+		return err
+	}
+	err, ok := e.(error)
+	if !ok {
+		err = fmt.Errorf("%v", e)
+	}
+	return &Error{Phase: phase, Err: err, Stack: string(debug.Stack())}
+}
@@ -0,0 +1,55 @@
+package rewrite
+
+import (
+	"errors"
+	"go/ast"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/go-inspect/rewrite/session"
+)
+
+// go test -run TestGenRewriteVisitError -v ./rewrite
+func TestGenRewriteVisitError(t *testing.T) {
+	metaRoot := t.TempDir()
+	ctrl := &ControllerFuncs{
+		BeforeLoadFn: withTestDirs("./testdata/simple", metaRoot),
+	}
+	visitErr := errors.New("bad file")
+	vis := &Visitors{
+		VisitFn: func(n ast.Node, sess session.Session) bool {
+			if _, ok := n.(*ast.File); ok {
+				panic(visitErr)
+			}
+			return true
+		},
+	}
+	_, err := GenRewrite([]string{"./"}, filepath.Join(metaRoot, "src"), ctrl, vis, &BuildRewriteOptions{
+		ProjectDir: "./testdata/simple",
+	})
+	var rewriteErr *Error
+	if !errors.As(err, &rewriteErr) {
+		t.Fatalf("expect *Error, actual: %v", err)
+	}
+	if rewriteErr.Phase != PhaseVisit || rewriteErr.Pkg == "" || !strings.HasSuffix(rewriteErr.File, "main.go") {
+		t.Fatalf("expect visit error of main.go, actual: %+v", rewriteErr)
+	}
+	if !errors.Is(err, visitErr) || rewriteErr.Stack == "" {
+		t.Fatalf("expect panic recovered with stack, actual: %+v", rewriteErr)
+	}
+}
+
+// go test -run TestGenRewriteLoadError -v ./rewrite
+func TestGenRewriteLoadError(t *testing.T) {
+	metaRoot := t.TempDir()
+	ctrl := &ControllerFuncs{
+		BeforeLoadFn: withTestDirs("./testdata/simple", metaRoot),
+	}
+	_, err := GenRewrite([]string{"./not_exist"}, filepath.Join(metaRoot, "src"), ctrl, &Visitors{}, &BuildRewriteOptions{
+		ProjectDir: "./testdata/simple",
+	})
+	if PhaseOf(err) != PhaseLoad {
+		t.Fatalf("expect load error, actual: %v", err)
+	}
+}
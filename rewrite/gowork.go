@@ -0,0 +1,140 @@
+package rewrite
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/xhd2015/go-vendor-pack/writefs"
+
+	"github.com/xhd2015/go-inspect/inspect/util"
+)
+
+// goWork is the go.work workspace the project is in,
+// all its modules are copied into the rewrite root, so
+// that they keep referring to each other
+type goWork struct {
+	file string
+	work *util.GoWork
+	// absolute dirs of used modules
+	modDirs []string
+}
+
+// loadGoWork returns nil if `projectDir` is not in workspace mode
+func loadGoWork(projectDir string) (*goWork, error) {
+	file, err := util.GetGoWorkFile(projectDir)
+	if err != nil {
+		return nil, fmt.Errorf("detect go.work: %w", err)
+	}
+	if file == "" {
+		return nil, nil
+	}
+	work, err := util.GetGoWork(file)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", file, err)
+	}
+	dir := filepath.Dir(file)
+	modDirs := make([]string, 0, len(work.Use))
+	for _, use := range work.Use {
+		modDirs = append(modDirs, absDirOf(dir, use.DiskPath))
+	}
+	return &goWork{
+		file:    file,
+		work:    work,
+		modDirs: modDirs,
+	}, nil
+}
+
+func absDirOf(base string, dir string) string {
+	if filepath.IsAbs(dir) {
+		return filepath.Clean(dir)
+	}
+	return filepath.Join(base, dir)
+}
+
+// isRelativeModPath tells whether a replacement refers to
+// a local directory relative to the file declaring it
+func isRelativeModPath(p string) bool {
+	return strings.HasPrefix(p, "./") || strings.HasPrefix(p, "../")
+}
+
+// rewrittenFile is the go.work inside rewrite root
+func (c *goWork) rewrittenFile(rewriteRoot string) string {
+	return path.Join(rewriteRoot, cleanGoFsPath(c.file))
+}
+
+// gen writes go.work into rewrite root, modules are used from
+// the rewrite root, relative replacements are made absolute.
+// It returns the rewritten go.work.
+func (c *goWork) gen(fs writefs.FS, rewriteRoot string) (string, error) {
+	dir := filepath.Dir(c.file)
+	isWorkMod := make(map[string]bool, len(c.modDirs))
+	for _, modDir := range c.modDirs {
+		isWorkMod[modDir] = true
+	}
+
+	var b strings.Builder
+	if c.work.Go != "" {
+		fmt.Fprintf(&b, "go %s\n", c.work.Go)
+	}
+	if c.work.Toolchain != "" {
+		fmt.Fprintf(&b, "\ntoolchain %s\n", c.work.Toolchain)
+	}
+	if len(c.modDirs) > 0 {
+		b.WriteString("\nuse (\n")
+		for _, modDir := range c.modDirs {
+			fmt.Fprintf(&b, "\t%s\n", quoteModPath(path.Join(rewriteRoot, cleanGoFsPath(modDir))))
+		}
+		b.WriteString(")\n")
+	}
+	if len(c.work.Replace) > 0 {
+		b.WriteString("\nreplace (\n")
+		for _, rp := range c.work.Replace {
+			newPath := rp.New.Path
+			if isRelativeModPath(newPath) {
+				newPath = absDirOf(dir, newPath)
+				if isWorkMod[newPath] {
+					newPath = path.Join(rewriteRoot, cleanGoFsPath(newPath))
+				}
+			}
+			fmt.Fprintf(&b, "\t%s => %s\n", modVersion(quoteModPath(rp.Old.Path), rp.Old.Version), modVersion(quoteModPath(newPath), rp.New.Version))
+		}
+		b.WriteString(")\n")
+	}
+
+	goWorkFile := c.rewrittenFile(rewriteRoot)
+	err := writefs.WriteFile(fs, goWorkFile, []byte(b.String()))
+	if err != nil {
+		return "", fmt.Errorf("write go.work: %w", err)
+	}
+	// go.work.sum is not inside any module
+	sum, err := ioutil.ReadFile(c.file + ".sum")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return goWorkFile, nil
+		}
+		return "", err
+	}
+	err = writefs.WriteFile(fs, goWorkFile+".sum", sum)
+	if err != nil {
+		return "", fmt.Errorf("write go.work.sum: %w", err)
+	}
+	return goWorkFile, nil
+}
+
+func modVersion(modPath string, version string) string {
+	if version == "" {
+		return modPath
+	}
+	return modPath + " " + version
+}
+
+func quoteModPath(p string) string {
+	if strings.ContainsAny(p, " \t\"'`") {
+		return fmt.Sprintf("%q", p)
+	}
+	return p
+}
@@ -0,0 +1,60 @@
+package rewrite
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// go test -run TestRewriteGoWork -v ./rewrite
+func TestRewriteGoWork(t *testing.T) {
+	// -mod=mod is not allowed in workspace mode
+	t.Setenv("GOFLAGS", "")
+	t.Setenv("GOWORK", "")
+
+	metaRoot := t.TempDir()
+	rewriteRoot := filepath.Join(metaRoot, "src")
+	ctrl := &ControllerFuncs{
+		BeforeLoadFn: withTestDirs("./testdata/workspace/a", metaRoot),
+	}
+	res, err := GenRewrite([]string{"./"}, rewriteRoot, ctrl, &Visitors{}, &BuildRewriteOptions{
+		ProjectDir: "./testdata/workspace/a",
+		DryRun:     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	workDir, err := filepath.Abs("./testdata/workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GoWork != filepath.Join(rewriteRoot, workDir, "go.work") {
+		t.Fatalf("bad go.work: %s", res.GoWork)
+	}
+	if res.MappedMod[filepath.Join(workDir, "b")] == "" {
+		t.Fatalf("expect module b mapped, actual: %v", res.MappedMod)
+	}
+
+	var goWork, goMod *FileDiff
+	for _, f := range res.Diff.Files {
+		switch {
+		case strings.HasSuffix(f.File, "/workspace/go.work"):
+			goWork = f
+		case strings.HasSuffix(f.File, "/workspace/a/go.mod"):
+			goMod = f
+		}
+	}
+	if goWork == nil || goWork.Kind != FileGenerated {
+		t.Fatalf("expect go.work generated, actual: %v", res.Diff.String())
+	}
+	for _, mod := range []string{"a", "b"} {
+		use := "\n+\t" + filepath.Join(rewriteRoot, workDir, mod) + "\n"
+		if !strings.Contains(goWork.Diff, use) {
+			t.Fatalf("expect go.work use %s, actual:\n%s", mod, goWork.Diff)
+		}
+	}
+	replace := "example.com/b => " + filepath.Join(rewriteRoot, workDir, "b")
+	if goMod == nil || !strings.Contains(goMod.Diff, replace) {
+		t.Fatalf("expect go.mod of a replaced with rewritten b, actual: %v", res.Diff.String())
+	}
+}
@@ -0,0 +1,129 @@
+package rewrite
+
+import (
+	"errors"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"path/filepath"
+	"testing"
+
+	"github.com/xhd2015/go-inspect/inspect"
+	"github.com/xhd2015/go-inspect/rewrite/session"
+)
+
+// go test -run TestNodeEdit -v ./rewrite
+func TestNodeEdit(t *testing.T) {
+	metaRoot := t.TempDir()
+	var content string
+	ctrl := &ControllerFuncs{
+		BeforeLoadFn: withTestDirs("./testdata/node_edit", metaRoot),
+		GenOverlayFn: func(g inspect.Global, sess session.Session) {
+			sess.Gen(&session.EditCallbackFn{
+				Rewrites: func(f inspect.FileContext, c string) bool {
+					content = c
+					return true
+				},
+			})
+		},
+	}
+	vis := &Visitors{
+		VisitFn: func(n ast.Node, sess session.Session) bool {
+			file, ok := n.(*ast.File)
+			if !ok {
+				return true
+			}
+			f := sess.Global().Registry().File(file)
+			edit := session.NewNodeEdit(f, sess.FileRewrite(f))
+
+			mainFn := file.Decls[1].(*ast.FuncDecl)
+			addFn := file.Decls[2].(*ast.FuncDecl)
+			call := mainFn.Body.List[0].(*ast.ExprStmt).X.(*ast.CallExpr).Args[0].(*ast.CallExpr)
+			ret := addFn.Body.List[0].(*ast.ReturnStmt)
+
+			// add(a, b, c), add(1, 2, 3)
+			err := edit.InsertNodeAfter(addFn.Type.Params.List[1], &ast.Field{
+				Names: []*ast.Ident{ast.NewIdent("c")},
+				Type:  ast.NewIdent("int"),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = edit.InsertNodeAfter(call.Args[1], &ast.BasicLit{Kind: token.INT, Value: "3"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// wrap the original body, with its comment, in a closure
+			err = edit.ReplaceNode(addFn.Body, &ast.BlockStmt{List: []ast.Stmt{
+				&ast.ExprStmt{X: &ast.CallExpr{
+					Fun:  &ast.SelectorExpr{X: ast.NewIdent("fmt"), Sel: ast.NewIdent("Println")},
+					Args: []ast.Expr{ast.NewIdent("c")},
+				}},
+				&ast.ReturnStmt{Results: []ast.Expr{&ast.CallExpr{
+					Fun: &ast.FuncLit{
+						Type: &ast.FuncType{Params: &ast.FieldList{}, Results: addFn.Type.Results},
+						Body: addFn.Body,
+					},
+				}}},
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// text edits at the boundary are merged
+			edit.Insert(addFn.Body.Pos(), "/* traced */ ")
+
+			// edits inside the replaced body conflict
+			err = edit.ReplaceNode(ret.Results[0], ast.NewIdent("a"))
+			var conflict *session.ConflictError
+			if !errors.As(err, &conflict) {
+				t.Fatalf("expect conflict error, actual: %v", err)
+			}
+			offset := func(pos token.Pos) int {
+				return sess.Global().FileSet().Position(pos).Offset
+			}
+			if conflict.File != f.AbsPath() || conflict.Start != offset(ret.Results[0].Pos()) ||
+				conflict.ConflictStart != offset(addFn.Body.Pos()) || conflict.ConflictEnd != offset(addFn.Body.End()) {
+				t.Fatalf("expect conflict of return value with function body, actual: %v", conflict)
+			}
+			func() {
+				defer func() {
+					if _, ok := recover().(*session.ConflictError); !ok {
+						t.Fatalf("expect text edit panic with conflict error")
+					}
+				}()
+				edit.Delete(ret.Pos(), ret.End())
+			}()
+			return false
+		},
+	}
+	_, err := GenRewrite([]string{"./"}, filepath.Join(metaRoot, "src"), ctrl, vis, &BuildRewriteOptions{
+		ProjectDir: "./testdata/node_edit",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	formatted, err := format.Source([]byte(content))
+	if err != nil {
+		t.Fatalf("bad rewritten code: %v\n%s", err, content)
+	}
+	expect := `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println(add(1, 2, 3))
+}
+
+func add(a int, b int, c int) int /* traced */ {
+	fmt.Println(c)
+	return func() int {
+		// sum of a and b
+		return a + b
+	}()
+}
+`
+	if string(formatted) != expect {
+		t.Fatalf("expect:\n%s\nactual:\n%s", expect, content)
+	}
+}
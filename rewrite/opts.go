@@ -1,6 +1,9 @@
 package rewrite
 
-import "github.com/xhd2015/go-inspect/inspect"
+import (
+	"github.com/xhd2015/go-inspect/code/edit"
+	"github.com/xhd2015/go-inspect/inspect"
+)
 
 type PkgFilterOptions struct {
 	OnlyPackages map[string]bool
@@ -14,6 +17,25 @@ type GenRewriteResult struct {
 	// to be used as -trim when building
 	MappedMod    map[string]string
 	UseNewGOROOT string
+
+	// GoWork is the rewritten go.work, only set in workspace mode
+	GoWork string
+
+	// BuildStrategy is the strategy actually used
+	BuildStrategy BuildStrategy
+	// Overlay is the file passed to -overlay, only
+	// set with BuildStrategyOverlay
+	Overlay string
+	// ModFile is the rewritten go.mod passed to -modfile,
+	// only set with BuildStrategyOverlay when go.mod is changed
+	ModFile string
+
+	// Diff is only set in dry run
+	Diff *RewriteDiff
+
+	// SourceMap maps rewritten files back to the original,
+	// it is saved as SourceMapFile in the meta root
+	SourceMap *SourceMap
 }
 
 // TODO: merge these 4 options
@@ -28,6 +50,13 @@ type BuildOpts struct {
 	ForTest    bool
 	GoFlags    []string // passed to go load
 	BuildFlags []string // passed to go build
+	// GCFlags are values of -gcflags, merged with
+	// those added by go-inspect, see BuildOptions
+	GCFlags []string
+
+	// Test if set, packages are tested by go test in the
+	// rewrite root instead of being built, implies ForTest
+	Test *TestOptions
 
 	DisableTrimPath bool
 	GoBinary        string
@@ -49,6 +78,29 @@ type RewriteOpts struct {
 	PreCode map[string]string
 
 	SkipBuild bool
+
+	// DryRun runs rewrite up to GenOverlay, then reports
+	// the diff instead of writing files and building
+	DryRun bool
+
+	// Concurrency is the number of packages rewritten in
+	// parallel, default 1. When greater than 1, RewritePackage
+	// and RewriteFile may be called concurrently for
+	// different packages.
+	Concurrency int
+
+	// EditPolicy decides how overlapping edits of rewriters
+	// are applied, by default they fail the rewrite.
+	EditPolicy edit.Policy
+
+	// BuildStrategy decides how rewritten files are
+	// built, default BuildStrategyCopy
+	BuildStrategy BuildStrategy
+
+	// SyntaxOnly loads packages without types, see
+	// BuildRewriteOptions.SyntaxOnly. It is also enabled
+	// by project.TryRewrite when no rewriter needs types.
+	SyntaxOnly bool
 }
 
 type BuildOptions struct {
@@ -59,10 +111,25 @@ type BuildOptions struct {
 	Output      string
 	ForTest     bool
 	GoFlags     []string
+	// GCFlags are values of -gcflags, e.g. all=-m, they are merged
+	// into the single -gcflags=all=... with -trimpath and those of
+	// Debug, since the last -gcflags wins. Patterns other than all
+	// are not supported, a value without pattern applies to all.
+	GCFlags []string
 	// extra trim path map to be applied
 	// cleanedModOrigAbsDir - modOrigAbsDir
 	MappedMod map[string]string
 	NewGoROOT string
+	// GoWork if not empty, set as GOWORK
+	GoWork string
+	// Overlay if not empty, the original project is built
+	// with -overlay, instead of the one in RebaseRoot
+	Overlay string
+	// ModFile if not empty, passed as -modfile with Overlay
+	ModFile string
+	// SourceMap if not nil, positions in build errors and
+	// test outputs are translated to the original files
+	SourceMap *SourceMap
 
 	DisableTrimPath bool
 	GoBinary        string
@@ -82,16 +149,54 @@ type BuildRewriteOptions struct {
 
 	Force bool // force indicates no cache
 
+	// CacheKey identifies the rewriter and its options.
+	// When not empty, edits made while visiting each package
+	// are cached under {RewriteMetaRoot}/rewrite-cache, and
+	// unchanged packages replay them instead of being visited again.
+	// The visitor must only depend on the package being visited,
+	// its dependencies and what CacheKey covers.
+	CacheKey string
+
+	// Concurrency is the number of packages visited in
+	// parallel, default 1. See Visitor for what may be
+	// called concurrently.
+	Concurrency int
+
+	// EditPolicy decides how overlapping edits of
+	// FileRewrite and FileEdit are applied
+	EditPolicy edit.Policy
+
+	// BuildStrategy decides how rewritten files
+	// are built, default BuildStrategyCopy
+	BuildStrategy BuildStrategy
+
+	// SyntaxOnly loads packages with load.LoadModeSyntax, skipping
+	// the type-check of all packages. Type-dependent APIs of inspect
+	// fail with inspect.ErrTypesNotAvailable. It can be changed
+	// in BeforeLoad by session.Options().SetSyntaxOnly.
+	SyntaxOnly bool
+
 	// for load & build
 	ForTest    bool
 	GoFlags    []string // passed to load packages,go build
 	BuildFlags []string // flags only passed to go build, not loading
+	// GCFlags are values of -gcflags, see BuildOptions
+	GCFlags []string
 
 	// for build
 	Debug     bool
 	Output    string
 	SkipBuild bool
 
+	// Test runs go test instead of go build, the result
+	// is BuildResult.Test. ForTest should also be set.
+	Test *TestOptions
+
+	// DryRun stops after GenOverlay, rewritten files are
+	// compared with their originals into GenRewriteResult.Diff,
+	// nothing is written to the rewrite root or built
+	DryRun bool
+
 	DisableTrimPath bool
 	GoBinary        string
 }
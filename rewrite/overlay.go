@@ -0,0 +1,199 @@
+package rewrite
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/xhd2015/go-vendor-pack/go_info"
+	"github.com/xhd2015/go-vendor-pack/writefs"
+	"github.com/xhd2015/go-vendor-pack/writefs/memfs"
+
+	"github.com/xhd2015/go-inspect/inspect"
+)
+
+// BuildStrategy decides how rewritten files are served to go build
+type BuildStrategy string
+
+const (
+	// BuildStrategyCopy copies modules containing rewritten packages
+	// into the rewrite root, and builds there. It is the default.
+	BuildStrategyCopy BuildStrategy = "copy"
+	// BuildStrategyOverlay writes only rewritten and generated files
+	// into the rewrite root, and builds the original project with
+	// go build -overlay. It falls back to BuildStrategyCopy where
+	// overlay cannot work, see GenRewriteResult.BuildStrategy.
+	BuildStrategyOverlay BuildStrategy = "overlay"
+)
+
+// OverlayFile is the file under RewriteMetaRoot passed
+// to go build -overlay
+const OverlayFile = "overlay.json"
+
+// overlayJSON is the format of go build -overlay
+type overlayJSON struct {
+	Replace map[string]string
+}
+
+// overlayUnsupported returns why overlay cannot be used, empty if it can.
+// sourceImports is true if modules are imported from source, which
+// edits go.mod, and vendor/modules.txt in vendor mode.
+func overlayUnsupported(hasStd bool, goWork bool, vendor bool, sourceImports bool) (string, error) {
+	goVersion, err := go_info.GetGoVersionCached()
+	if err != nil {
+		return "", err
+	}
+	if goVersion.Major == 1 && goVersion.Minor < 16 {
+		return fmt.Sprintf("-overlay requires go1.16, actual: go%d.%d", goVersion.Major, goVersion.Minor), nil
+	}
+	// before go1.20, std packages are installed
+	// as archives in GOROOT/pkg
+	if hasStd && goVersion.Major == 1 && goVersion.Minor < 20 {
+		return fmt.Sprintf("overlay of GOROOT requires go1.20, actual: go%d.%d", goVersion.Major, goVersion.Minor), nil
+	}
+	if sourceImports && vendor {
+		return "source imports edit vendor/modules.txt", nil
+	}
+	if sourceImports && goWork {
+		return "source imports edit go.mod, but -modfile cannot be used with go.work", nil
+	}
+	return "", nil
+}
+
+// overlayModFiles are read into `fs` so that source imports can edit them,
+// the edited go.mod is passed by -modfile, which reads go.sum beside it
+var overlayModFiles = []string{"go.mod", "go.sum"}
+
+// loadOverlayModFiles reads go.mod and go.sum of the main module in `modDir`
+// into `fs`, it returns the source of each file read.
+func loadOverlayModFiles(fs writefs.FS, rewriteRoot string, modDir string) (destSources map[string]string, err error) {
+	destSources = make(map[string]string, len(overlayModFiles))
+	for _, name := range overlayModFiles {
+		src := filepath.Join(modDir, name)
+		data, err := ioutil.ReadFile(src)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		dest := path.Join(rewriteRoot, cleanGoFsPath(src))
+		err = fs.MkdirAll(path.Dir(dest), 0755)
+		if err != nil {
+			return nil, err
+		}
+		err = writefs.WriteFile(fs, dest, data)
+		if err != nil {
+			return nil, err
+		}
+		destSources[dest] = src
+	}
+	return destSources, nil
+}
+
+// overlayDirs returns original directories by their
+// paths in the rewrite root, files rewritten or generated
+// under these directories are served by overlay
+func overlayDirs(g inspect.Global, extraDirs []string) map[string]string {
+	dirs := make(map[string]string)
+	add := func(dir string) {
+		if dir != "" {
+			dirs[cleanGoFsPath(dir)] = dir
+		}
+	}
+	g.RangePkg(func(pkg inspect.Pkg) bool {
+		add(pkg.Dir())
+		if mod := pkg.Module(); mod != nil {
+			add(mod.Dir())
+		}
+		return true
+	})
+	for _, dir := range extraDirs {
+		add(dir)
+	}
+	return dirs
+}
+
+// genOverlay maps files of `fs` to their original paths by `dirs`. Files outside
+// `dirs` are written as is, e.g. modules imported from source. Sources of
+// files in `destSources` are added for files that exist.
+func genOverlay(fs *memfs.MemFS, rewriteRoot string, dirs map[string]string, destSources map[string]string) (*overlayJSON, error) {
+	root := strings.TrimSuffix(rewriteRoot, "/")
+	overlay := &overlayJSON{Replace: make(map[string]string)}
+	var err error
+	fs.TraversePath(func(file string, e memfs.MemFileInfo) bool {
+		if e.IsDir() || !strings.HasPrefix(file, root+"/") {
+			return true
+		}
+		if destSources[file] != "" {
+			// go.mod and go.sum
+			return true
+		}
+		rel := strings.TrimPrefix(file, root)
+		for dir := path.Dir(rel); dir != "/" && dir != "."; dir = path.Dir(dir) {
+			origDir, ok := dirs[dir]
+			if !ok {
+				continue
+			}
+			orig := origDir + rel[len(dir):]
+			overlay.Replace[orig] = file
+
+			_, statErr := os.Stat(orig)
+			if statErr == nil {
+				destSources[file] = orig
+			} else if !os.IsNotExist(statErr) {
+				err = statErr
+				return false
+			}
+			break
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return overlay, nil
+}
+
+func (c *overlayJSON) files() []string {
+	files := make([]string, 0, len(c.Replace))
+	for file := range c.Replace {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func (c *overlayJSON) save(file string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// overlayModFile returns the rewritten go.mod of `modDir`
+// if it differs from the original, empty otherwise
+func overlayModFile(rewriteRoot string, modDir string) (string, error) {
+	for _, name := range overlayModFiles {
+		src := filepath.Join(modDir, name)
+		dest := path.Join(rewriteRoot, cleanGoFsPath(src))
+		srcData, err := ioutil.ReadFile(src)
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		destData, err := ioutil.ReadFile(dest)
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		if string(srcData) != string(destData) {
+			return path.Join(rewriteRoot, cleanGoFsPath(filepath.Join(modDir, "go.mod"))), nil
+		}
+	}
+	return "", nil
+}
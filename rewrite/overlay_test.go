@@ -0,0 +1,108 @@
+package rewrite
+
+import (
+	"encoding/json"
+	"go/ast"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/xhd2015/go-inspect/inspect"
+	"github.com/xhd2015/go-inspect/rewrite/session"
+)
+
+// go test -run TestBuildRewriteOverlay -v ./rewrite
+func TestBuildRewriteOverlay(t *testing.T) {
+	metaRoot := t.TempDir()
+	rewriteRoot := filepath.Join(metaRoot, "src")
+	output := filepath.Join(t.TempDir(), "overlay.bin")
+
+	ctrl := &ControllerFuncs{
+		BeforeLoadFn: withTestDirs("./testdata/simple", metaRoot),
+		GenOverlayFn: func(g inspect.Global, sess session.Session) {
+			mainPkg := g.LoadInfo().StarterPkgs()[0]
+			sess.PackageEdit(mainPkg, "extra").AddCode("var extra = 1")
+			sess.Gen(&session.EditCallbackFn{
+				Rewrites: func(f inspect.FileContext, content string) bool {
+					err := sess.SetRewriteFile(f.AbsPath(), content)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return true
+				},
+				Pkg: func(p inspect.Pkg, kind, realName, content string) bool {
+					err := sess.SetRewriteFile(filepath.Join(p.Dir(), realName+".go"), content)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return true
+				},
+			})
+		},
+	}
+	vis := &Visitors{
+		VisitFn: func(n ast.Node, sess session.Session) bool {
+			fn, ok := n.(*ast.FuncDecl)
+			if !ok || fn.Name.Name != "main" {
+				return true
+			}
+			f := sess.Global().Registry().FileOf(fn)
+			sess.FileRewrite(f).Replace(fn.Body.Pos(), fn.Body.End(), `{ fmt.Printf("overlay %d\n", extra) }`)
+			return false
+		},
+	}
+	_, err := BuildRewrite([]string{"./"}, ctrl, vis, &BuildRewriteOptions{
+		ProjectDir:    "./testdata/simple",
+		RebaseRoot:    rewriteRoot,
+		Output:        output,
+		BuildStrategy: BuildStrategyOverlay,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(output).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "overlay 1\n" {
+		t.Fatalf("expect output: overlay 1, actual: %q", string(out))
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(metaRoot, OverlayFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var overlay overlayJSON
+	err = json.Unmarshal(data, &overlay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the rewritten main.go, and the generated extra.go
+	if len(overlay.Replace) != 2 {
+		t.Fatalf("expect 2 files in overlay, actual: %v", overlay.Replace)
+	}
+	mainFile, err := filepath.Abs("testdata/simple/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newMainFile := overlay.Replace[mainFile]
+	if newMainFile != filepath.Join(rewriteRoot, mainFile) {
+		t.Fatalf("expect %s served from rewrite root, actual: %v", mainFile, overlay.Replace)
+	}
+	// nothing else is copied, except go.mod for source imports
+	var files []string
+	err = filepath.Walk(rewriteRoot, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			files = append(files, filepath.Base(path))
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("expect main.go, go.mod and the generated file in rewrite root, actual: %v", files)
+	}
+}
@@ -0,0 +1,198 @@
+package rewrite
+
+import (
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xhd2015/go-inspect/inspect"
+	"github.com/xhd2015/go-inspect/rewrite/session"
+)
+
+// go test -run TestGenRewriteParallel -v ./rewrite
+func TestGenRewriteParallel(t *testing.T) {
+	const mainPkgPath = "github.com/xhd2015/go-inspect/rewrite/testdata/parallel"
+	metaRoot := t.TempDir()
+	rewrite := func(concurrency int, cacheKey string) map[string]string {
+		contents := make(map[string]string)
+		ctrl := &ControllerFuncs{
+			BeforeLoadFn: withTestDirs("./testdata/parallel", metaRoot),
+			GenOverlayFn: func(g inspect.Global, sess session.Session) {
+				sess.Gen(&session.EditCallbackFn{
+					Rewrites: func(f inspect.FileContext, content string) bool {
+						contents[f.Pkg().Path()] = content
+						return true
+					},
+					Pkg: func(p inspect.Pkg, kind, realName, content string) bool {
+						contents[kind] = content
+						return true
+					},
+				})
+			},
+		}
+		vis := &Visitors{
+			VisitFn: func(n ast.Node, sess session.Session) bool {
+				file, ok := n.(*ast.File)
+				if !ok {
+					return true
+				}
+				f := sess.Global().Registry().File(file)
+				edit := sess.FileRewrite(f)
+				fmtPkg := edit.MustImport("fmt", "fmt", "", nil)
+				edit.AddAnaymouseInit(fmt.Sprintf(";var _ = func() bool { %s.Printf(%q);return true;}()", fmtPkg, f.Pkg().Path()))
+
+				// all packages share the same edit
+				mainPkg := sess.Global().GetPkg(mainPkgPath)
+				sess.PackageEdit(mainPkg, "all").AddCode(fmt.Sprintf("var _ = %q", f.Pkg().Path()))
+				return false
+			},
+		}
+		_, err := GenRewrite([]string{"./..."}, filepath.Join(metaRoot, "src"), ctrl, vis, &BuildRewriteOptions{
+			ProjectDir:  "./testdata/parallel",
+			CacheKey:    cacheKey,
+			Concurrency: concurrency,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return contents
+	}
+	sortedLines := func(s string) string {
+		lines := strings.Split(s, "\n")
+		sort.Strings(lines)
+		return strings.Join(lines, "\n")
+	}
+
+	serial := rewrite(1, "")
+	if len(serial) != 6 {
+		t.Fatalf("expect 5 files and 1 package edit, actual: %d", len(serial))
+	}
+	for _, cacheKey := range []string{"", "test", "test"} {
+		parallel := rewrite(4, cacheKey)
+		if len(parallel) != len(serial) {
+			t.Fatalf("cache=%q expect %d contents, actual: %d", cacheKey, len(serial), len(parallel))
+		}
+		for name, content := range serial {
+			if name == "all" {
+				// order of packages is not determined
+				if sortedLines(parallel[name]) != sortedLines(content) {
+					t.Fatalf("cache=%q expect package edit:\n%s\nactual:\n%s", cacheKey, content, parallel[name])
+				}
+				continue
+			}
+			if parallel[name] != content {
+				t.Fatalf("cache=%q expect %s:\n%s\nactual:\n%s", cacheKey, name, content, parallel[name])
+			}
+		}
+	}
+}
+
+// go test -race -run TestGenRewriteParallelRegistry -v ./rewrite
+func TestGenRewriteParallelRegistry(t *testing.T) {
+	const mainPkgPath = "github.com/xhd2015/go-inspect/rewrite/testdata/parallel"
+	metaRoot := t.TempDir()
+	const concurrency = 4
+	// func decl -> wrapper first seen, same for all visitors
+	var funcs sync.Map
+	var funcTypes sync.Map
+	// the first visitors wait for each other, so that
+	// they look up wrappers at the same time
+	var arrived int32
+	start := make(chan struct{})
+	vis := &Visitors{
+		VisitFn: func(n ast.Node, sess session.Session) bool {
+			if _, ok := n.(*ast.File); !ok {
+				return true
+			}
+			switch atomic.AddInt32(&arrived, 1) {
+			case concurrency:
+				close(start)
+			default:
+				select {
+				case <-start:
+				case <-time.After(5 * time.Second):
+				}
+			}
+			// like the trace and mock rewriters, but wrappers of all
+			// packages are looked up, so that visitors overlap
+			g := sess.Global()
+			g.RangePkg(func(pkg inspect.Pkg) bool {
+				if !strings.HasPrefix(pkg.Path(), mainPkgPath) {
+					return true
+				}
+				pkg.RangeFiles(func(i int, f inspect.FileContext) bool {
+					for _, decl := range f.AST().Decls {
+						fnDecl, ok := decl.(*ast.FuncDecl)
+						if !ok {
+							continue
+						}
+						fn := g.Registry().FuncDecl(fnDecl)
+						if prev, loaded := funcs.LoadOrStore(fnDecl, fn); loaded && prev != fn {
+							t.Errorf("expect one wrapper of %s", fn.Name())
+						}
+						fnType := g.Registry().FuncType(fnDecl.Type)
+						if prev, loaded := funcTypes.LoadOrStore(fnDecl.Type, fnType); loaded && prev != fnType {
+							t.Errorf("expect one wrapper of type of %s", fn.Name())
+						}
+					}
+					return true
+				})
+				return true
+			})
+			return false
+		},
+	}
+	_, err := GenRewrite([]string{"./..."}, filepath.Join(metaRoot, "src"), &ControllerFuncs{
+		BeforeLoadFn: withTestDirs("./testdata/parallel", metaRoot),
+	}, vis, &BuildRewriteOptions{
+		ProjectDir:  "./testdata/parallel",
+		Concurrency: concurrency,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	funcs.Range(func(key, value interface{}) bool {
+		count++
+		return true
+	})
+	// main and N of p1 to p4
+	if count != 5 {
+		t.Fatalf("expect 5 funcs, actual: %d", count)
+	}
+}
+
+// go test -run TestRangeParallelError -v ./rewrite
+func TestRangeParallelError(t *testing.T) {
+	n := 20
+	var visited [20]int32
+	err := rangeParallel(n, 4, func(i int) error {
+		atomic.StoreInt32(&visited[i], 1)
+		if i == 3 || i == 5 {
+			return fmt.Errorf("index %d", i)
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	if err == nil || err.Error() != "index 3" {
+		t.Fatalf("expect error of index 3, actual: %v", err)
+	}
+	if atomic.LoadInt32(&visited[n-1]) != 0 {
+		t.Fatalf("expect indexes after the error skipped")
+	}
+
+	var count int32
+	err = rangeParallel(n, 4, func(i int) error {
+		atomic.AddInt32(&count, 1)
+		return nil
+	})
+	if err != nil || count != int32(n) {
+		t.Fatalf("expect all %d visited, actual: %d, err: %v", n, count, err)
+	}
+}
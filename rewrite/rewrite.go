@@ -16,6 +16,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	source_import_internal "github.com/xhd2015/go-inspect/rewrite/internal/source_import"
 	"github.com/xhd2015/go-inspect/rewrite/session/session_impl"
 	"github.com/xhd2015/go-inspect/rewrite/source_import"
 	"github.com/xhd2015/go-vendor-pack/go_cmd"
@@ -145,7 +146,22 @@ func CleanGoFsPath(s string) string {
 //
 // the second phase of filecopy.SyncGenerated:
 // overlay, which is for generated file.
+//
+// errors are returned as *Error, including panics
+// of `ctrl` and `rewritter`.
 func GenRewrite(args []string, rewriteRoot string, ctrl Controller, rewritter Visitor, opts *BuildRewriteOptions) (res *GenRewriteResult, err error) {
+	phase := PhaseLoad
+	defer func() {
+		if e := recover(); e != nil {
+			res = nil
+			err = recoverError(phase, e)
+			return
+		}
+		if err != nil {
+			res = nil
+			err = phaseError(phase, err)
+		}
+	}()
 	res = &GenRewriteResult{}
 	if opts == nil {
 		opts = &BuildRewriteOptions{}
@@ -157,7 +173,8 @@ func GenRewrite(args []string, rewriteRoot string, ctrl Controller, rewritter Vi
 	verboseCost := true
 
 	if rewriteRoot == "" {
-		panic(fmt.Errorf("rewriteRoot is empty"))
+		err = fmt.Errorf("rewriteRoot is empty")
+		return
 	}
 	if opts.Verbose {
 		log.Printf("rewrite root: %s", rewriteRoot)
@@ -178,16 +195,24 @@ func GenRewrite(args []string, rewriteRoot string, ctrl Controller, rewritter Vi
 	memfsDir := filepath.Join(filepath.Dir(rewriteRoot), filepath.Base(rewriteRoot)+"-shadow")
 	// create a session, and rewrite
 	session := session_impl.NewSession(nil /* filled later*/, nil /*filled later: this is a workaround*/, memfsDir)
+	session_impl.OnSessionEditPolicy(session, opts.EditPolicy)
 
 	ctrl.BeforeLoad(opts, session)
 
 	loadPkgTime := time.Now()
 
-	g, err := load.LoadPackages(args, &load.LoadOptions{
+	loadOpts := &load.LoadOptions{
 		ProjectDir: projectDir,
 		ForTest:    opts.ForTest,
 		BuildFlags: opts.GoFlags,
-	})
+	}
+	if opts.SyntaxOnly {
+		if verbose {
+			log.Printf("load packages without types")
+		}
+		loadOpts.LoadMode = load.LoadModeSyntax
+	}
+	g, err := load.LoadPackages(args, loadOpts)
 	if err != nil {
 		err = fmt.Errorf("loading packages err: %v", err)
 		return
@@ -222,6 +247,19 @@ func GenRewrite(args []string, rewriteRoot string, ctrl Controller, rewritter Vi
 		return
 	}
 
+	work, err := loadGoWork(projectDir)
+	if err != nil {
+		return
+	}
+	var workModDirs []string
+	if work != nil {
+		workModDirs = work.modDirs
+		if verbose {
+			log.Printf("go.work: %s, modules: %v", work.file, workModDirs)
+		}
+	}
+
+	phase = PhaseVisit
 	// filter pkgs
 	pkgsFn := ctrl.FilterPkgs(g, session)
 	if pkgsFn == nil {
@@ -242,11 +280,27 @@ func GenRewrite(args []string, rewriteRoot string, ctrl Controller, rewritter Vi
 		log.Printf("COST load package -> rewrite package:%v", rewriteTime.Sub(loadPkgEnd))
 	}
 
-	VisitAll(func(f func(pkg inspect.Pkg) bool) {
+	visitPkgs := func(f func(pkg inspect.Pkg) bool) {
 		pkgsFn(func(p inspect.Pkg, pkgFlag PkgFlag) bool {
 			return f(p)
 		})
-	}, session, rewritter)
+	}
+	if opts.CacheKey == "" {
+		err = visitAll(visitPkgs, session, rewritter, opts.Concurrency)
+		if err != nil {
+			return
+		}
+	} else {
+		cache := newRewriteCache(session.Dirs().RewriteMetaSubPath(RewriteCacheDir), g, opts)
+		var hit, miss int
+		hit, miss, err = visitAllCached(visitPkgs, session, rewritter, cache, opts.Force, opts.Concurrency)
+		if err != nil {
+			return
+		}
+		if verbose {
+			log.Printf("rewrite cache: %d hit, %d miss", hit, miss)
+		}
+	}
 	rewriteEnd := time.Now()
 	if verboseCost {
 		log.Printf("COST rewrite:%v", rewriteEnd.Sub(rewriteTime))
@@ -283,55 +337,89 @@ func GenRewrite(args []string, rewriteRoot string, ctrl Controller, rewritter Vi
 		return true
 	})
 
-	if hasStd {
-		res.UseNewGOROOT = g.GOROOT()
-	}
-
 	if verbose {
 		if hasExtra {
 			log.Printf("extra packages in vendor:%v", extraPkgInVendor)
 		}
 	}
 
+	res.BuildStrategy = BuildStrategyCopy
+	if opts.BuildStrategy == BuildStrategyOverlay {
+		sourceImports := len(session.(source_import_internal.SourceImportRegistryRetriever).GetModules()) > 0
+		_, statErr := os.Stat(filepath.Join(modDir, "vendor", "modules.txt"))
+		vendor := extraPkgInVendor || statErr == nil
+		var reason string
+		reason, err = overlayUnsupported(hasStd, work != nil, vendor, sourceImports)
+		if err != nil {
+			return
+		}
+		if reason == "" {
+			res.BuildStrategy = BuildStrategyOverlay
+		} else {
+			log.Printf("overlay falls back to copy: %s", reason)
+		}
+	}
+	useOverlay := res.BuildStrategy == BuildStrategyOverlay
+
+	if hasStd && !useOverlay {
+		res.UseNewGOROOT = g.GOROOT()
+	}
+
 	// copy files
-	var destUpdatedBySource map[string]bool // todo: clear
-	doCopy := func() {
+	phase = PhaseCopy
+	var destSources map[string]string
+	if useOverlay {
+		// only go.mod is needed, files are served by overlay
+		destSources, err = loadOverlayModFiles(session.RewriteFS(), rewriteRoot, modDir)
+		if err != nil {
+			return
+		}
+	} else {
 		if verbose {
 			log.Printf("copying packages files into rewrite dir: total packages=%d", pkgCnt)
 		}
 		copyTime := time.Now()
-		destUpdatedBySource = copyPackageFiles(pkgsFn, session.RewriteFS(), rewriteRoot, extraPkgInVendor, hasStd, opts.Force, verboseCopy, verbose)
-		copyEnd := time.Now()
+		destSources, err = copyPackageFiles(pkgsFn, workModDirs, session.RewriteFS(), rewriteRoot, extraPkgInVendor, hasStd, opts.Force, verboseCopy, verbose)
+		if err != nil {
+			return
+		}
 		if verboseCost {
-			log.Printf("COST copy:%v", copyEnd.Sub(copyTime))
+			log.Printf("COST copy:%v", time.Since(copyTime))
 		}
 	}
-	_ = destUpdatedBySource
-	doCopy()
+
+	phase = PhaseGoMod
 
 	// NOTE: only non-vendor needs to replace relative module path
 	// with absolute path, because vendored packages are inside
-	// vendor
-	if !extraPkgInVendor {
+	// vendor.
+	// With overlay, go.mod is not moved, so nothing to replace.
+	if !extraPkgInVendor && !useOverlay {
 		// TODO: edit go mod in JSON and format back
 		// mod replace only work at module-level, so if at least
 		// one package inside a module is modified, we need to
 		// copy its module out.
-		doMod := func() {
-			// after copied, modify go.mod with replace absoluted
-			if verbose {
-				log.Printf("replacing go.mod with rewritten paths")
-			}
-			goModTime := time.Now()
-			res.MappedMod = makeGomodReplaceAboslute(session.RewriteFS(), pkgsFn, rewriteRoot, projectDir, verbose)
-			goModEnd := time.Now()
-			if verboseCost {
-				log.Printf("COST go mod:%v", goModEnd.Sub(goModTime))
-			}
+		// after copied, modify go.mod with replace absoluted
+		if verbose {
+			log.Printf("replacing go.mod with rewritten paths")
+		}
+		goModTime := time.Now()
+		res.MappedMod, err = makeGomodReplaceAboslute(session.RewriteFS(), pkgsFn, workModDirs, rewriteRoot, projectDir, verbose)
+		if err != nil {
+			return
+		}
+		if verboseCost {
+			log.Printf("COST go mod:%v", time.Since(goModTime))
+		}
+	}
+	if work != nil && !useOverlay {
+		res.GoWork, err = work.gen(session.RewriteFS(), rewriteRoot)
+		if err != nil {
+			return
 		}
-		doMod()
 	}
 
+	phase = PhaseOverlay
 	ctrl.BeforeCopy(g, session)
 
 	// import source first
@@ -347,7 +435,32 @@ func GenRewrite(args []string, rewriteRoot string, ctrl Controller, rewritter Vi
 	// including GOROOT/src rewritted ones.
 	ctrl.GenOverlay(g, session)
 
+	res.SourceMap, err = genSourceMap(session, rewriteRoot)
+	if err != nil {
+		err = fmt.Errorf("source map: %w", err)
+		return
+	}
+
 	rewriteFS := session.RewriteFS()
+	var overlay *overlayJSON
+	if useOverlay {
+		dirs := overlayDirs(g, append([]string{projectDir, modDir, g.GOROOT()}, workModDirs...))
+		overlay, err = genOverlay(rewriteFS, rewriteRoot, dirs, destSources)
+		if err != nil {
+			err = fmt.Errorf("overlay: %w", err)
+			return
+		}
+		if verbose {
+			log.Printf("overlay files: %v", overlay.files())
+		}
+	}
+	if opts.DryRun {
+		res.Diff, err = genRewriteDiff(rewriteFS, rewriteRoot, destSources)
+		if err != nil {
+			err = fmt.Errorf("dry run: %w", err)
+		}
+		return
+	}
 	// var disableDigest bool
 
 	// it seems that go cache is happy with content overridding
@@ -355,9 +468,11 @@ func GenRewrite(args []string, rewriteRoot string, ctrl Controller, rewritter Vi
 
 	// TODO: make file path relative to rewrite root
 
+	phase = PhaseCopy
 	var updatedDigestMap sync.Map // map[string]string
 	var savedDigestMap map[string]string
 
+	metaRoot := session.Dirs().RewriteMetaRoot()
 	srcMD5File := session.Dirs().RewriteMetaSubPath("src-md5.json")
 	if !opts.Force {
 		// with Force = true
@@ -476,6 +591,24 @@ func GenRewrite(args []string, rewriteRoot string, ctrl Controller, rewritter Vi
 		err = fmt.Errorf("write digest map: %w", err)
 		return
 	}
+	err = res.SourceMap.save(metaRoot)
+	if err != nil {
+		err = fmt.Errorf("write source map: %w", err)
+		return
+	}
+	if useOverlay {
+		res.Overlay = filepath.Join(metaRoot, OverlayFile)
+		err = overlay.save(res.Overlay)
+		if err != nil {
+			err = fmt.Errorf("write overlay: %w", err)
+			return
+		}
+		res.ModFile, err = overlayModFile(rewriteRoot, modDir)
+		if err != nil {
+			err = fmt.Errorf("overlay go.mod: %w", err)
+			return
+		}
+	}
 
 	if verboseCost {
 		log.Printf("COST load->rewrite->copy:%v", time.Since(loadPkgTime))
@@ -486,7 +619,9 @@ func GenRewrite(args []string, rewriteRoot string, ctrl Controller, rewritter Vi
 var ignores = []string{"(.*/)?\\.git\\b", "(.*/)?node_modules\\b"}
 
 // copyPackageFiles copy starter packages(with all packages under the same module) and extra packages into rootDir, to bundle them together.
-func copyPackageFiles(pkgs func(func(p inspect.Pkg, flag PkgFlag) bool), fs writefs.FS, rootDir string, extraPkgInVendor bool, hasStd bool, force bool, verboseDetail bool, verboseOverall bool) (destUpdated map[string]bool) {
+// modules in `workModDirs` are always copied, they are used by go.work.
+// it returns the source of each copied file.
+func copyPackageFiles(pkgs func(func(p inspect.Pkg, flag PkgFlag) bool), workModDirs []string, fs writefs.FS, rootDir string, extraPkgInVendor bool, hasStd bool, force bool, verboseDetail bool, verboseOverall bool) (destSources map[string]string, err error) {
 	var dirList []string
 	fileIgnores := append([]string(nil), ignores...)
 
@@ -514,6 +649,10 @@ func copyPackageFiles(pkgs func(func(p inspect.Pkg, flag PkgFlag) bool), fs writ
 		return true
 	})
 
+	for _, modDir := range workModDirs {
+		moduleDirs[modDir] = true
+	}
+
 	dirList = make([]string, 0, len(moduleDirs))
 	for modDir := range moduleDirs {
 		dirList = append(dirList, modDir)
@@ -531,10 +670,10 @@ func copyPackageFiles(pkgs func(func(p inspect.Pkg, flag PkgFlag) bool), fs writ
 	// 	dirList = append(dirList, inspect.GetFsPathOfPkg(p.Module, p.PkgPath))
 	// }
 
-	var destUpdatedM sync.Map
+	var destSourcesM sync.Map
 
 	size := int64(0)
-	err := filecopy.SyncRebase(dirList, rootDir, filecopy.SyncRebaseOptions{
+	err = filecopy.SyncRebase(dirList, rootDir, filecopy.SyncRebaseOptions{
 		Ignores:         fileIgnores,
 		Force:           true, // always set to true to force read all files into memory
 		DeleteNotFound:  true, // uncovered files are deleted
@@ -543,15 +682,15 @@ func copyPackageFiles(pkgs func(func(p inspect.Pkg, flag PkgFlag) bool), fs writ
 			log.Printf(format, args...)
 		}, verboseDetail, verboseOverall, 200*time.Millisecond),
 		DidCopy: func(srcPath, destPath string) {
-			destUpdatedM.Store(destPath, true)
+			destSourcesM.Store(destPath, srcPath)
 			atomic.AddInt64(&size, 1)
 		},
 		FS: fs,
 	})
 
-	destUpdated = make(map[string]bool, atomic.LoadInt64(&size))
-	destUpdatedM.Range(func(destPath, value interface{}) bool {
-		destUpdated[destPath.(string)] = true
+	destSources = make(map[string]string, atomic.LoadInt64(&size))
+	destSourcesM.Range(func(destPath, srcPath interface{}) bool {
+		destSources[destPath.(string)] = srcPath.(string)
 		return true
 	})
 
@@ -561,13 +700,15 @@ func copyPackageFiles(pkgs func(func(p inspect.Pkg, flag PkgFlag) bool), fs writ
 	// 	ProcessDest: cleanGoFsPath,
 	// })
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	return
 }
 
-// go mod's replace, find relative paths and replace them with absolute path
-func makeGomodReplaceAboslute(fs writefs.FS, pkgs func(func(pkg inspect.Pkg, flag PkgFlag) bool), rebaseDir string, projectDir string, verbose bool) (mappedMod map[string]string) {
+// go mod's replace, find relative paths and replace them with absolute path.
+// relative paths referring to modules in `workModDirs` are replaced with
+// their copies in `rebaseDir` instead.
+func makeGomodReplaceAboslute(fs writefs.FS, pkgs func(func(pkg inspect.Pkg, flag PkgFlag) bool), workModDirs []string, rebaseDir string, projectDir string, verbose bool) (mappedMod map[string]string, err error) {
 	// if there is vendor/modules.txt, should also replace there
 	replaceMap := make(map[string]string)
 
@@ -583,14 +724,20 @@ func makeGomodReplaceAboslute(fs writefs.FS, pkgs func(func(pkg inspect.Pkg, fla
 	var preReplaceList []goModReplace
 	mappedMod = make(map[string]string)
 
-	// get modules(for mods, actually only 1 module, i.e. the current module will be processed)
-	mods := make([]inspect.Module, 0, 1)
+	type goModDir struct {
+		path string
+		dir  string
+	}
+	// get modules(for mods, actually only 1 module, i.e. the current module will be processed,
+	// plus modules of go.work)
+	mods := make([]goModDir, 0, 1+len(workModDirs))
 	modMap := make(map[string]bool, 1)
 	pkgs(func(p inspect.Pkg, flag PkgFlag) bool {
 		mod := p.Module()
 		if mod == nil {
 			if flag.IsExtra() {
-				panic(fmt.Errorf("cannot replace non-module package:%v", p.Path()))
+				err = &Error{Phase: PhaseGoMod, Pkg: p.Path(), Err: fmt.Errorf("cannot replace non-module package")}
+				return false
 			}
 			return true
 		}
@@ -628,33 +775,51 @@ func makeGomodReplaceAboslute(fs writefs.FS, pkgs func(func(pkg inspect.Pkg, fla
 		}
 
 		modMap[modPath] = true
-		mods = append(mods, mod)
+		mods = append(mods, goModDir{path: modPath, dir: modDir})
 		return true
 	})
+	if err != nil {
+		return nil, err
+	}
+	modDirMap := make(map[string]bool, len(mods))
 	for _, mod := range mods {
-		dir := mod.Dir()
+		modDirMap[mod.dir] = true
+	}
+	isWorkMod := make(map[string]bool, len(workModDirs))
+	for _, modDir := range workModDirs {
+		isWorkMod[modDir] = true
+		// dir always absolute
+		mappedMod[modDir] = cleanGoFsPath(modDir)
+		if !modDirMap[modDir] {
+			modDirMap[modDir] = true
+			mods = append(mods, goModDir{path: modDir, dir: modDir})
+		}
+	}
+	for _, mod := range mods {
+		dir := mod.dir
 		origDir := dir
 		// rebase to rootDir
 		if rebaseDir != "" {
 			dir = path.Join(rebaseDir, dir)
 		}
 		goModFile := filepath.Join(dir, "go.mod")
+		goModErr := func(err error) error {
+			return &Error{Phase: PhaseGoMod, File: goModFile, Err: err}
+		}
 
 		var gomod *model.GoMod
-		var err error
-
 		if _, ok := fs.(writefs.SysFS); ok {
 			gomod, err = go_cmd.ParseGoMod(dir)
 		} else {
 			var content []byte
 			content, err = writefs.ReadFile(fs, goModFile)
 			if err != nil {
-				panic(err)
+				return nil, goModErr(err)
 			}
 			gomod, err = go_cmd.ParseGoModContent(string(content))
 		}
 		if err != nil {
-			panic(err)
+			return nil, goModErr(err)
 		}
 
 		// replace with absolute paths
@@ -675,6 +840,9 @@ func makeGomodReplaceAboslute(fs writefs.FS, pkgs func(func(pkg inspect.Pkg, fla
 					oldv += "@" + rp.Old.Version
 				}
 				newPath := path.Join(origDir, rp.New.Path)
+				if isWorkMod[newPath] {
+					newPath = path.Join(rebaseDir, cleanGoFsPath(newPath))
+				}
 				// replaceList = append(replaceList, goModEditReplace(oldv, newPath))
 				replaceList = append(replaceList, goModReplace{oldPath: oldv, newPath: newPath})
 
@@ -687,7 +855,7 @@ func makeGomodReplaceAboslute(fs writefs.FS, pkgs func(func(pkg inspect.Pkg, fla
 
 		if len(replaceList) > 0 || len(preReplaceList) > 0 {
 			if verbose {
-				log.Printf("make absolute replace in go.mod for %v", mod.OrigPath())
+				log.Printf("make absolute replace in go.mod for %v", mod.path)
 			}
 			doCmds := func(goModFile string) error {
 				for _, replace := range replaceList {
@@ -712,25 +880,26 @@ func makeGomodReplaceAboslute(fs writefs.FS, pkgs func(func(pkg inspect.Pkg, fla
 				// read and update
 				content, err := writefs.ReadFile(fs, goModFile)
 				if err != nil {
-					panic(err)
+					return nil, goModErr(err)
 				}
 				newGoMod, err := go_cmd.GoModEdit(string(content), doCmds)
 				if err != nil {
-					panic(err)
+					return nil, goModErr(err)
 				}
 				cmdErr = writefs.WriteFile(fs, goModFile, []byte(newGoMod))
 			}
 			if cmdErr != nil {
-				panic(cmdErr)
+				return nil, goModErr(cmdErr)
 			}
 		}
 	}
 
 	// because files are already copied, so we can check vendor/modules.txt locally
-	vendorErr := appendVendorModulesIgnoreNonExist(fs, path.Join(rebaseDir, projectDir, "vendor/modules.txt"), replaceMap)
+	modulesTxt := path.Join(rebaseDir, projectDir, "vendor/modules.txt")
+	vendorErr := appendVendorModulesIgnoreNonExist(fs, modulesTxt, replaceMap)
 	if vendorErr != nil {
 		log.Printf("ERROR failed to update vendor/modules.txt: %v\n", vendorErr)
-		panic(vendorErr)
+		return nil, &Error{Phase: PhaseGoMod, File: modulesTxt, Err: vendorErr}
 	}
 	return
 }
@@ -3,6 +3,8 @@ package rewrite
 import (
 	"fmt"
 	"go/ast"
+	"sync"
+	"sync/atomic"
 
 	"github.com/xhd2015/go-inspect/inspect"
 	"github.com/xhd2015/go-inspect/rewrite/session"
@@ -16,6 +18,13 @@ type Visitor interface {
 	// any Pakcage, making it holds a list of ast files.
 	// So we start by packages, not by file.
 	// You can type check n against *ast.Package, *ast.File, ...
+	//
+	// When packages are visited in parallel, Visit and VisitEnd
+	// may be called concurrently for nodes of different packages,
+	// nodes of the same package are always visited by one goroutine.
+	// State shared across packages must be guarded, Session
+	// and its edits are safe for concurrent use, so is
+	// Session.Global(), including its Registry.
 	Visit(n ast.Node, session session.Session) bool
 	VisitEnd(n ast.Node, session session.Session)
 }
@@ -124,6 +133,8 @@ type stackVisitor struct {
 
 	// root is package, then file
 	stack []ast.Node
+	// cur is the node being visited
+	cur ast.Node
 }
 
 // Visit implements ast.Visitor
@@ -132,9 +143,11 @@ func (c *stackVisitor) Visit(node ast.Node) (w ast.Visitor) {
 		// back
 		last := c.stack[len(c.stack)-1]
 		c.stack = c.stack[:len(c.stack)-1]
+		c.cur = last
 		c.v.VisitEnd(last, c.session)
 		return
 	}
+	c.cur = node
 	if !c.v.Visit(node, c.session) {
 		return nil
 	}
@@ -143,18 +156,118 @@ func (c *stackVisitor) Visit(node ast.Node) (w ast.Visitor) {
 }
 
 func VisitAll(pkgs func(func(pkg inspect.Pkg) bool), session session.Session, visitor Visitor) {
+	VisitAllParallel(pkgs, session, visitor, 1)
+}
+
+// VisitAllParallel is like VisitAll, but visits at most
+// `workers` packages in parallel, see Visitor for what
+// may be called concurrently.
+func VisitAllParallel(pkgs func(func(pkg inspect.Pkg) bool), session session.Session, visitor Visitor, workers int) {
+	err := visitAll(pkgs, session, visitor, workers)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// visitAll is like VisitAllParallel, but stops at the first
+// panic of `visitor`, returned as an Error of PhaseVisit
+func visitAll(pkgs func(func(pkg inspect.Pkg) bool), session session.Session, visitor Visitor, workers int) error {
+	return rangePkgsParallel(pkgs, workers, func(p inspect.Pkg) error {
+		return visitPkg(p, session, visitor)
+	})
+}
+
+// rangePkgsParallel calls `fn` on every package, with at most
+// `workers` packages in parallel.
+// workers <= 1 calls `fn` in order in the current goroutine.
+func rangePkgsParallel(pkgs func(func(pkg inspect.Pkg) bool), workers int, fn func(p inspect.Pkg) error) (err error) {
+	if workers <= 1 {
+		pkgs(func(p inspect.Pkg) bool {
+			err = fn(p)
+			return err == nil
+		})
+		return
+	}
+	var list []inspect.Pkg
+	pkgs(func(p inspect.Pkg) bool {
+		list = append(list, p)
+		return true
+	})
+	return rangeParallel(len(list), workers, func(i int) error {
+		return fn(list[i])
+	})
+}
+
+// rangeParallel calls `fn` on 0..n-1 with at most `workers`
+// goroutines. Indexes not yet started are skipped once `fn`
+// fails, and the error of the smallest failed index is
+// returned, so the result does not depend on scheduling.
+func rangeParallel(n int, workers int, fn func(i int) error) error {
+	errs := make([]error, n)
+	var next int64 = -1
+	var failed int32
+	var wg sync.WaitGroup
+	if workers > n {
+		workers = n
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= n || atomic.LoadInt32(&failed) != 0 {
+					return
+				}
+				errs[i] = fn(i)
+				if errs[i] != nil {
+					atomic.StoreInt32(&failed, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func visitPkg(p inspect.Pkg, session session.Session, visitor Visitor) (err error) {
 	st := &stackVisitor{
 		v:       visitor,
 		session: session,
 	}
-	// traverse all packages
-	pkgs(func(p inspect.Pkg) bool {
-		ast.Walk(st, p.ASTNode())
-		if len(st.stack) != 0 {
-			panic(fmt.Errorf("internal error, expect empty stack,actual:%d", len(st.stack)))
+	defer func() {
+		if e := recover(); e != nil {
+			visitErr := recoverError(PhaseVisit, e)
+			if visitErr.Pkg == "" {
+				visitErr.Pkg = p.Path()
+				visitErr.File = st.file()
+			}
+			err = visitErr
 		}
-		return true
-	})
+	}()
+	ast.Walk(st, p.ASTNode())
+	if len(st.stack) != 0 {
+		return &Error{Phase: PhaseVisit, Pkg: p.Path(), Err: fmt.Errorf("internal error, expect empty stack,actual:%d", len(st.stack))}
+	}
+	return nil
+}
+
+// file returns the file being visited, if any
+func (c *stackVisitor) file() string {
+	if f, ok := c.cur.(*ast.File); ok {
+		return c.session.Global().FileSet().Position(f.Package).Filename
+	}
+	for i := len(c.stack) - 1; i >= 0; i-- {
+		if f, ok := c.stack[i].(*ast.File); ok {
+			return c.session.Global().FileSet().Position(f.Package).Filename
+		}
+	}
+	return ""
 }
 
 // RewritePackages
@@ -49,6 +49,20 @@ type GoRewriteEdit interface {
 	Append(code string)
 }
 
+// OriginEdit is optionally implemented by GoRewriteEdit, see edit.Originator
+type OriginEdit interface {
+	WithOrigin(origin string) GoRewriteEdit
+}
+
+// WithOrigin returns an edit recording `origin` with edits made
+// through it, or `edit` itself if it does not support origins.
+func WithOrigin(edit GoRewriteEdit, origin string) GoRewriteEdit {
+	if o, ok := edit.(OriginEdit); ok {
+		return o.WithOrigin(origin)
+	}
+	return edit
+}
+
 type GoNewEdit interface {
 	SetPackageName(name string)
 
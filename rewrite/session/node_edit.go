@@ -0,0 +1,346 @@
+package session
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/ast/astutil"
+
+	"github.com/xhd2015/go-inspect/code/edit"
+	"github.com/xhd2015/go-inspect/inspect"
+)
+
+// NodeEdit edits a file by AST nodes of the original file,
+// alongside text edits of the underlying GoRewriteEdit.
+// Edits made through it are checked against each other,
+// overlapping edits are reported as *ConflictError:
+// node methods return it, text methods panic with it.
+type NodeEdit interface {
+	GoRewriteEdit
+
+	// ReplaceNode replaces `old`, a node of the original file, with `new`.
+	// Nodes of the original file inside `new` are printed as their
+	// original source, keeping comments inside them.
+	ReplaceNode(old ast.Node, new ast.Node) error
+	// DeleteNode deletes `old`, a node of the original file
+	DeleteNode(old ast.Node) error
+	// InsertNodeBefore inserts `new` before `anchor`, separated by
+	// a new line for statements and declarations, by comma for
+	// expressions and fields as in argument and parameter lists.
+	InsertNodeBefore(anchor ast.Node, new ast.Node) error
+	// InsertNodeAfter is like InsertNodeBefore, but after `anchor`
+	InsertNodeAfter(anchor ast.Node, new ast.Node) error
+}
+
+// ConflictError reports an edit overlapping a previous one, it is
+// the same type as edit.ConflictError of package code/edit. For node
+// edits, offsets are of the original file, and origins describe the
+// edits, e.g. replace *ast.BlockStmt.
+type ConflictError = edit.ConflictError
+
+// NewNodeEdit returns a NodeEdit of `f` editing through `edit`,
+// usually the one returned by FileRewrite or FileEdit.
+// Only edits made through the returned NodeEdit are checked.
+func NewNodeEdit(f inspect.FileContext, edit GoRewriteEdit) NodeEdit {
+	g := f.Pkg().Global()
+	tokenFile := g.FileSet().File(f.AST().Package)
+	return &nodeEdit{
+		GoRewriteEdit: edit,
+		f:             f,
+		fset:          g.FileSet(),
+		base:          token.Pos(tokenFile.Base()),
+		end:           token.Pos(tokenFile.Base() + tokenFile.Size()),
+	}
+}
+
+type nodeEdit struct {
+	GoRewriteEdit
+	f    inspect.FileContext
+	fset *token.FileSet
+	// range of the file
+	base token.Pos
+	end  token.Pos
+
+	mutex sync.Mutex
+	done  []*editRange
+}
+
+// editRange is [start,end), insertion has start == end
+type editRange struct {
+	desc  string
+	start token.Pos
+	end   token.Pos
+}
+
+func (c *editRange) overlaps(r *editRange) bool {
+	if c.start == c.end || r.start == r.end {
+		// insertion only conflicts when strictly inside
+		insert, other := c, r
+		if c.start != c.end {
+			insert, other = r, c
+		}
+		return other.start < insert.start && insert.start < other.end
+	}
+	return c.start < r.end && r.start < c.end
+}
+
+var _ NodeEdit = ((*nodeEdit)(nil))
+
+// check records `r` if it does not overlap previous edits
+func (c *nodeEdit) check(r *editRange) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, d := range c.done {
+		if d.overlaps(r) {
+			return &ConflictError{
+				File:           c.f.AbsPath(),
+				Start:          int(r.start - c.base),
+				End:            int(r.end - c.base),
+				Origin:         r.desc,
+				ConflictStart:  int(d.start - c.base),
+				ConflictEnd:    int(d.end - c.base),
+				ConflictOrigin: d.desc,
+			}
+		}
+	}
+	c.done = append(c.done, r)
+	return nil
+}
+
+func (c *nodeEdit) mustCheck(r *editRange) {
+	err := c.check(r)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Insert implements GoRewriteEdit
+func (c *nodeEdit) Insert(start token.Pos, content string) {
+	c.mustCheck(&editRange{desc: "insert", start: start, end: start})
+	c.GoRewriteEdit.Insert(start, content)
+}
+
+// Delete implements GoRewriteEdit
+func (c *nodeEdit) Delete(start token.Pos, end token.Pos) {
+	c.mustCheck(&editRange{desc: "delete", start: start, end: end})
+	c.GoRewriteEdit.Delete(start, end)
+}
+
+// Replace implements GoRewriteEdit
+func (c *nodeEdit) Replace(start token.Pos, end token.Pos, content string) {
+	c.mustCheck(&editRange{desc: "replace", start: start, end: end})
+	c.GoRewriteEdit.Replace(start, end, content)
+}
+
+// ReplaceNode implements NodeEdit
+func (c *nodeEdit) ReplaceNode(old ast.Node, new ast.Node) error {
+	err := c.checkOriginal(old)
+	if err != nil {
+		return err
+	}
+	code, err := c.format(new, c.indentOf(old.Pos()))
+	if err != nil {
+		return err
+	}
+	err = c.check(&editRange{desc: fmt.Sprintf("replace %T", old), start: old.Pos(), end: old.End()})
+	if err != nil {
+		return err
+	}
+	c.GoRewriteEdit.Replace(old.Pos(), old.End(), code)
+	return nil
+}
+
+// DeleteNode implements NodeEdit
+func (c *nodeEdit) DeleteNode(old ast.Node) error {
+	err := c.checkOriginal(old)
+	if err != nil {
+		return err
+	}
+	err = c.check(&editRange{desc: fmt.Sprintf("delete %T", old), start: old.Pos(), end: old.End()})
+	if err != nil {
+		return err
+	}
+	c.GoRewriteEdit.Delete(old.Pos(), old.End())
+	return nil
+}
+
+// InsertNodeBefore implements NodeEdit
+func (c *nodeEdit) InsertNodeBefore(anchor ast.Node, new ast.Node) error {
+	return c.insertNode(anchor, new, true)
+}
+
+// InsertNodeAfter implements NodeEdit
+func (c *nodeEdit) InsertNodeAfter(anchor ast.Node, new ast.Node) error {
+	return c.insertNode(anchor, new, false)
+}
+
+func (c *nodeEdit) insertNode(anchor ast.Node, new ast.Node, before bool) error {
+	err := c.checkOriginal(anchor)
+	if err != nil {
+		return err
+	}
+	indent := c.indentOf(anchor.Pos())
+	var sep string
+	switch anchor.(type) {
+	case ast.Stmt, ast.Spec:
+		sep = "\n" + indent
+	case ast.Decl:
+		sep = "\n\n" + indent
+	case ast.Expr, *ast.Field:
+		sep = ", "
+	default:
+		return fmt.Errorf("cannot insert around %T", anchor)
+	}
+	code, err := c.format(new, indent)
+	if err != nil {
+		return err
+	}
+	pos := anchor.End()
+	content := sep + code
+	if before {
+		pos = anchor.Pos()
+		content = code + sep
+	}
+	err = c.check(&editRange{desc: fmt.Sprintf("insert %T", new), start: pos, end: pos})
+	if err != nil {
+		return err
+	}
+	c.GoRewriteEdit.Insert(pos, content)
+	return nil
+}
+
+func (c *nodeEdit) isOriginal(n ast.Node) bool {
+	return n.Pos().IsValid() && n.End().IsValid() && c.base <= n.Pos() && n.End() <= c.end
+}
+
+func (c *nodeEdit) checkOriginal(n ast.Node) error {
+	if n == nil || !c.isOriginal(n) {
+		return fmt.Errorf("%T is not a node of %s", n, c.f.AbsPath())
+	}
+	return nil
+}
+
+// indentOf returns leading white spaces of the line containing `pos`
+func (c *nodeEdit) indentOf(pos token.Pos) string {
+	code := c.f.Pkg().Global().Code(c.f)
+	offset := int(pos - c.base)
+	lineStart := strings.LastIndexByte(code[:offset], '\n') + 1
+	i := lineStart
+	for i < offset && (code[i] == ' ' || code[i] == '\t') {
+		i++
+	}
+	return code[lineStart:i]
+}
+
+// format prints `n`, nodes of the original file are
+// printed as their original source. Lines except the
+// first are indented by `indent`.
+func (c *nodeEdit) format(n ast.Node, indent string) (string, error) {
+	if n == nil {
+		return "", fmt.Errorf("nil node")
+	}
+	g := c.f.Pkg().Global()
+	if c.isOriginal(n) {
+		return g.CodeSlice(n.Pos(), n.End()), nil
+	}
+
+	// temporarily replace original nodes with placeholders,
+	// so that the printer does not mix their positions
+	// with new nodes.
+	placeholders := make(map[ast.Node]ast.Node)
+	var patterns []*regexp.Regexp
+	var origins []string
+	astutil.Apply(n, func(cur *astutil.Cursor) bool {
+		node := cur.Node()
+		if node == nil || node == n || !c.isOriginal(node) {
+			return true
+		}
+		name := fmt.Sprintf("_go_inspect_placeholder_%d", len(origins))
+		ph, pattern := placeholderOf(node, name)
+		if ph == nil || !tryReplace(cur, ph) {
+			return true
+		}
+		placeholders[ph] = node
+		patterns = append(patterns, pattern)
+		origins = append(origins, g.CodeSlice(node.Pos(), node.End()))
+		return false
+	}, nil)
+	printed, err := printNode(n)
+	// restore
+	if len(placeholders) > 0 {
+		astutil.Apply(n, func(cur *astutil.Cursor) bool {
+			if orig, ok := placeholders[cur.Node()]; ok {
+				cur.Replace(orig)
+				return false
+			}
+			return true
+		}, nil)
+	}
+	if err != nil {
+		return "", err
+	}
+	code := strings.ReplaceAll(printed, "\n", "\n"+indent)
+	for i, pattern := range patterns {
+		// original code is already indented
+		origin := origins[i]
+		code = pattern.ReplaceAllLiteralString(code, origin)
+	}
+	return code, nil
+}
+
+func printNode(n ast.Node) (string, error) {
+	field, isField := n.(*ast.Field)
+	if isField {
+		// the printer does not print a single field,
+		// print it as func(field) instead
+		n = &ast.FuncType{Params: &ast.FieldList{List: []*ast.Field{field}}}
+	}
+	var buf bytes.Buffer
+	err := printer.Fprint(&buf, token.NewFileSet(), n)
+	if err != nil {
+		return "", err
+	}
+	code := buf.String()
+	if isField {
+		code = strings.TrimSuffix(strings.TrimPrefix(code, "func("), ")")
+	}
+	return code, nil
+}
+
+// tryReplace fails if `n` does not fit where the cursor is,
+// e.g. a field of type *ast.BasicLit
+func tryReplace(cur *astutil.Cursor, n ast.Node) (ok bool) {
+	defer func() {
+		if e := recover(); e != nil {
+			ok = false
+		}
+	}()
+	cur.Replace(n)
+	return true
+}
+
+// placeholderOf returns a node printed as something matched by
+// the returned pattern, that can be placed where `n` is
+func placeholderOf(n ast.Node, name string) (ast.Node, *regexp.Regexp) {
+	ident := ast.NewIdent(name)
+	quoted := regexp.QuoteMeta(name) + `\b`
+	switch n.(type) {
+	case ast.Expr:
+		return ident, regexp.MustCompile(quoted)
+	case *ast.BlockStmt:
+		return &ast.BlockStmt{List: []ast.Stmt{&ast.ExprStmt{X: ident}}}, regexp.MustCompile(`\{\s*` + quoted + `\s*\}`)
+	case ast.Stmt:
+		return &ast.ExprStmt{X: ident}, regexp.MustCompile(quoted)
+	case *ast.Field:
+		return &ast.Field{Type: ident}, regexp.MustCompile(quoted)
+	case ast.Decl:
+		return &ast.GenDecl{Tok: token.VAR, Specs: []ast.Spec{&ast.ValueSpec{Names: []*ast.Ident{ident}}}}, regexp.MustCompile(`var ` + quoted)
+	}
+	return nil, nil
+}
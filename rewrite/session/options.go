@@ -15,6 +15,16 @@ type Options interface {
 	RewriteStd() bool
 	SetRewriteStd(rewriteStd bool)
 
+	// CacheKey identifies rewriters and their options,
+	// empty means packages are always visited.
+	CacheKey() string
+	SetCacheKey(key string)
+
+	// SyntaxOnly loads packages without types, it
+	// only takes effect when set in BeforeLoad
+	SyntaxOnly() bool
+	SetSyntaxOnly(syntaxOnly bool)
+
 	// GoFlags are common to load and build
 	GoFlags() []string
 
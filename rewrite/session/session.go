@@ -5,7 +5,10 @@ import (
 	"github.com/xhd2015/go-vendor-pack/writefs/memfs"
 )
 
-// Session session represents a rewrite pass
+// Session session represents a rewrite pass.
+// Except Gen, its methods and the edits they return are
+// safe for concurrent use, since packages may be visited
+// in parallel.
 type Session interface {
 	Global() inspect.Global
 
@@ -2,47 +2,68 @@ package session_impl
 
 import (
 	"go/token"
+	"sync"
 
 	"github.com/xhd2015/go-inspect/code/edit"
 	"github.com/xhd2015/go-inspect/code/gen"
 	"github.com/xhd2015/go-inspect/inspect"
 	"github.com/xhd2015/go-inspect/inspect/util"
+	rewrite_edit "github.com/xhd2015/go-inspect/rewrite/edit"
 	sessionpkg "github.com/xhd2015/go-inspect/rewrite/session"
 )
 
 type editImpl struct {
-	buf  *edit.Buffer
-	fset *token.FileSet
+	buf    *edit.Buffer
+	fset   *token.FileSet
+	origin string
 }
 
 var _ sessionpkg.Edit = ((*editImpl)(nil))
+var _ rewrite_edit.Originator = ((*editImpl)(nil))
 
 func NewEdit(fset *token.FileSet, content string) sessionpkg.Edit {
+	return newEdit(fset, content, edit.PolicyConflict)
+}
+
+func newEdit(fset *token.FileSet, content string, policy edit.Policy) *editImpl {
+	buf := edit.NewBuffer([]byte(content))
+	buf.SetPolicy(policy)
 	return &editImpl{
 		fset: fset,
-		buf:  edit.NewBuffer([]byte(content)),
+		buf:  buf,
 	}
 }
 
+// WithOrigin implements edit.Originator, the returned
+// Edit shares the same buffer
+func (c *editImpl) WithOrigin(origin string) rewrite_edit.Edit {
+	return &editImpl{buf: c.buf, fset: c.fset, origin: origin}
+}
+
 // Delete implements Edit
 func (c *editImpl) Delete(start token.Pos, end token.Pos) {
-	c.buf.Delete(util.OffsetOf(c.fset, start), util.OffsetOf(c.fset, end))
+	c.buf.WithOrigin(c.origin).Delete(util.OffsetOf(c.fset, start), util.OffsetOf(c.fset, end))
 }
 
 // Insert implements Edit
 func (c *editImpl) Insert(start token.Pos, content string) {
-	c.buf.Insert(util.OffsetOf(c.fset, start), content)
+	c.buf.WithOrigin(c.origin).Insert(util.OffsetOf(c.fset, start), content)
 }
 
 // Replace implements Edit
 func (c *editImpl) Replace(start token.Pos, end token.Pos, content string) {
-	c.buf.Replace(util.OffsetOf(c.fset, start), util.OffsetOf(c.fset, end), content)
+	c.buf.WithOrigin(c.origin).Replace(util.OffsetOf(c.fset, start), util.OffsetOf(c.fset, end), content)
 }
 
 func (c *editImpl) String() string {
 	return c.buf.String()
 }
 
+// Mapping implements edit.Mapper
+func (c *editImpl) Mapping() *edit.Mapping {
+	return c.buf.Mapping()
+}
+
 type posInfo struct {
 	pos    token.Pos
 	offset int
@@ -62,31 +83,113 @@ func (c *posInfo) Advance(off int) {
 	c.offset += off
 }
 
+// goRewriteEdit is safe for concurrent use, as visitors
+// of different packages may edit the same file
 type goRewriteEdit struct {
 	sessionpkg.Edit
 	inspect.ImportListContext
 
 	anonymousPos *posInfo
+	file         string
+
+	// shared with edits returned by WithOrigin
+	mutex *sync.Mutex
 }
 
 var _ sessionpkg.GoRewriteEdit = ((*goRewriteEdit)(nil))
+var _ sessionpkg.OriginEdit = ((*goRewriteEdit)(nil))
 
 func NewGoRewrite(f inspect.FileContext) sessionpkg.GoRewriteEdit {
+	return newGoRewrite(f, edit.PolicyConflict)
+}
+
+func newGoRewrite(f inspect.FileContext, policy edit.Policy) *goRewriteEdit {
 	g := f.Pkg().Global()
-	edit := NewEdit(g.FileSet(), g.Code(f))
+	edit := newEdit(g.FileSet(), g.Code(f), policy)
 	return &goRewriteEdit{
 		Edit:              edit,
 		ImportListContext: f.EditImports(edit),
 		anonymousPos:      NewPos(f.AST().End(), 0),
+		file:              f.AbsPath(),
+		mutex:             &sync.Mutex{},
+	}
+}
+
+// WithOrigin implements OriginEdit
+func (c *goRewriteEdit) WithOrigin(origin string) sessionpkg.GoRewriteEdit {
+	o, ok := c.Edit.(rewrite_edit.Originator)
+	if !ok {
+		return c
 	}
+	edit := *c
+	edit.Edit = o.WithOrigin(origin)
+	return &edit
+}
+
+// Delete implements GoRewriteEdit
+func (c *goRewriteEdit) Delete(start token.Pos, end token.Pos) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.Edit.Delete(start, end)
+}
+
+// Insert implements GoRewriteEdit
+func (c *goRewriteEdit) Insert(start token.Pos, content string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.Edit.Insert(start, content)
+}
+
+// Replace implements GoRewriteEdit
+func (c *goRewriteEdit) Replace(start token.Pos, end token.Pos, content string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.Edit.Replace(start, end, content)
+}
+
+// String panics with *edit.ConflictError if edits overlap
+func (c *goRewriteEdit) String() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defer func() {
+		if e := recover(); e != nil {
+			if conflict, ok := e.(*edit.ConflictError); ok && conflict.File == "" {
+				conflict.File = c.file
+			}
+			panic(e)
+		}
+	}()
+	return c.Edit.String()
+}
+
+// Mapping implements edit.Mapper, nil if
+// the underlying Edit does not support it
+func (c *goRewriteEdit) Mapping() *edit.Mapping {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if m, ok := c.Edit.(rewrite_edit.Mapper); ok {
+		return m.Mapping()
+	}
+	return nil
+}
+
+// MustImport implements GoRewriteEdit
+func (c *goRewriteEdit) MustImport(pkgPath string, name string, suggestAlias string, forbidden func(name string) bool) string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.ImportListContext.MustImport(pkgPath, name, suggestAlias, forbidden)
 }
 
 // AddAnaymouseInit implements GoRewriteEdit
 func (c *goRewriteEdit) AddAnaymouseInit(code string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	c.Edit.Insert(c.anonymousPos.Pos(), code)
 	c.anonymousPos.Advance(len(code))
 }
 func (c *goRewriteEdit) Append(code string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	c.Edit.Insert(c.anonymousPos.pos, code)
 	// c.anonymousPos.Advance(len(code))
 }
@@ -100,6 +203,7 @@ func (c *imp) Format() string {
 	return util.FormatImport(c.alias, c.pkgPath)
 }
 
+// goNewEdit is safe for concurrent use
 type goNewEdit struct {
 	inspect.ImportListContext
 	imports []*imp
@@ -110,6 +214,8 @@ type goNewEdit struct {
 	headCodes          []string
 	codes              []string
 	anonymousInitCodes []string
+
+	mutex sync.Mutex
 }
 
 var _ sessionpkg.GoNewEdit = ((*goNewEdit)(nil))
@@ -127,25 +233,42 @@ func NewGoEdit() sessionpkg.GoNewEdit {
 
 // SetPackageName implements GoNewEdit
 func (c *goNewEdit) SetPackageName(name string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	c.pkgName = name
 }
 
+// MustImport implements GoNewEdit
+func (c *goNewEdit) MustImport(pkgPath string, name string, suggestAlias string, forbidden func(name string) bool) string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.ImportListContext.MustImport(pkgPath, name, suggestAlias, forbidden)
+}
+
 // AddAnaymouseInit implements GoNewEdit
 func (c *goNewEdit) AddAnaymouseInit(code string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	c.anonymousInitCodes = append(c.anonymousInitCodes, code)
 }
 
 // before package
 func (c *goNewEdit) AddHeadCode(code string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	c.headCodes = append(c.headCodes, code)
 }
 
 // after import
 func (c *goNewEdit) AddCode(code string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	c.codes = append(c.codes, code)
 }
 
 func (c *goNewEdit) String() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	t := gen.NewTemplateBuilder()
 	if len(c.headCodes) > 0 {
 		t.Block(
@@ -0,0 +1,368 @@
+package session_impl
+
+import (
+	"fmt"
+	"go/token"
+	"sync"
+
+	"github.com/xhd2015/go-inspect/inspect"
+	sessionpkg "github.com/xhd2015/go-inspect/rewrite/session"
+)
+
+// Journal records edits made through a session, so
+// that they can be replayed on another session
+// loaded from the same source.
+// Positions are saved as offsets relative to the
+// file, so they do not depend on the file set.
+type Journal struct {
+	Ops []*JournalOp `json:"ops"`
+
+	mutex sync.Mutex
+}
+
+type JournalTarget string
+
+const (
+	JournalTargetFileRewrite JournalTarget = "rewrite"
+	JournalTargetFileEdit    JournalTarget = "edit"
+	JournalTargetPkg         JournalTarget = "pkg"
+	JournalTargetRewriteFile JournalTarget = "rewrite_file"
+	JournalTargetReplaceFile JournalTarget = "replace_file"
+)
+
+type JournalMethod string
+
+const (
+	JournalInsert      JournalMethod = "insert"
+	JournalDelete      JournalMethod = "delete"
+	JournalReplace     JournalMethod = "replace"
+	JournalImport      JournalMethod = "import"
+	JournalInit        JournalMethod = "init"
+	JournalAppend      JournalMethod = "append"
+	JournalPackageName JournalMethod = "package_name"
+	JournalHeadCode    JournalMethod = "head_code"
+	JournalCode        JournalMethod = "code"
+	JournalSetFile     JournalMethod = "set_file"
+)
+
+type JournalOp struct {
+	Target JournalTarget `json:"target"`
+	Method JournalMethod `json:"method"`
+
+	// File is the absolute path for file targets
+	File string `json:"file,omitempty"`
+
+	// Pkg and Kind identify a PackageEdit
+	Pkg  string `json:"pkg,omitempty"`
+	Kind string `json:"kind,omitempty"`
+
+	Start   int    `json:"start,omitempty"`
+	End     int    `json:"end,omitempty"`
+	Content string `json:"content,omitempty"`
+
+	// for MustImport, Alias is the actual name returned
+	ImportPath string `json:"import_path,omitempty"`
+	ImportName string `json:"import_name,omitempty"`
+	Alias      string `json:"alias,omitempty"`
+
+	// Origin is the origin of file edits, see OriginSession
+	Origin string `json:"origin,omitempty"`
+}
+
+// JournalSession returns a session that forwards to `s`,
+// edits made through it are recorded into the returned journal.
+// Each package visited in parallel uses its own JournalSession.
+func JournalSession(s sessionpkg.Session) (sessionpkg.Session, *Journal) {
+	j := &Journal{}
+	return &journalSession{Session: s, j: j}, j
+}
+
+type journalSession struct {
+	sessionpkg.Session
+	j *Journal
+}
+
+var _ sessionpkg.Session = ((*journalSession)(nil))
+
+// FileEdit implements Session
+func (c *journalSession) FileEdit(f inspect.FileContext) sessionpkg.GoRewriteEdit {
+	return c.fileEdit(JournalTargetFileEdit, f, c.Session.FileEdit(f))
+}
+
+// FileRewrite implements Session
+func (c *journalSession) FileRewrite(f inspect.FileContext) sessionpkg.GoRewriteEdit {
+	return c.fileEdit(JournalTargetFileRewrite, f, c.Session.FileRewrite(f))
+}
+
+func (c *journalSession) fileEdit(target JournalTarget, f inspect.FileContext, edit sessionpkg.GoRewriteEdit) sessionpkg.GoRewriteEdit {
+	return &journalRewriteEdit{GoRewriteEdit: edit, j: c.j, target: target, file: f.AbsPath(), base: fileBase(f)}
+}
+
+// PackageEdit implements Session
+func (c *journalSession) PackageEdit(p inspect.Pkg, kind string) sessionpkg.GoNewEdit {
+	return &journalNewEdit{GoNewEdit: c.Session.PackageEdit(p, kind), j: c.j, pkg: p.Path(), kind: kind}
+}
+
+// SetRewriteFile implements Session
+func (c *journalSession) SetRewriteFile(filePath string, content string) error {
+	c.j.record(&JournalOp{Target: JournalTargetRewriteFile, Method: JournalSetFile, File: filePath, Content: content})
+	return c.Session.SetRewriteFile(filePath, content)
+}
+
+// ReplaceFile implements Session
+func (c *journalSession) ReplaceFile(filePath string, content string) error {
+	c.j.record(&JournalOp{Target: JournalTargetReplaceFile, Method: JournalSetFile, File: filePath, Content: content})
+	return c.Session.ReplaceFile(filePath, content)
+}
+
+// ReplayJournal applies edits recorded in `j` to `s`.
+// Files and packages are looked up in the session's Global.
+func ReplayJournal(s sessionpkg.Session, j *Journal) error {
+	if j == nil {
+		return nil
+	}
+	g := s.Global()
+	var files map[string]inspect.FileContext
+	getFile := func(absPath string) (inspect.FileContext, error) {
+		if files == nil {
+			files = make(map[string]inspect.FileContext)
+			g.RangePkg(func(pkg inspect.Pkg) bool {
+				pkg.RangeFiles(func(i int, f inspect.FileContext) bool {
+					files[f.AbsPath()] = f
+					return true
+				})
+				return true
+			})
+		}
+		f := files[absPath]
+		if f == nil {
+			return nil, fmt.Errorf("file not found: %s", absPath)
+		}
+		return f, nil
+	}
+	for _, op := range j.Ops {
+		switch op.Target {
+		case JournalTargetFileRewrite, JournalTargetFileEdit:
+			f, err := getFile(op.File)
+			if err != nil {
+				return err
+			}
+			var edit sessionpkg.GoRewriteEdit
+			if op.Target == JournalTargetFileRewrite {
+				edit = s.FileRewrite(f)
+			} else {
+				edit = s.FileEdit(f)
+			}
+			if op.Origin != "" {
+				edit = sessionpkg.WithOrigin(edit, op.Origin)
+			}
+			err = replayRewriteOp(edit, fileBase(f), op)
+			if err != nil {
+				return fmt.Errorf("%s: %w", op.File, err)
+			}
+		case JournalTargetPkg:
+			pkg := g.GetPkg(op.Pkg)
+			if pkg == nil {
+				return fmt.Errorf("package not found: %s", op.Pkg)
+			}
+			err := replayNewEditOp(s.PackageEdit(pkg, op.Kind), op)
+			if err != nil {
+				return fmt.Errorf("%s: %w", op.Pkg, err)
+			}
+		case JournalTargetRewriteFile:
+			err := s.SetRewriteFile(op.File, op.Content)
+			if err != nil {
+				return err
+			}
+		case JournalTargetReplaceFile:
+			err := s.ReplaceFile(op.File, op.Content)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown journal target: %s", op.Target)
+		}
+	}
+	return nil
+}
+
+func replayRewriteOp(edit sessionpkg.GoRewriteEdit, base token.Pos, op *JournalOp) error {
+	switch op.Method {
+	case JournalInsert:
+		edit.Insert(base+token.Pos(op.Start), op.Content)
+	case JournalDelete:
+		edit.Delete(base+token.Pos(op.Start), base+token.Pos(op.End))
+	case JournalReplace:
+		edit.Replace(base+token.Pos(op.Start), base+token.Pos(op.End), op.Content)
+	case JournalImport:
+		name := edit.MustImport(op.ImportPath, op.ImportName, op.Alias, nil)
+		if name != op.Alias {
+			return fmt.Errorf("import %s: expect name %s, actual %s", op.ImportPath, op.Alias, name)
+		}
+	case JournalInit:
+		edit.AddAnaymouseInit(op.Content)
+	case JournalAppend:
+		edit.Append(op.Content)
+	default:
+		return fmt.Errorf("unknown file edit method: %s", op.Method)
+	}
+	return nil
+}
+
+func replayNewEditOp(edit sessionpkg.GoNewEdit, op *JournalOp) error {
+	switch op.Method {
+	case JournalPackageName:
+		edit.SetPackageName(op.Content)
+	case JournalImport:
+		name := edit.MustImport(op.ImportPath, op.ImportName, op.Alias, nil)
+		if name != op.Alias {
+			return fmt.Errorf("import %s: expect name %s, actual %s", op.ImportPath, op.Alias, name)
+		}
+	case JournalHeadCode:
+		edit.AddHeadCode(op.Content)
+	case JournalCode:
+		edit.AddCode(op.Content)
+	case JournalInit:
+		edit.AddAnaymouseInit(op.Content)
+	default:
+		return fmt.Errorf("unknown package edit method: %s", op.Method)
+	}
+	return nil
+}
+
+func fileBase(f inspect.FileContext) token.Pos {
+	return token.Pos(f.Pkg().Global().FileSet().File(f.AST().Package).Base())
+}
+
+func (c *Journal) record(op *JournalOp) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.Ops = append(c.Ops, op)
+}
+
+type journalRewriteEdit struct {
+	sessionpkg.GoRewriteEdit
+	j      *Journal
+	target JournalTarget
+	file   string
+	base   token.Pos
+	origin string
+}
+
+var _ sessionpkg.GoRewriteEdit = ((*journalRewriteEdit)(nil))
+var _ sessionpkg.OriginEdit = ((*journalRewriteEdit)(nil))
+
+func (c *journalRewriteEdit) op(method JournalMethod) *JournalOp {
+	return &JournalOp{Target: c.target, Method: method, File: c.file, Origin: c.origin}
+}
+
+// WithOrigin implements OriginEdit
+func (c *journalRewriteEdit) WithOrigin(origin string) sessionpkg.GoRewriteEdit {
+	edit := *c
+	edit.GoRewriteEdit = sessionpkg.WithOrigin(c.GoRewriteEdit, origin)
+	edit.origin = origin
+	return &edit
+}
+
+// Insert implements GoRewriteEdit
+func (c *journalRewriteEdit) Insert(start token.Pos, content string) {
+	c.GoRewriteEdit.Insert(start, content)
+	op := c.op(JournalInsert)
+	op.Start = int(start - c.base)
+	op.Content = content
+	c.j.record(op)
+}
+
+// Delete implements GoRewriteEdit
+func (c *journalRewriteEdit) Delete(start token.Pos, end token.Pos) {
+	c.GoRewriteEdit.Delete(start, end)
+	op := c.op(JournalDelete)
+	op.Start = int(start - c.base)
+	op.End = int(end - c.base)
+	c.j.record(op)
+}
+
+// Replace implements GoRewriteEdit
+func (c *journalRewriteEdit) Replace(start token.Pos, end token.Pos, content string) {
+	c.GoRewriteEdit.Replace(start, end, content)
+	op := c.op(JournalReplace)
+	op.Start = int(start - c.base)
+	op.End = int(end - c.base)
+	op.Content = content
+	c.j.record(op)
+}
+
+// MustImport implements GoRewriteEdit
+func (c *journalRewriteEdit) MustImport(pkgPath string, name string, suggestAlias string, forbidden func(name string) bool) string {
+	use := c.GoRewriteEdit.MustImport(pkgPath, name, suggestAlias, forbidden)
+	op := c.op(JournalImport)
+	op.ImportPath = pkgPath
+	op.ImportName = name
+	op.Alias = use
+	c.j.record(op)
+	return use
+}
+
+// AddAnaymouseInit implements GoRewriteEdit
+func (c *journalRewriteEdit) AddAnaymouseInit(code string) {
+	c.GoRewriteEdit.AddAnaymouseInit(code)
+	op := c.op(JournalInit)
+	op.Content = code
+	c.j.record(op)
+}
+
+// Append implements GoRewriteEdit
+func (c *journalRewriteEdit) Append(code string) {
+	c.GoRewriteEdit.Append(code)
+	op := c.op(JournalAppend)
+	op.Content = code
+	c.j.record(op)
+}
+
+type journalNewEdit struct {
+	sessionpkg.GoNewEdit
+	j    *Journal
+	pkg  string
+	kind string
+}
+
+var _ sessionpkg.GoNewEdit = ((*journalNewEdit)(nil))
+
+func (c *journalNewEdit) op(method JournalMethod, content string) *JournalOp {
+	return &JournalOp{Target: JournalTargetPkg, Method: method, Pkg: c.pkg, Kind: c.kind, Content: content}
+}
+
+// SetPackageName implements GoNewEdit
+func (c *journalNewEdit) SetPackageName(name string) {
+	c.GoNewEdit.SetPackageName(name)
+	c.j.record(c.op(JournalPackageName, name))
+}
+
+// MustImport implements GoNewEdit
+func (c *journalNewEdit) MustImport(pkgPath string, name string, suggestAlias string, forbidden func(name string) bool) string {
+	use := c.GoNewEdit.MustImport(pkgPath, name, suggestAlias, forbidden)
+	op := c.op(JournalImport, "")
+	op.ImportPath = pkgPath
+	op.ImportName = name
+	op.Alias = use
+	c.j.record(op)
+	return use
+}
+
+// AddHeadCode implements GoNewEdit
+func (c *journalNewEdit) AddHeadCode(code string) {
+	c.GoNewEdit.AddHeadCode(code)
+	c.j.record(c.op(JournalHeadCode, code))
+}
+
+// AddCode implements GoNewEdit
+func (c *journalNewEdit) AddCode(code string) {
+	c.GoNewEdit.AddCode(code)
+	c.j.record(c.op(JournalCode, code))
+}
+
+// AddAnaymouseInit implements GoNewEdit
+func (c *journalNewEdit) AddAnaymouseInit(code string) {
+	c.GoNewEdit.AddAnaymouseInit(code)
+	c.j.record(c.op(JournalInit, code))
+}
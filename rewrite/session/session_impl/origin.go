@@ -0,0 +1,30 @@
+package session_impl
+
+import (
+	"github.com/xhd2015/go-inspect/inspect"
+	sessionpkg "github.com/xhd2015/go-inspect/rewrite/session"
+)
+
+// OriginSession returns a session that forwards to `s`, edits
+// of FileRewrite and FileEdit made through it are recorded
+// with `origin`, which is reported when edits conflict.
+func OriginSession(s sessionpkg.Session, origin string) sessionpkg.Session {
+	return &originSession{Session: s, origin: origin}
+}
+
+type originSession struct {
+	sessionpkg.Session
+	origin string
+}
+
+var _ sessionpkg.Session = ((*originSession)(nil))
+
+// FileEdit implements Session
+func (c *originSession) FileEdit(f inspect.FileContext) sessionpkg.GoRewriteEdit {
+	return sessionpkg.WithOrigin(c.Session.FileEdit(f), c.origin)
+}
+
+// FileRewrite implements Session
+func (c *originSession) FileRewrite(f inspect.FileContext) sessionpkg.GoRewriteEdit {
+	return sessionpkg.WithOrigin(c.Session.FileRewrite(f), c.origin)
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/xhd2015/go-inspect/code/edit"
 	"github.com/xhd2015/go-inspect/inspect"
 	"github.com/xhd2015/go-inspect/inspect/util"
 	source_import_internal "github.com/xhd2015/go-inspect/rewrite/internal/source_import"
@@ -25,6 +26,8 @@ type session struct {
 
 	opts sessionpkg.Options
 
+	editPolicy edit.Policy
+
 	source_import_internal.SourceImportRegistryRetriever
 
 	fileEditMap    util.SyncMap
@@ -62,6 +65,14 @@ func OnSessionGlobal(s sessionpkg.Session, g inspect.Global) {
 	}
 }
 
+// OnSessionEditPolicy sets the policy of overlapping
+// edits of FileRewrite and FileEdit created afterwards
+func OnSessionEditPolicy(s sessionpkg.Session, policy edit.Policy) {
+	if s, ok := s.(*session); ok {
+		s.editPolicy = policy
+	}
+}
+
 func OnSessionProject(s sessionpkg.Session, project sessionpkg.Project) {
 	if s, ok := s.(*session); ok {
 		s.project = project
@@ -131,7 +142,7 @@ func (c *session) RewriteFS() *memfs.MemFS {
 func (c *session) FileEdit(f inspect.FileContext) sessionpkg.GoRewriteEdit {
 	absPath := f.AbsPath()
 	v := c.fileEditMap.LoadOrCompute(absPath, func() interface{} {
-		return &fileEntry{f: f, edit: NewGoRewrite(f)}
+		return &fileEntry{f: f, edit: newGoRewrite(f, c.editPolicy)}
 	})
 	return v.(*fileEntry).edit
 }
@@ -140,7 +151,7 @@ func (c *session) FileEdit(f inspect.FileContext) sessionpkg.GoRewriteEdit {
 func (c *session) FileRewrite(f inspect.FileContext) sessionpkg.GoRewriteEdit {
 	absPath := f.AbsPath()
 	v := c.fileRewriteMap.LoadOrCompute(absPath, func() interface{} {
-		return &fileEntry{f: f, edit: NewGoRewrite(f)}
+		return &fileEntry{f: f, edit: newGoRewrite(f, c.editPolicy)}
 	})
 	return v.(*fileEntry).edit
 }
@@ -195,6 +206,16 @@ func (c *session) Gen(callback sessionpkg.EditCallback) {
 	}
 }
 
+// RangeFileRewrites calls `fn` with edits made by FileRewrite
+func RangeFileRewrites(s sessionpkg.Session, fn func(f inspect.FileContext, edit sessionpkg.GoRewriteEdit) bool) {
+	if s, ok := s.(*session); ok {
+		s.fileRewriteMap.RangeComputed(func(key, value interface{}) bool {
+			e := value.(*fileEntry)
+			return fn(e.f, e.edit)
+		})
+	}
+}
+
 func (c *session) SetRewriteFile(filePath string, content string) error {
 	p := CleanGoFsPath(path.Join(c.dirs.RewriteRoot(), filePath))
 	return c.setFile(p, content)
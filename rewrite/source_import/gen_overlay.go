@@ -29,6 +29,9 @@ func OnSessionGenOverlay(sess session.Session) {
 
 // GenOverlay implements project.Rewriter
 func genOverlay(session session.Session, modMapping Modules) {
+	if len(modMapping) == 0 {
+		return
+	}
 	g := session.Global()
 	proj := session.Project()
 	dirs := session.Dirs()
@@ -47,6 +50,13 @@ func genOverlay(session session.Session, modMapping Modules) {
 		rewriteVendorDir = dirs.RewriteProjectVendorRoot()
 	}
 
+	// the project dir may not be a module in go.work
+	// workspace, add requirements to the main module instead
+	rewriteModRoot := dirs.RewriteProjectRoot()
+	if mainMod := g.LoadInfo().MainModule(); !isVendor && mainMod != nil && mainMod.Dir() != "" {
+		rewriteModRoot = path.Join(dirs.RewriteRoot(), mainMod.Dir())
+	}
+
 	// process in order
 	type modInfo struct {
 		modPath string
@@ -135,7 +145,7 @@ func genOverlay(session session.Session, modMapping Modules) {
 		// and if vendor, update vendor/modules.txt.
 		// NOTE: can always ignore sums if we make absolution replace here
 		// and also NOTE that, seems sums not necessary when in vendor mode without replace
-		err := helper.AddVersionAndSumFS(wfs, dirs.RewriteProjectRoot(), modPath, maxMod.Version, "" /*sums optional*/, replaceModPath)
+		err := helper.AddVersionAndSumFS(wfs, rewriteModRoot, modPath, maxMod.Version, "" /*sums optional*/, replaceModPath)
 		if err != nil {
 			panic(fmt.Errorf("adding sum:%s %w", modPath, err))
 		}
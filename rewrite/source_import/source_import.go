@@ -3,6 +3,7 @@ package source_import
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	source_import_internal "github.com/xhd2015/go-inspect/rewrite/internal/source_import"
@@ -15,7 +16,8 @@ import (
 type Modules map[string][]*model.Module
 
 type registry struct {
-	mods Modules
+	mutex sync.Mutex
+	mods  Modules
 }
 
 func NewRegistry() source_import_internal.SourceImportRegistryRetriever {
@@ -31,6 +33,8 @@ func (c *registry) AddModule(mod *model.Module) error {
 		return fmt.Errorf("no module path: %+v", mod)
 	}
 
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	c.mods[mod.Path] = append(c.mods[mod.Path], mod)
 	return nil
 }
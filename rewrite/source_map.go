@@ -0,0 +1,272 @@
+package rewrite
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/xhd2015/go-vendor-pack/writefs"
+
+	"github.com/xhd2015/go-inspect/code/edit"
+	"github.com/xhd2015/go-inspect/inspect"
+	rewrite_edit "github.com/xhd2015/go-inspect/rewrite/edit"
+	session_pkg "github.com/xhd2015/go-inspect/rewrite/session"
+	"github.com/xhd2015/go-inspect/rewrite/session/session_impl"
+)
+
+// SourceMapFile is the file under RewriteMetaRoot holding
+// the source map of the last rewrite
+const SourceMapFile = "source-map.json"
+
+// SourceMap maps positions of rewritten files back to
+// their original files, so that compiler errors, panics
+// and stack traces point to the code the user wrote.
+type SourceMap struct {
+	// Files maps absolute path of the rewritten file to its map
+	Files map[string]*FileSourceMap `json:"files"`
+
+	// original file -> map
+	byOriginal map[string]*FileSourceMap
+	once       sync.Once
+}
+
+type FileSourceMap struct {
+	Original string        `json:"original"`
+	Mapping  *edit.Mapping `json:"mapping"`
+
+	// offsets where each line starts, of
+	// the original and the rewritten file
+	OldLines []int `json:"old_lines"`
+	NewLines []int `json:"new_lines"`
+}
+
+// Position in a file, Line and Column start from 1,
+// Column is 0 if unknown.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (c Position) String() string {
+	if c.Column == 0 {
+		return fmt.Sprintf("%s:%d", c.File, c.Line)
+	}
+	return fmt.Sprintf("%s:%d:%d", c.File, c.Line, c.Column)
+}
+
+// LoadSourceMap reads the source map saved in `metaRoot`,
+// it returns nil if there is none.
+func LoadSourceMap(metaRoot string) (*SourceMap, error) {
+	data, err := ioutil.ReadFile(filepath.Join(metaRoot, SourceMapFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	m := &SourceMap{}
+	err = json.Unmarshal(data, m)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", SourceMapFile, err)
+	}
+	return m, nil
+}
+
+func (c *SourceMap) save(metaRoot string) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(metaRoot, SourceMapFile), data, 0644)
+}
+
+// Original returns the original position of `pos`. pos.File is either
+// the rewritten file, or the original file as seen in stack traces
+// of binaries built with -trimpath.
+// It returns false if the file is not rewritten.
+func (c *SourceMap) Original(pos Position) (Position, bool) {
+	if c == nil {
+		return pos, false
+	}
+	m := c.Files[pos.File]
+	if m == nil {
+		c.once.Do(func() {
+			c.byOriginal = make(map[string]*FileSourceMap, len(c.Files))
+			for _, f := range c.Files {
+				c.byOriginal[f.Original] = f
+			}
+		})
+		m = c.byOriginal[pos.File]
+	}
+	if m == nil || pos.Line < 1 || pos.Line > len(m.NewLines) {
+		return pos, false
+	}
+	col := pos.Column
+	if col < 1 {
+		col = 1
+	}
+	oldOffset, _ := m.Mapping.OldOffset(m.NewLines[pos.Line-1] + col - 1)
+	// the last line starts before or at offset
+	line := sort.Search(len(m.OldLines), func(i int) bool {
+		return m.OldLines[i] > oldOffset
+	})
+	res := Position{File: m.Original, Line: line}
+	if line > 0 && pos.Column > 0 {
+		res.Column = oldOffset - m.OldLines[line-1] + 1
+	}
+	return res, true
+}
+
+var sourcePosRegex = regexp.MustCompile(`([^\s:"'()]+\.go):(\d+)(?::(\d+))?`)
+
+// Translate replaces positions like file.go:line:col in `text`
+// with their original positions, relative files are
+// resolved against `dir`.
+func (c *SourceMap) Translate(text string, dir string) string {
+	if c == nil || len(c.Files) == 0 {
+		return text
+	}
+	return sourcePosRegex.ReplaceAllStringFunc(text, func(s string) string {
+		m := sourcePosRegex.FindStringSubmatch(s)
+		file := m[1]
+		if !filepath.IsAbs(file) {
+			if dir == "" {
+				return s
+			}
+			file = filepath.Join(dir, file)
+		}
+		line, _ := strconv.Atoi(m[2])
+		col, _ := strconv.Atoi(m[3])
+		pos, ok := c.Original(Position{File: file, Line: line, Column: col})
+		if !ok {
+			return s
+		}
+		return pos.String()
+	})
+}
+
+// Writer returns a writer translating every line
+// written to it before writing it to `w`
+func (c *SourceMap) Writer(w io.Writer, dir string) *SourceMapWriter {
+	return &SourceMapWriter{m: c, dir: dir, w: w}
+}
+
+// SourceMapWriter translates lines, Flush must be
+// called to write the last incomplete line
+type SourceMapWriter struct {
+	m   *SourceMap
+	dir string
+	w   io.Writer
+	buf []byte
+}
+
+func (c *SourceMapWriter) Write(p []byte) (int, error) {
+	c.buf = append(c.buf, p...)
+	idx := bytes.LastIndexByte(c.buf, '\n')
+	if idx < 0 {
+		return len(p), nil
+	}
+	_, err := io.WriteString(c.w, c.m.Translate(string(c.buf[:idx+1]), c.dir))
+	c.buf = append(c.buf[:0], c.buf[idx+1:]...)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *SourceMapWriter) Flush() error {
+	if len(c.buf) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(c.w, c.m.Translate(string(c.buf), c.dir))
+	c.buf = nil
+	return err
+}
+
+// sourceMappedError is an error whose message
+// has positions translated
+type sourceMappedError struct {
+	msg string
+	err error
+}
+
+func (c *sourceMappedError) Error() string {
+	return c.msg
+}
+
+func (c *sourceMappedError) Unwrap() error {
+	return c.err
+}
+
+func (c *SourceMap) translateError(err error, dir string) error {
+	if err == nil {
+		return nil
+	}
+	msg := c.Translate(err.Error(), dir)
+	if msg == err.Error() {
+		return err
+	}
+	return &sourceMappedError{msg: msg, err: err}
+}
+
+// genSourceMap maps files rewritten by FileRewrite, files
+// overridden by other contents are skipped.
+func genSourceMap(session session_pkg.Session, rewriteRoot string) (*SourceMap, error) {
+	g := session.Global()
+	rewriteFS := session.RewriteFS()
+	m := &SourceMap{Files: make(map[string]*FileSourceMap)}
+	var err error
+	session_impl.RangeFileRewrites(session, func(f inspect.FileContext, e session_pkg.GoRewriteEdit) bool {
+		mapper, ok := e.(rewrite_edit.Mapper)
+		if !ok {
+			return true
+		}
+		newFile := path.Join(rewriteRoot, cleanGoFsPath(f.AbsPath()))
+		content, readErr := readMemFile(rewriteFS, newFile)
+		if readErr != nil {
+			if writefs.IsNotExist(readErr) {
+				return true
+			}
+			err = readErr
+			return false
+		}
+		if content != e.String() {
+			return true
+		}
+		mapping := mapper.Mapping()
+		if mapping == nil {
+			return true
+		}
+		m.Files[newFile] = &FileSourceMap{
+			Original: f.AbsPath(),
+			Mapping:  mapping,
+			OldLines: lineStarts(g.Code(f)),
+			NewLines: lineStarts(content),
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func lineStarts(s string) []int {
+	starts := []int{0}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\n' && i+1 < len(s) {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
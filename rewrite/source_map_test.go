@@ -0,0 +1,83 @@
+package rewrite
+
+import (
+	"go/ast"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/xhd2015/go-inspect/inspect"
+	"github.com/xhd2015/go-inspect/rewrite/session"
+)
+
+// go test -run TestRewriteSourceMap -v ./rewrite
+func TestRewriteSourceMap(t *testing.T) {
+	metaRoot := t.TempDir()
+	rewriteRoot := filepath.Join(metaRoot, "src")
+	ctrl := &ControllerFuncs{
+		BeforeLoadFn: withTestDirs("./testdata/simple", metaRoot),
+		GenOverlayFn: func(g inspect.Global, sess session.Session) {
+			sess.Gen(&session.EditCallbackFn{
+				Rewrites: func(f inspect.FileContext, content string) bool {
+					err := sess.SetRewriteFile(f.AbsPath(), content)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return true
+				},
+			})
+		},
+	}
+	vis := &Visitors{
+		VisitFn: func(n ast.Node, sess session.Session) bool {
+			if file, ok := n.(*ast.File); ok {
+				f := sess.Global().Registry().File(file)
+				// 2 lines after the package clause
+				sess.FileRewrite(f).Insert(file.Name.End(), "\n// a\n// b")
+				return false
+			}
+			return true
+		},
+	}
+	res, err := GenRewrite([]string{"./"}, rewriteRoot, ctrl, vis, &BuildRewriteOptions{
+		ProjectDir: "./testdata/simple",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	origFile, err := filepath.Abs("testdata/simple/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newFile := path.Join(rewriteRoot, origFile)
+	if res.SourceMap == nil || res.SourceMap.Files[newFile] == nil {
+		t.Fatalf("expect source map of %s, actual: %+v", newFile, res.SourceMap)
+	}
+
+	// `func main() {` is at line 8
+	pos, ok := res.SourceMap.Original(Position{File: newFile, Line: 10, Column: 6})
+	if !ok || pos != (Position{File: origFile, Line: 8, Column: 6}) {
+		t.Fatalf("expect main.go:8:6, actual: %v %v", pos, ok)
+	}
+	// inserted lines map to where they are inserted
+	pos, _ = res.SourceMap.Original(Position{File: newFile, Line: 2})
+	if pos.Line != 1 {
+		t.Fatalf("expect inserted line mapped to line 1, actual: %v", pos)
+	}
+
+	saved, err := LoadSourceMap(metaRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// stack traces show the original file with -trimpath
+	trace := "main.main()\n\t" + origFile + ":12 +0x1d\n"
+	expect := "main.main()\n\t" + origFile + ":10 +0x1d\n"
+	if actual := saved.Translate(trace, ""); actual != expect {
+		t.Fatalf("expect %q, actual %q", expect, actual)
+	}
+	compileErr := "./main.go:17:3: undefined: x\n"
+	expect = origFile + ":15:3: undefined: x\n"
+	if actual := saved.Translate(compileErr, path.Dir(newFile)); actual != expect {
+		t.Fatalf("expect %q, actual %q", expect, actual)
+	}
+}
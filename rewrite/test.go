@@ -0,0 +1,296 @@
+package rewrite
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xhd2015/go-inspect/inspect/util"
+	"github.com/xhd2015/go-inspect/sh"
+)
+
+// TestOptions makes the rewritten packages tested by
+// `go test` inside the rewrite root instead of being built,
+// so any number of packages can be tested, e.g. ./...
+type TestOptions struct {
+	// Flags passed to go test after build flags,
+	// e.g. -run, -v, -count, -args
+	Flags []string
+	// CoverProfile if not empty, coverage of all
+	// packages is written to this file.
+	// relative path is resolved against the project dir
+	CoverProfile string
+	// Output receives outputs of tests as printed by go test,
+	// and stderr of go test. Default discarded.
+	Output io.Writer
+}
+
+type TestAction string
+
+const (
+	TestPass TestAction = "pass"
+	TestFail TestAction = "fail"
+	TestSkip TestAction = "skip"
+)
+
+// TestResult is the result of go test over
+// the rewritten packages
+type TestResult struct {
+	Packages []*PackageTestResult
+	// Passed is true if go test exits with 0
+	Passed   bool
+	ExitCode int
+	// CoverProfile is the absolute path of the
+	// coverage profile, if requested
+	CoverProfile string
+}
+
+type PackageTestResult struct {
+	Package string
+	Action  TestAction
+	// Elapsed in seconds
+	Elapsed float64
+	Output  string
+	// Coverage percent of statements, -1 if not reported
+	Coverage float64
+	Tests    []*TestCaseResult
+}
+
+type TestCaseResult struct {
+	Name    string
+	Action  TestAction
+	Elapsed float64
+	Output  string
+}
+
+// Failed returns packages not passed
+func (c *TestResult) Failed() []*PackageTestResult {
+	if c == nil {
+		return nil
+	}
+	var failed []*PackageTestResult
+	for _, p := range c.Packages {
+		if p.Action == TestFail {
+			failed = append(failed, p)
+		}
+	}
+	return failed
+}
+
+// testEvent is the line emitted by go test -json,
+// see `go doc test2json`
+type testEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+var coverageRegex = regexp.MustCompile(`^coverage: ([0-9.]+)% of statements`)
+
+// testCollector turns events into TestResult,
+// keeping the order packages and tests appear
+type testCollector struct {
+	out      io.Writer
+	packages []*PackageTestResult
+	pkgMap   map[string]*PackageTestResult
+	testMap  map[string]*TestCaseResult
+
+	pkgOutput  map[string]*strings.Builder
+	testOutput map[string]*strings.Builder
+}
+
+func newTestCollector(out io.Writer) *testCollector {
+	return &testCollector{
+		out:        out,
+		pkgMap:     make(map[string]*PackageTestResult),
+		testMap:    make(map[string]*TestCaseResult),
+		pkgOutput:  make(map[string]*strings.Builder),
+		testOutput: make(map[string]*strings.Builder),
+	}
+}
+
+func (c *testCollector) pkg(name string) *PackageTestResult {
+	p := c.pkgMap[name]
+	if p == nil {
+		p = &PackageTestResult{Package: name, Coverage: -1}
+		c.pkgMap[name] = p
+		c.pkgOutput[name] = &strings.Builder{}
+		c.packages = append(c.packages, p)
+	}
+	return p
+}
+
+func (c *testCollector) test(pkg string, name string) *TestCaseResult {
+	key := pkg + "\x00" + name
+	t := c.testMap[key]
+	if t == nil {
+		t = &TestCaseResult{Name: name}
+		c.testMap[key] = t
+		c.testOutput[key] = &strings.Builder{}
+		p := c.pkg(pkg)
+		p.Tests = append(p.Tests, t)
+	}
+	return t
+}
+
+func (c *testCollector) add(e *testEvent) {
+	if e.Output != "" && c.out != nil {
+		io.WriteString(c.out, e.Output)
+	}
+	// build output has no package in older go
+	if e.Package == "" {
+		return
+	}
+	p := c.pkg(e.Package)
+	if e.Test != "" {
+		t := c.test(e.Package, e.Test)
+		switch e.Action {
+		case "output":
+			c.testOutput[e.Package+"\x00"+e.Test].WriteString(e.Output)
+		case "pass", "fail", "skip":
+			t.Action = TestAction(e.Action)
+			t.Elapsed = e.Elapsed
+		}
+		return
+	}
+	switch e.Action {
+	case "output", "build-output":
+		c.pkgOutput[e.Package].WriteString(e.Output)
+		if m := coverageRegex.FindStringSubmatch(e.Output); m != nil {
+			p.Coverage, _ = strconv.ParseFloat(m[1], 64)
+		}
+	case "pass", "fail", "skip":
+		p.Action = TestAction(e.Action)
+		p.Elapsed = e.Elapsed
+	}
+}
+
+func (c *testCollector) result() []*PackageTestResult {
+	for _, p := range c.packages {
+		p.Output = c.pkgOutput[p.Package].String()
+		for _, t := range p.Tests {
+			t.Output = c.testOutput[p.Package+"\x00"+t.Name].String()
+		}
+	}
+	return c.packages
+}
+
+// testEventWriter decodes go test -json lines
+type testEventWriter struct {
+	c   *testCollector
+	buf []byte
+
+	// outputs are translated by the source map,
+	// relative files are resolved against dir
+	sourceMap *SourceMap
+	dir       string
+}
+
+func (c *testEventWriter) Write(p []byte) (int, error) {
+	c.buf = append(c.buf, p...)
+	for {
+		idx := bytes.IndexByte(c.buf, '\n')
+		if idx < 0 {
+			break
+		}
+		c.line(c.buf[:idx])
+		c.buf = c.buf[idx+1:]
+	}
+	return len(p), nil
+}
+
+func (c *testEventWriter) line(line []byte) {
+	var e testEvent
+	if len(line) == 0 || line[0] != '{' || json.Unmarshal(line, &e) != nil {
+		// not an event, e.g. output of -n or -x
+		c.c.add(&testEvent{Action: "output", Output: c.sourceMap.Translate(string(line)+"\n", c.dir)})
+		return
+	}
+	e.Output = c.sourceMap.Translate(e.Output, c.dir)
+	c.c.add(&e)
+}
+
+func (c *testEventWriter) flush() {
+	if len(c.buf) > 0 {
+		c.line(c.buf)
+		c.buf = nil
+	}
+}
+
+// Test runs go test with `args` in the rewrite root
+func Test(args []string, opts *BuildOptions, testOpts *TestOptions) (*TestResult, error) {
+	return test(args, opts, testOpts)
+}
+
+func test(args []string, opts *BuildOptions, testOpts *TestOptions) (result *TestResult, err error) {
+	if opts == nil {
+		opts = &BuildOptions{}
+	}
+	if testOpts == nil {
+		testOpts = &TestOptions{}
+	}
+	goCmd, err := prepareGoCmd(opts)
+	if err != nil {
+		return
+	}
+	var coverProfile string
+	coverFlags := ""
+	if testOpts.CoverProfile != "" {
+		coverProfile = testOpts.CoverProfile
+		if !filepath.IsAbs(coverProfile) {
+			projectRoot, err := util.ToAbsPath(opts.ProjectRoot)
+			if err != nil {
+				return nil, err
+			}
+			coverProfile = filepath.Join(projectRoot, coverProfile)
+		}
+		coverFlags = " " + sh.Quote("-coverprofile="+coverProfile)
+	}
+	flagsSpace := ""
+	if len(testOpts.Flags) > 0 {
+		flagsSpace = " " + sh.Quotes(testOpts.Flags...)
+	}
+	cmdList := append(goCmd.cmdList, fmt.Sprintf(`%s test -json %s%s%s %s%s`, goCmd.goBinary, goCmd.gcflagsQuoted, goCmd.goFlagsSpace, coverFlags, sh.JoinArgs(args), flagsSpace))
+
+	collector := newTestCollector(testOpts.Output)
+	events := &testEventWriter{c: collector, sourceMap: opts.SourceMap, dir: goCmd.workDir}
+	var runCmd *exec.Cmd
+	_, _, runErr := sh.RunBashWithOpts(cmdList, sh.RunBashOptions{
+		Verbose: opts.Verbose,
+		FilterCmd: func(cmd *exec.Cmd) {
+			setTargetEnv(cmd)
+			cmd.Stdout = events
+			if testOpts.Output != nil {
+				cmd.Stderr = io.MultiWriter(cmd.Stderr, testOpts.Output)
+			}
+			runCmd = cmd
+		},
+	})
+	events.flush()
+
+	result = &TestResult{
+		Packages:     collector.result(),
+		CoverProfile: coverProfile,
+	}
+	if runCmd != nil && runCmd.ProcessState != nil {
+		result.ExitCode = runCmd.ProcessState.ExitCode()
+	}
+	result.Passed = runErr == nil && result.ExitCode == 0
+	if runErr != nil && len(result.Failed()) == 0 {
+		// go test itself failed, e.g. bad flags or no packages
+		return nil, fmt.Errorf("test %s err:%v", strings.Join(args, " "), runErr)
+	}
+	if opts.Verbose {
+		log.Printf("test finished: %d packages, %d failed", len(result.Packages), len(result.Failed()))
+	}
+	return result, nil
+}
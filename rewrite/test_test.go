@@ -0,0 +1,60 @@
+package rewrite
+
+import (
+	"strings"
+	"testing"
+)
+
+// go test -run TestCollectTestEvents -v ./rewrite
+func TestCollectTestEvents(t *testing.T) {
+	events := `{"Action":"start","Package":"a"}
+{"Action":"run","Package":"a","Test":"TestA"}
+{"Action":"output","Package":"a","Test":"TestA","Output":"=== RUN   TestA\n"}
+{"Action":"output","Package":"a","Test":"TestA","Output":"    a_test.go:5: bad\n"}
+{"Action":"fail","Package":"a","Test":"TestA","Elapsed":0.01}
+{"Action":"output","Package":"a","Output":"FAIL\n"}
+{"Action":"fail","Package":"a","Elapsed":0.02}
+{"Action":"start","Package":"b"}
+{"Action":"run","Package":"b","Test":"TestB"}
+{"Action":"pass","Package":"b","Test":"TestB"}
+{"Action":"output","Package":"b","Output":"coverage: 35.7% of statements\n"}
+{"Action":"pass","Package":"b","Elapsed":0.5}
+`
+	var out strings.Builder
+	c := newTestCollector(&out)
+	w := &testEventWriter{c: c}
+	// split in the middle of lines
+	for i := 0; i < len(events); i += 7 {
+		end := i + 7
+		if end > len(events) {
+			end = len(events)
+		}
+		w.Write([]byte(events[i:end]))
+	}
+	w.flush()
+	pkgs := c.result()
+
+	if len(pkgs) != 2 {
+		t.Fatalf("expect 2 packages, actual: %d", len(pkgs))
+	}
+	a, b := pkgs[0], pkgs[1]
+	if a.Package != "a" || a.Action != TestFail || a.Coverage != -1 {
+		t.Fatalf("bad package a: %+v", a)
+	}
+	if len(a.Tests) != 1 || a.Tests[0].Action != TestFail || a.Tests[0].Output != "=== RUN   TestA\n    a_test.go:5: bad\n" {
+		t.Fatalf("bad tests of a: %+v", a.Tests)
+	}
+	if b.Action != TestPass || b.Coverage != 35.7 || b.Elapsed != 0.5 {
+		t.Fatalf("bad package b: %+v", b)
+	}
+	expectOut := "=== RUN   TestA\n    a_test.go:5: bad\nFAIL\ncoverage: 35.7% of statements\n"
+	if out.String() != expectOut {
+		t.Fatalf("expect output %q, actual %q", expectOut, out.String())
+	}
+
+	res := &TestResult{Packages: pkgs}
+	failed := res.Failed()
+	if len(failed) != 1 || failed[0] != a {
+		t.Fatalf("expect a failed, actual: %+v", failed)
+	}
+}
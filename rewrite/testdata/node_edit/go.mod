@@ -0,0 +1,3 @@
+module github.com/xhd2015/go-inspect/rewrite/testdata/node_edit
+
+go 1.13
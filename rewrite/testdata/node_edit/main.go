@@ -0,0 +1,12 @@
+package main
+
+import "fmt"
+
+func main() {
+	fmt.Println(add(1, 2))
+}
+
+func add(a int, b int) int {
+	// sum of a and b
+	return a + b
+}
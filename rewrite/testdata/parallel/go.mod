@@ -0,0 +1,3 @@
+module github.com/xhd2015/go-inspect/rewrite/testdata/parallel
+
+go 1.13
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/xhd2015/go-inspect/rewrite/testdata/parallel/p1"
+	"github.com/xhd2015/go-inspect/rewrite/testdata/parallel/p2"
+	"github.com/xhd2015/go-inspect/rewrite/testdata/parallel/p3"
+	"github.com/xhd2015/go-inspect/rewrite/testdata/parallel/p4"
+)
+
+func main() {
+	fmt.Println(p1.N() + p2.N() + p3.N() + p4.N())
+}
@@ -0,0 +1,5 @@
+package p1
+
+func N() int {
+	return 1
+}
@@ -0,0 +1,5 @@
+package p2
+
+func N() int {
+	return 2
+}
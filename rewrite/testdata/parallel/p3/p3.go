@@ -0,0 +1,5 @@
+package p3
+
+func N() int {
+	return 3
+}
@@ -0,0 +1,5 @@
+package p4
+
+func N() int {
+	return 4
+}
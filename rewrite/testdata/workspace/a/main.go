@@ -0,0 +1,7 @@
+package main
+
+import "example.com/b"
+
+func main() {
+	println(b.Hello())
+}
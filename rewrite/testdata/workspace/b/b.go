@@ -0,0 +1,5 @@
+package b
+
+func Hello() string {
+	return "hello"
+}